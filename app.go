@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"manga-visor/internal/archiver"
 	"manga-visor/internal/fileloader"
 	"manga-visor/internal/modules/downloader"
 	"manga-visor/internal/modules/explorer"
@@ -59,6 +60,10 @@ func NewApp() *App {
 	downloaderPersist := persistence.NewDownloaderManager()
 	tabsManager := persistence.NewTabsManager()
 	viewerStatesManager := persistence.NewViewerStatesManager()
+	cookiesManager := persistence.NewCookiesManager()
+	subscriptionsManager := persistence.NewSubscriptionsManager()
+	contentIndex := persistence.NewContentIndexManager()
+	mangaDexAuth := persistence.NewMangaDexAuthManager()
 
 	// Image Server (if needed by modules for URL generation)
 	// We might need to initialize it here or pass nil and set it up later if it depends on port finding?
@@ -78,10 +83,26 @@ func NewApp() *App {
 	// We MUST reconstruct or add setter.
 	// Since I added `imgServer` to `NewModule` args, I pass nil here.
 	eMod := explorer.NewModule(fileLoader, nil)
-	dMod := downloader.NewModule(downloaderPersist, settings)
+	dMod := downloader.NewModule(downloaderPersist, settings, cookiesManager, subscriptionsManager, contentIndex, mangaDexAuth)
 
 	// Dependency injection (Circular dependency resolution)
 	lMod.SetSeriesModule(sMod)
+	dMod.SetSeriesModule(sMod)
+	// Library and Series folders are checked for duplicates along with the downloads
+	dMod.SetIndexRoots(func() []string {
+		var roots []string
+		for _, entry := range libraryManager.GetAll() {
+			if !entry.IsTemporary {
+				roots = append(roots, entry.FolderPath)
+			}
+		}
+		for _, entry := range seriesManager.GetAll() {
+			if !entry.IsTemporary {
+				roots = append(roots, entry.Path)
+			}
+		}
+		return roots
+	})
 
 	return &App{
 		settings:            settings,
@@ -437,6 +458,10 @@ func (a *App) GetImages(path string) ([]persistence.ImageInfo, error) {
 			Size:         img.Size,
 			Index:        img.Index,
 			ModTime:      img.ModTime,
+			Animated:     img.Animated,
+		}
+		if img.Animated && settings.AnimatedThumbnails {
+			result[i].PreviewURL = result[i].ThumbnailURL + "&animated=1"
 		}
 	}
 
@@ -514,6 +539,10 @@ func (a *App) GetImagesShallow(path string) ([]persistence.ImageInfo, error) {
 			Size:         img.Size,
 			Index:        img.Index,
 			ModTime:      img.ModTime,
+			Animated:     img.Animated,
+		}
+		if img.Animated && settings.AnimatedThumbnails {
+			result[i].PreviewURL = result[i].ThumbnailURL + "&animated=1"
 		}
 	}
 
@@ -673,6 +702,180 @@ func (a *App) RemoveDownloadJob(id string) {
 	a.downloaderMod.RemoveJob(id)
 }
 
+func (a *App) PauseDownloadJob(id string) error {
+	return a.downloaderMod.PauseJob(id)
+}
+
+func (a *App) ResumeDownloadJob(id string) error {
+	return a.downloaderMod.ResumeJob(id)
+}
+
+func (a *App) PauseAllDownloads() error {
+	return a.downloaderMod.PauseAllJobs()
+}
+
+// MoveDownloadJob moves a queued download to a position in its site queue (0 = next)
+func (a *App) MoveDownloadJob(id string, position int) error {
+	return a.downloaderMod.MoveJob(id, position)
+}
+
+// PrioritizeDownloadJob puts a queued download ahead of the rest of its site queue
+func (a *App) PrioritizeDownloadJob(id string) error {
+	return a.downloaderMod.PrioritizeJob(id)
+}
+
+// SetDownloadJobPriority changes the priority of a download (higher starts first)
+func (a *App) SetDownloadJobPriority(id string, priority int) error {
+	return a.downloaderMod.SetJobPriority(id, priority)
+}
+
+// GetQueuePositions returns the position of each queued download in its site queue
+func (a *App) GetQueuePositions() map[string]int {
+	return a.downloaderMod.GetQueuePositions()
+}
+
+func (a *App) ResumeAllDownloads() error {
+	return a.downloaderMod.ResumeAllJobs()
+}
+
+func (a *App) RetryFailedPages(id string) error {
+	return a.downloaderMod.RetryFailedPages(id)
+}
+
+// ImportCookies asks for a cookies.txt or JSON export and adds its cookies to the downloader.
+// userAgent should be the one of the browser the cookies come from, it may be empty.
+func (a *App) ImportCookies(userAgent string) (int, error) {
+	path, err := runtime.OpenFileDialog(a.ctx, runtime.OpenDialogOptions{
+		Title: "Import Cookies",
+		Filters: []runtime.FileFilter{
+			{DisplayName: "Cookies (*.txt, *.json)", Pattern: "*.txt;*.json"},
+		},
+	})
+	if err != nil || path == "" {
+		return 0, err
+	}
+	return a.downloaderMod.ImportCookies(path, userAgent)
+}
+
+func (a *App) GetCookieSites() []downloader.CookieSite {
+	return a.downloaderMod.GetCookieSites()
+}
+
+func (a *App) SetSiteUserAgent(domain string, userAgent string) error {
+	return a.downloaderMod.SetSiteUserAgent(domain, userAgent)
+}
+
+func (a *App) ClearSiteCookies(domain string) error {
+	return a.downloaderMod.ClearSiteCookies(domain)
+}
+
+func (a *App) GetSubscriptions() []persistence.Subscription {
+	return a.downloaderMod.GetSubscriptions()
+}
+
+func (a *App) AddSubscription(url string, languages []string, groups []string, autoDownload bool) (*persistence.Subscription, error) {
+	return a.downloaderMod.AddSubscription(url, languages, groups, autoDownload)
+}
+
+func (a *App) UpdateSubscription(id string, languages []string, groups []string, autoDownload bool) error {
+	return a.downloaderMod.UpdateSubscription(id, languages, groups, autoDownload)
+}
+
+func (a *App) RemoveSubscription(id string) {
+	a.downloaderMod.RemoveSubscription(id)
+}
+
+func (a *App) CheckSubscription(id string) ([]downloader.ChapterInfo, error) {
+	return a.downloaderMod.CheckSubscription(id)
+}
+
+func (a *App) CheckAllSubscriptions() int {
+	return a.downloaderMod.CheckAllSubscriptions()
+}
+
+// GetMangaDexAccount returns the MangaDex login state
+func (a *App) GetMangaDexAccount() downloader.MangaDexAccount {
+	return a.downloaderMod.GetMangaDexAccount()
+}
+
+// MangaDexLogin signs in with a MangaDex personal API client
+func (a *App) MangaDexLogin(clientID string, clientSecret string, username string, password string) (downloader.MangaDexAccount, error) {
+	return a.downloaderMod.MangaDexLogin(clientID, clientSecret, username, password)
+}
+
+// MangaDexLogout forgets the MangaDex session
+func (a *App) MangaDexLogout() error {
+	return a.downloaderMod.MangaDexLogout()
+}
+
+// GetMangaDexLists returns the custom lists of the MangaDex account
+func (a *App) GetMangaDexLists() ([]downloader.MangaDexList, error) {
+	return a.downloaderMod.GetMangaDexLists()
+}
+
+// ImportMangaDexTitles subscribes to the followed titles, or to a custom list's titles
+func (a *App) ImportMangaDexTitles(listID string, autoDownload bool) (int, error) {
+	return a.downloaderMod.ImportMangaDexTitles(listID, autoDownload)
+}
+
+// DownloadMangaDexList queues the matching chapters of every title in a custom list
+func (a *App) DownloadMangaDexList(listID string, filter downloader.SeriesFilter) ([]*downloader.SeriesBatch, error) {
+	return a.downloaderMod.DownloadMangaDexList(listID, filter)
+}
+
+// CheckMangaDexFeed reads the MangaDex follows feed for new chapters
+func (a *App) CheckMangaDexFeed() (int, error) {
+	return a.downloaderMod.CheckMangaDexFeed()
+}
+
+func (a *App) StartSeriesDownload(url string, filter downloader.SeriesFilter) (*downloader.SeriesBatch, error) {
+	return a.downloaderMod.StartSeriesDownload(url, filter)
+}
+
+func (a *App) GetBatchProgress(batchID string) downloader.BatchProgress {
+	return a.downloaderMod.GetBatchProgress(batchID)
+}
+
+// RebuildContentIndex rescans downloads and library for the duplicate check
+func (a *App) RebuildContentIndex() int {
+	return a.downloaderMod.RebuildContentIndex()
+}
+
+// GetDownloadSpace returns the download folder size, the free disk space and the quota
+func (a *App) GetDownloadSpace() downloader.DownloadSpace {
+	return a.downloaderMod.GetDownloadSpace()
+}
+
+// GetScheduleStatus returns whether the download window is open and when that changes
+func (a *App) GetScheduleStatus() downloader.ScheduleStatus {
+	return a.downloaderMod.GetScheduleStatus()
+}
+
+// PreviewNamingTemplate renders a download naming template with sample values
+func (a *App) PreviewNamingTemplate(template string) (string, error) {
+	return a.downloaderMod.PreviewNamingTemplate(template)
+}
+
+// PackageJobCBZ packages a completed download as CBZ
+func (a *App) PackageJobCBZ(id string) error {
+	return a.downloaderMod.PackageJobCBZ(id)
+}
+
+// GetHookLog returns the output log of a download hook
+func (a *App) GetHookLog(id string) (string, error) {
+	return a.downloaderMod.GetHookLog(id)
+}
+
+// TestHook runs a download hook once with a sample event
+func (a *App) TestHook(id string) error {
+	return a.downloaderMod.TestHook(id)
+}
+
+// RenameDownloadsToTemplate moves completed downloads to the paths of the current naming template
+func (a *App) RenameDownloadsToTemplate() (*downloader.RenameResult, error) {
+	return a.downloaderMod.RenameDownloadsToTemplate()
+}
+
 func (a *App) FetchMangaInfo(url string) (*downloader.SiteInfo, error) {
 	return a.downloaderMod.FetchMangaInfo(url)
 }
@@ -681,6 +884,22 @@ func (a *App) ResumeIncompleteDownloads(autoResume bool) error {
 	return a.downloaderMod.ResumeIncompleteDownloads(autoResume)
 }
 
+func (a *App) ReloadScrapers() error {
+	return a.downloaderMod.ReloadScrapers()
+}
+
+func (a *App) GetScrapersPath() string {
+	return a.downloaderMod.GetScrapersPath()
+}
+
+func (a *App) ReloadPlugins() error {
+	return a.downloaderMod.ReloadPlugins()
+}
+
+func (a *App) GetPluginsPath() string {
+	return a.downloaderMod.GetPluginsPath()
+}
+
 // OpenInFileManager opens a path in the system's file manager
 func (a *App) OpenInFileManager(path string) error {
 	var cmd *exec.Cmd
@@ -706,7 +925,8 @@ func (a *App) AddDownloadedFolder(downloadPath string) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("path not found: %v", err)
 	}
-	if !info.IsDir() {
+	// Chapters packaged as CBZ are added as archives
+	if !info.IsDir() && !archiver.IsArchive(downloadPath) {
 		downloadPath = filepath.Dir(downloadPath)
 	}
 
@@ -721,6 +941,11 @@ func (a *App) AddDownloadedFolder(downloadPath string) (string, error) {
 // AddDownloadedSeries adds a downloaded series (parent folder with chapters) to Series
 // Used when clicking Play on a series header in the download manager
 func (a *App) AddDownloadedSeries(chapterPath string) (string, error) {
+	// A series of CBZ chapters has no chapter folders, open the chapter itself
+	if archiver.IsArchive(chapterPath) {
+		return a.AddDownloadedFolder(chapterPath)
+	}
+
 	// Get the parent folder (series folder)
 	seriesPath := filepath.Dir(chapterPath)
 
@@ -121,6 +121,16 @@ export function MainLayout({ children }: MainLayoutProps) {
         };
     }, []);
 
+    // New chapters from subscriptions - works from any page
+    useEffect(() => {
+        const unoff = EventsOn('new_chapters', (event: { seriesName: string; chapters: unknown[]; queued: number }) => {
+            if (!event?.chapters?.length) return;
+            const key = event.queued > 0 ? 'download.newChaptersQueued' : 'download.newChapters';
+            showToast(t(key, { count: event.chapters.length, series: event.seriesName }), 'info');
+        });
+        return () => unoff();
+    }, []);
+
     // Global clipboard monitoring - works from any page
     useEffect(() => {
         // Listen for clipboard URL detection from backend
@@ -10,6 +10,7 @@ import { EventsOn } from '../../../wailsjs/runtime/runtime';
 import * as AppBackend from '../../../wailsjs/go/main/App';
 import { Tooltip } from '../common/Tooltip';
 import { downloader } from '../../../wailsjs/go/models';
+import { MangaDexAccount, MangaDexList, ScheduleStatus, Subscription } from '../../types';
 
 interface DownloadJob {
     id: string;
@@ -17,12 +18,15 @@ interface DownloadJob {
     site: string;
     seriesName: string;
     chapterName: string;
-    status: 'pending' | 'running' | 'completed' | 'failed' | 'cancelled';
+    status: 'pending' | 'scheduled' | 'running' | 'completed' | 'completed_with_errors' | 'failed' | 'cancelled' | 'paused';
     progress: number;
     totalPages: number;
     error?: string;
     createdAt: string;
     path: string;
+    batchId?: string;
+    /** Local copy found by the duplicate check when it was queued */
+    duplicateOf?: string;
 }
 
 export const DownloadPage: React.FC = () => {
@@ -33,12 +37,15 @@ export const DownloadPage: React.FC = () => {
     const navigate = useNavigationStore((state) => state.navigate);
     const [url, setUrl] = useState('');
     const [history, setHistory] = useState<DownloadJob[]>([]);
+    const [scheduleStatus, setScheduleStatus] = useState<ScheduleStatus | null>(null);
+    const [queuePositions, setQueuePositions] = useState<Record<string, number>>({});
     const [isLoading, setIsLoading] = useState(false);
 
     // State for series download
     const [seriesInfo, setSeriesInfo] = useState<downloader.SiteInfo | null>(null);
     const [selectedChapters, setSelectedChapters] = useState<Set<string>>(new Set());
     const [isSeriesModalOpen, setIsSeriesModalOpen] = useState(false);
+    const [seriesUrl, setSeriesUrl] = useState('');
 
     // Series Grouping Logic
     const [expandedSeries, setExpandedSeries] = useState<Set<string>>(new Set());
@@ -114,6 +121,8 @@ export const DownloadPage: React.FC = () => {
         try {
             const jobs = await AppBackend.GetDownloadHistory();
             setHistory(jobs as any);
+            setScheduleStatus(await (AppBackend as any).GetScheduleStatus());
+            setQueuePositions(await (AppBackend as any).GetQueuePositions() || {});
         } catch (err) {
             console.error('Failed to load download history:', err);
         }
@@ -150,11 +159,16 @@ export const DownloadPage: React.FC = () => {
 
             if (info.Type === 'series') {
                 setSeriesInfo(info);
+                setSeriesUrl(urlToDownload);
                 // Start with empty selection so user must choose
                 setSelectedChapters(new Set());
                 setIsSeriesModalOpen(true);
                 setUrl(''); // Clear input
             } else {
+                // Already on disk from another source, the backend skips it or just warns
+                if (info.Duplicates?.length) {
+                    showToast(t('download.duplicateFound', { path: info.Duplicates[0].path }), 'info');
+                }
                 // It's a single chapter, start download directly
                 const jobId = await (AppBackend.StartDownload(urlToDownload, "", ""));
                 setUrl('');
@@ -190,62 +204,70 @@ export const DownloadPage: React.FC = () => {
     // This allows auto-download of single chapters from any page
     // Series URLs will show a toast prompting user to go to Downloads page
 
-    const handleDownloadSeries = async () => {
-        if (!seriesInfo) return;
+    // Series filters applied by the backend (chapter ranges, latest N, one release per chapter)
+    const [seriesRanges, setSeriesRanges] = useState('');
+    const [seriesLatest, setSeriesLatest] = useState(0);
+    const [seriesDedupe, setSeriesDedupe] = useState(false);
 
-        const chaptersToDownload = seriesInfo.Chapters.filter((c: any) => selectedChapters.has(c.ID));
-
-        if (chaptersToDownload.length === 0) {
-            showToast("No chapters selected", "error");
-            return;
-        }
+    const startSeriesBatch = async (filter: downloader.SeriesFilter) => {
+        if (!seriesInfo || !seriesUrl) return;
 
         setIsLoading(true);
         setIsSeriesModalOpen(false);
 
-        let started = 0;
-        let alreadyDownloaded = 0;
-        const jobIds: string[] = [];
-        
-        for (const chapter of chaptersToDownload) {
-            try {
-                const jobId = await (AppBackend as any).StartDownload(chapter.URL, seriesInfo.SeriesName, chapter.Name);
-                jobIds.push(jobId);
-                started++;
-            } catch (err) {
-                console.error(`Failed to start download for ${chapter.Name}:`, err);
-            }
-        }
+        try {
+            const batch = await AppBackend.StartSeriesDownload(seriesUrl, filter);
+            await loadHistory();
 
-        // Reload history to get the latest state
-        await loadHistory();
-        
-        // Check which jobs were already completed
-        const jobs = await AppBackend.GetDownloadHistory();
-        for (const jobId of jobIds) {
-            const job = jobs.find((j: any) => j.id === jobId);
-            if (job && job.status === 'completed') {
-                alreadyDownloaded++;
+            const started = batch.jobIds.length;
+            if (started === 0 && batch.skipped > 0) {
+                showToast(t('download.allAlreadyDownloaded') || 'All chapters already downloaded', 'info');
+            } else if (batch.skipped > 0) {
+                showToast(`${started} new, ${batch.skipped} already downloaded`, 'info');
+            } else {
+                showToast(`Started ${started} downloads`, 'success');
             }
+        } catch (err: any) {
+            showToast(err.toString(), 'error');
         }
 
-        if (alreadyDownloaded > 0 && alreadyDownloaded === started) {
-            showToast(t('download.allAlreadyDownloaded') || 'All chapters already downloaded', 'info');
-        } else if (alreadyDownloaded > 0) {
-            showToast(`${started - alreadyDownloaded} new, ${alreadyDownloaded} already downloaded`, 'info');
-        } else {
-            showToast(`Started ${started} downloads`, 'success');
-        }
-        
         // Auto-expand the series to show the downloaded chapters
         if (seriesInfo.SeriesName && seriesInfo.SeriesName !== 'Unknown Series' && seriesInfo.SeriesName !== 'Unknown') {
             setExpandedSeries(prev => new Set([...prev, seriesInfo.SeriesName]));
         }
-        
+
         setIsLoading(false);
         setSeriesInfo(null);
     };
 
+    const handleDownloadSeries = async () => {
+        if (selectedChapters.size === 0) {
+            showToast("No chapters selected", "error");
+            return;
+        }
+        await startSeriesBatch(downloader.SeriesFilter.createFrom({ chapterIds: Array.from(selectedChapters) }));
+    };
+
+    const handleDownloadFiltered = async () => {
+        await startSeriesBatch(downloader.SeriesFilter.createFrom({
+            languages: filterLanguage === 'all' ? [] : [filterLanguage],
+            ranges: seriesRanges.trim(),
+            latest: seriesLatest > 0 ? seriesLatest : 0,
+            dedupe: seriesDedupe,
+        }));
+    };
+
+    const handleSubscribe = async () => {
+        if (!seriesUrl) return;
+        try {
+            const languages = filterLanguage === 'all' ? [] : [filterLanguage];
+            await (AppBackend as any).AddSubscription(seriesUrl, languages, [], true);
+            showToast(t('download.subscribed') || 'Subscribed', 'success');
+        } catch (err: any) {
+            showToast(err.toString(), 'error');
+        }
+    };
+
     const handleClearHistory = async () => {
         await AppBackend.ClearDownloadHistory();
         loadHistory();
@@ -256,9 +278,69 @@ export const DownloadPage: React.FC = () => {
         loadHistory();
     };
 
+    const handlePauseJob = async (id: string) => {
+        try {
+            await AppBackend.PauseDownloadJob(id);
+            await loadHistory();
+        } catch (err: any) {
+            showToast(err.toString(), 'error');
+        }
+    };
+
+    const handleMoveJob = async (id: string, position: number) => {
+        try {
+            await (AppBackend as any).MoveDownloadJob(id, position);
+            await loadHistory();
+        } catch (err: any) {
+            showToast(err.toString(), 'error');
+        }
+    };
+
+    const handlePrioritizeJob = async (id: string) => {
+        try {
+            await (AppBackend as any).PrioritizeDownloadJob(id);
+            await loadHistory();
+        } catch (err: any) {
+            showToast(err.toString(), 'error');
+        }
+    };
+
+    const handlePauseAll = async () => {
+        try {
+            await AppBackend.PauseAllDownloads();
+        } catch (err: any) {
+            showToast(err.toString(), 'error');
+        }
+        loadHistory();
+    };
+
+    const handleResumeAll = async () => {
+        try {
+            await AppBackend.ResumeAllDownloads();
+        } catch (err: any) {
+            showToast(err.toString(), 'error');
+        }
+        loadHistory();
+    };
+
+    const handleRetryFailedPages = async (id: string) => {
+        try {
+            await AppBackend.RetryFailedPages(id);
+            showToast(t('download.resumed') || 'Download resumed', 'success');
+            await loadHistory();
+        } catch (err: any) {
+            showToast(err.toString(), 'error');
+        }
+    };
+
     const handleResumeDownload = async (job: DownloadJob) => {
         try {
-            await AppBackend.StartDownload(job.url, job.seriesName, job.chapterName);
+            if (job.status === 'paused') {
+                // Continues with the image list kept by the backend
+                await AppBackend.ResumeDownloadJob(job.id);
+            } else {
+                await AppBackend.StartDownload(job.url, job.seriesName, job.chapterName);
+            }
             showToast(t('download.resumed') || 'Download resumed', 'success');
             await loadHistory();
         } catch (err: any) {
@@ -274,6 +356,15 @@ export const DownloadPage: React.FC = () => {
         loadHistory();
     };
 
+    const handlePackageCBZ = async (id: string) => {
+        try {
+            await (AppBackend as any).PackageJobCBZ(id);
+            showToast(t('download.packagedCbz', 'Packaged as CBZ'), 'success');
+        } catch (error) {
+            showToast(`${t('download.packageCbzFailed', 'Packaging failed')}: ${error}`, 'error');
+        }
+    };
+
     const handlePlayDownload = async (job: DownloadJob) => {
         if (!job.path) return;
         
@@ -498,21 +589,54 @@ export const DownloadPage: React.FC = () => {
                 </div>
             </section>
 
+            <MangaDexSection />
+
+            <SubscriptionsSection />
+
             {/* History Section */}
             <section className="flex-1 min-h-0 flex flex-col">
                 <div className="flex items-center justify-between mb-4">
                     <h2 className="text-xl font-semibold" style={{ color: 'var(--color-text-primary)' }}>
                         {t('download.downloadHistory')}
                     </h2>
+                    {scheduleStatus?.enabled && !scheduleStatus.open && (
+                        <span className="text-sm text-purple-400">
+                            {scheduleStatus.nextChange
+                                ? t('download.scheduleClosedUntil', { time: new Date(scheduleStatus.nextChange).toLocaleString() })
+                                : t('download.scheduleClosed')}
+                        </span>
+                    )}
                     {history.length > 0 && (
-                        <Button
-                            onClick={handleClearHistory}
-                            variant="ghost"
-                            size="sm"
-                            className="text-sm font-medium hover:text-red-400 hover:bg-transparent px-0"
-                        >
-                            {t('download.clearHistory')}
-                        </Button>
+                        <div className="flex items-center gap-4">
+                            {history.some(j => j.status === 'running' || j.status === 'pending' || j.status === 'scheduled') && (
+                                <Button
+                                    onClick={handlePauseAll}
+                                    variant="ghost"
+                                    size="sm"
+                                    className="text-sm font-medium hover:bg-transparent px-0"
+                                >
+                                    {t('download.pauseAll')}
+                                </Button>
+                            )}
+                            {history.some(j => j.status === 'paused') && (
+                                <Button
+                                    onClick={handleResumeAll}
+                                    variant="ghost"
+                                    size="sm"
+                                    className="text-sm font-medium hover:bg-transparent px-0"
+                                >
+                                    {t('download.resumeAll')}
+                                </Button>
+                            )}
+                            <Button
+                                onClick={handleClearHistory}
+                                variant="ghost"
+                                size="sm"
+                                className="text-sm font-medium hover:text-red-400 hover:bg-transparent px-0"
+                            >
+                                {t('download.clearHistory')}
+                            </Button>
+                        </div>
                     )}
                 </div>
 
@@ -565,6 +689,11 @@ export const DownloadPage: React.FC = () => {
                                                             </span>
                                                         </div>
                                                     )}
+                                                    {job.duplicateOf && (
+                                                        <p className="text-xs text-yellow-400 mt-1 truncate" title={job.duplicateOf}>
+                                                            {t('download.duplicateOf', { path: job.duplicateOf })}
+                                                        </p>
+                                                    )}
                                                     {job.error && (
                                                         <p className="text-xs text-red-400 mt-1 truncate">
                                                             {job.error}
@@ -577,15 +706,61 @@ export const DownloadPage: React.FC = () => {
                                             <div className="flex items-center gap-2 shrink-0">
                                                 {/* Status badge */}
                                                 <span className={`px-2 py-1 rounded text-xs font-bold uppercase shrink-0 ${job.status === 'completed' ? 'bg-green-500/20 text-green-400' :
+                                                    job.status === 'completed_with_errors' ? 'bg-orange-500/20 text-orange-400' :
                                                     job.status === 'failed' ? 'bg-red-500/20 text-red-400' :
                                                         job.status === 'running' ? 'bg-blue-500/20 text-blue-400 animate-pulse' :
+                                                            job.status === 'paused' ? 'bg-yellow-500/20 text-yellow-400' :
+                                                            job.status === 'scheduled' ? 'bg-purple-500/20 text-purple-400' :
                                                             'bg-gray-500/20 text-gray-400'
                                                     }`}>
                                                     {t(`download.status${job.status.charAt(0).toUpperCase() + job.status.slice(1)}`)}
                                                 </span>
 
-                                                {/* Resume button - show when toggle is off and download is incomplete */}
-                                                {job.status !== 'completed' && job.status !== 'running' && !(settings.autoResumeDownloads || false) && (
+                                                {/* Retry failed pages */}
+                                                {job.status === 'completed_with_errors' && (
+                                                    <Tooltip content={t('download.retryFailedPages') || 'Retry failed pages'} placement="top">
+                                                        <button
+                                                            onClick={() => handleRetryFailedPages(job.id)}
+                                                            className="p-1 hover:bg-white/10 rounded transition-colors shrink-0"
+                                                            style={{ color: 'var(--color-text-secondary)' }}
+                                                            aria-label={t('download.retryFailedPages') || 'Retry failed pages'}
+                                                        >
+                                                            <svg width="16" height="16" viewBox="0 0 24 24" fill="none" stroke="currentColor" strokeWidth="2" strokeLinecap="round" strokeLinejoin="round">
+                                                                <polyline points="23 4 23 10 17 10"></polyline>
+                                                                <path d="M20.49 15a9 9 0 1 1-2.12-9.36L23 10"></path>
+                                                            </svg>
+                                                        </button>
+                                                    </Tooltip>
+                                                )}
+
+                                                {/* Queue controls - jobs waiting for a free slot */}
+                                                {queuePositions[job.id] !== undefined && (
+                                                    <QueueControls
+                                                        position={queuePositions[job.id]}
+                                                        onMove={(position) => handleMoveJob(job.id, position)}
+                                                        onPrioritize={() => handlePrioritizeJob(job.id)}
+                                                    />
+                                                )}
+
+                                                {/* Pause button - running or queued downloads */}
+                                                {(job.status === 'running' || job.status === 'pending' || job.status === 'scheduled') && (
+                                                    <Tooltip content={t('download.pause') || 'Pause download'} placement="top">
+                                                        <button
+                                                            onClick={() => handlePauseJob(job.id)}
+                                                            className="p-1 hover:bg-white/10 rounded transition-colors shrink-0"
+                                                            style={{ color: 'var(--color-text-secondary)' }}
+                                                            aria-label={t('download.pause') || 'Pause'}
+                                                        >
+                                                            <svg width="16" height="16" viewBox="0 0 24 24" fill="none" stroke="currentColor" strokeWidth="2" strokeLinecap="round" strokeLinejoin="round">
+                                                                <rect x="6" y="4" width="4" height="16"></rect>
+                                                                <rect x="14" y="4" width="4" height="16"></rect>
+                                                            </svg>
+                                                        </button>
+                                                    </Tooltip>
+                                                )}
+
+                                                {/* Resume button - paused, or incomplete when the toggle is off */}
+                                                {(job.status === 'paused' || (job.status !== 'completed' && job.status !== 'completed_with_errors' && job.status !== 'running' && !(settings.autoResumeDownloads || false))) && (
                                                     <Tooltip content={t('download.resume') || 'Resume download'} placement="top">
                                                         <button
                                                             onClick={() => handleResumeDownload(job)}
@@ -601,7 +776,7 @@ export const DownloadPage: React.FC = () => {
                                                 )}
 
                                                 {/* Action buttons - only show when completed */}
-                                                {job.status === 'completed' && job.path && (
+                                                {(job.status === 'completed' || job.status === 'completed_with_errors') && job.path && (
                                                     <>
                                                         <Tooltip content={t('download.playInViewer')} placement="top">
                                                             <button
@@ -628,6 +803,20 @@ export const DownloadPage: React.FC = () => {
                                                         >
                                                             {t('download.openFolder')}
                                                         </button>
+                                                        {job.status === 'completed' && !job.path.toLowerCase().endsWith('.cbz') && (
+                                                            <Tooltip content={t('download.packageCbzHint', 'Package this chapter as CBZ with ComicInfo.xml')} placement="top">
+                                                                <button
+                                                                    className="text-xs font-semibold px-3 py-1.5 rounded transition-colors shrink-0"
+                                                                    style={{
+                                                                        backgroundColor: 'rgba(255, 255, 255, 0.1)',
+                                                                        color: 'var(--color-text-primary)'
+                                                                    }}
+                                                                    onClick={() => handlePackageCBZ(job.id)}
+                                                                >
+                                                                    CBZ
+                                                                </button>
+                                                            </Tooltip>
+                                                        )}
                                                     </>
                                                 )}
 
@@ -793,14 +982,58 @@ export const DownloadPage: React.FC = () => {
                                                             </div>
                                                             <div className="flex items-center gap-3">
                                                                 <span className={`px-2 py-1 rounded text-xs font-bold uppercase ${job.status === 'completed' ? 'bg-green-500/20 text-green-400' :
+                                                                    job.status === 'completed_with_errors' ? 'bg-orange-500/20 text-orange-400' :
                                                                     job.status === 'failed' ? 'bg-red-500/20 text-red-400' :
                                                                         job.status === 'running' ? 'bg-blue-500/20 text-blue-400 animate-pulse' :
+                                                                            job.status === 'paused' ? 'bg-yellow-500/20 text-yellow-400' :
+                                                                            job.status === 'scheduled' ? 'bg-purple-500/20 text-purple-400' :
                                                                             'bg-gray-500/20 text-gray-400'
                                                                     }`}>
                                                                     {t(`download.status${job.status.charAt(0).toUpperCase() + job.status.slice(1)}`)}
                                                                 </span>
-                                                                {/* Resume button - show when toggle is off and download is incomplete */}
-                                                                {job.status !== 'completed' && job.status !== 'running' && !(settings.autoResumeDownloads || false) && (
+                                                                {/* Retry failed pages */}
+                                                                {job.status === 'completed_with_errors' && (
+                                                                    <Tooltip content={t('download.retryFailedPages') || 'Retry failed pages'} placement="left" className="flex-shrink-0">
+                                                                        <button
+                                                                            onClick={(e) => { e.stopPropagation(); handleRetryFailedPages(job.id); }}
+                                                                            className="p-1 hover:bg-white/10 rounded transition-colors"
+                                                                            style={{ color: 'var(--color-text-secondary)' }}
+                                                                            aria-label={t('download.retryFailedPages') || 'Retry failed pages'}
+                                                                        >
+                                                                            <svg width="16" height="16" viewBox="0 0 24 24" fill="none" stroke="currentColor" strokeWidth="2" strokeLinecap="round" strokeLinejoin="round">
+                                                                                <polyline points="23 4 23 10 17 10"></polyline>
+                                                                                <path d="M20.49 15a9 9 0 1 1-2.12-9.36L23 10"></path>
+                                                                            </svg>
+                                                                        </button>
+                                                                    </Tooltip>
+                                                                )}
+                                                                {/* Queue controls - jobs waiting for a free slot */}
+                                                                {queuePositions[job.id] !== undefined && (
+                                                                    <QueueControls
+                                                                        position={queuePositions[job.id]}
+                                                                        onMove={(position) => handleMoveJob(job.id, position)}
+                                                                        onPrioritize={() => handlePrioritizeJob(job.id)}
+                                                                    />
+                                                                )}
+
+                                                                {/* Pause button - running or queued downloads */}
+                                                                {(job.status === 'running' || job.status === 'pending' || job.status === 'scheduled') && (
+                                                                    <Tooltip content={t('download.pause') || 'Pause download'} placement="left" className="flex-shrink-0">
+                                                                        <button
+                                                                            onClick={(e) => { e.stopPropagation(); handlePauseJob(job.id); }}
+                                                                            className="p-1 hover:bg-white/10 rounded transition-colors"
+                                                                            style={{ color: 'var(--color-text-secondary)' }}
+                                                                            aria-label={t('download.pause') || 'Pause'}
+                                                                        >
+                                                                            <svg width="16" height="16" viewBox="0 0 24 24" fill="none" stroke="currentColor" strokeWidth="2" strokeLinecap="round" strokeLinejoin="round">
+                                                                                <rect x="6" y="4" width="4" height="16"></rect>
+                                                                                <rect x="14" y="4" width="4" height="16"></rect>
+                                                                            </svg>
+                                                                        </button>
+                                                                    </Tooltip>
+                                                                )}
+                                                                {/* Resume button - paused, or incomplete when the toggle is off */}
+                                                                {(job.status === 'paused' || (job.status !== 'completed' && job.status !== 'completed_with_errors' && job.status !== 'running' && !(settings.autoResumeDownloads || false))) && (
                                                                     <Tooltip content={t('download.resume') || 'Resume download'} placement="left" className="flex-shrink-0">
                                                                         <button
                                                                             onClick={(e) => { e.stopPropagation(); handleResumeDownload(job); }}
@@ -847,13 +1080,18 @@ export const DownloadPage: React.FC = () => {
                                                             </div>
                                                         )}
 
+                                                        {job.duplicateOf && (
+                                                            <p className="text-xs text-yellow-400 mt-1 truncate" title={job.duplicateOf}>
+                                                                {t('download.duplicateOf', { path: job.duplicateOf })}
+                                                            </p>
+                                                        )}
                                                         {job.error && (
                                                             <p className="text-xs text-red-400 bg-red-400/10 p-2 rounded">
                                                                 {job.error}
                                                             </p>
                                                         )}
 
-                                                        {job.status === 'completed' && job.path && (
+                                                        {(job.status === 'completed' || job.status === 'completed_with_errors') && job.path && (
                                                             <div className="flex gap-2 mt-2">
                                                                 <Tooltip content={t('download.playInViewer')} placement="top">
                                                                     <button
@@ -1001,6 +1239,11 @@ export const DownloadPage: React.FC = () => {
                                             <div className="font-medium transition-colors" style={{ color: 'var(--color-text-primary)' }}>
                                                 {chapter.Name}
                                             </div>
+                                            {chapter.LocalPath && (
+                                                <span className="px-1.5 py-0.5 rounded text-xs bg-green-500/20 text-green-400" title={chapter.LocalPath}>
+                                                    {t('download.onDisk')}
+                                                </span>
+                                            )}
                                         </div>
                                         <div className="text-xs flex gap-2" style={{ color: 'var(--color-text-secondary)' }}>
                                             <span>{chapter.Date ? new Date(chapter.Date).toLocaleDateString() : ''}</span>
@@ -1023,7 +1266,43 @@ export const DownloadPage: React.FC = () => {
                             ))}
                         </div>
 
+                        <div className="px-4 pt-4 flex items-center gap-3 border-t" style={{ backgroundColor: 'var(--color-surface-secondary)', borderColor: 'var(--color-border)' }}>
+                            <input
+                                type="text"
+                                value={seriesRanges}
+                                onChange={(e) => setSeriesRanges(e.target.value)}
+                                placeholder={t('download.chapterRanges')}
+                                className="flex-1 text-sm rounded border px-2 py-1 outline-none"
+                                style={{ backgroundColor: 'var(--color-surface-tertiary)', color: 'var(--color-text-primary)', borderColor: 'var(--color-border)' }}
+                            />
+                            <span className="text-sm" style={{ color: 'var(--color-text-secondary)' }}>{t('download.latestChapters')}</span>
+                            <input
+                                type="number"
+                                min={0}
+                                value={seriesLatest}
+                                onChange={(e) => setSeriesLatest(Number(e.target.value) || 0)}
+                                className="w-16 text-sm rounded border px-2 py-1 outline-none"
+                                style={{ backgroundColor: 'var(--color-surface-tertiary)', color: 'var(--color-text-primary)', borderColor: 'var(--color-border)' }}
+                            />
+                            <div className="flex items-center gap-2">
+                                <Toggle checked={seriesDedupe} onChange={setSeriesDedupe} />
+                                <span className="text-sm" style={{ color: 'var(--color-text-secondary)' }}>{t('download.onePerChapter')}</span>
+                            </div>
+                            <Button onClick={handleDownloadFiltered} variant="secondary" size="sm">
+                                {t('download.downloadFiltered')}
+                            </Button>
+                        </div>
+
                         <div className="p-4 flex justify-end gap-3" style={{ backgroundColor: 'var(--color-surface-secondary)' }}>
+                            <Tooltip content={t('download.subscribeHint')} placement="top" className="mr-auto">
+                                <Button
+                                    onClick={handleSubscribe}
+                                    variant="secondary"
+                                    className="px-4"
+                                >
+                                    {t('download.subscribe')}
+                                </Button>
+                            </Tooltip>
                             <Button
                                 onClick={() => setIsSeriesModalOpen(false)}
                                 variant="ghost"
@@ -1047,3 +1326,331 @@ export const DownloadPage: React.FC = () => {
     );
 };
 
+const splitList = (value: string) => value.split(',').map(v => v.trim()).filter(Boolean);
+
+// Followed series, checked for new chapters on the interval from settings
+// Position in the site queue with buttons to reorder it
+function QueueControls({
+    position,
+    onMove,
+    onPrioritize,
+}: {
+    position: number;
+    onMove: (position: number) => void;
+    onPrioritize: () => void;
+}) {
+    const { t } = useTranslation();
+    const buttonClass = "p-1 hover:bg-white/10 rounded transition-colors shrink-0 disabled:opacity-30";
+
+    return (
+        <div className="flex items-center gap-0.5 shrink-0" style={{ color: 'var(--color-text-secondary)' }}>
+            <span className="text-xs font-mono px-1" title={t('download.queuePosition') || 'Position in queue'}>
+                #{position + 1}
+            </span>
+            <Tooltip content={t('download.prioritize') || 'Download next'} placement="top">
+                <button onClick={onPrioritize} disabled={position === 0} className={buttonClass} aria-label={t('download.prioritize') || 'Download next'}>
+                    <svg width="16" height="16" viewBox="0 0 24 24" fill="none" stroke="currentColor" strokeWidth="2" strokeLinecap="round" strokeLinejoin="round">
+                        <polyline points="17 11 12 6 7 11"></polyline>
+                        <polyline points="17 18 12 13 7 18"></polyline>
+                    </svg>
+                </button>
+            </Tooltip>
+            <Tooltip content={t('download.moveUp') || 'Move up'} placement="top">
+                <button onClick={() => onMove(position - 1)} disabled={position === 0} className={buttonClass} aria-label={t('download.moveUp') || 'Move up'}>
+                    <svg width="16" height="16" viewBox="0 0 24 24" fill="none" stroke="currentColor" strokeWidth="2" strokeLinecap="round" strokeLinejoin="round">
+                        <polyline points="18 15 12 9 6 15"></polyline>
+                    </svg>
+                </button>
+            </Tooltip>
+            <Tooltip content={t('download.moveDown') || 'Move down'} placement="top">
+                <button onClick={() => onMove(position + 1)} className={buttonClass} aria-label={t('download.moveDown') || 'Move down'}>
+                    <svg width="16" height="16" viewBox="0 0 24 24" fill="none" stroke="currentColor" strokeWidth="2" strokeLinecap="round" strokeLinejoin="round">
+                        <polyline points="6 9 12 15 18 9"></polyline>
+                    </svg>
+                </button>
+            </Tooltip>
+        </div>
+    );
+}
+
+function MangaDexSection() {
+    const { t } = useTranslation();
+    const { showToast } = useToast();
+    const [account, setAccount] = useState<MangaDexAccount | null>(null);
+    const [lists, setLists] = useState<MangaDexList[]>([]);
+    const [busy, setBusy] = useState<string | null>(null);
+
+    useEffect(() => {
+        (async () => {
+            try {
+                const acc: MangaDexAccount = await (AppBackend as any).GetMangaDexAccount();
+                setAccount(acc);
+                if (acc.loggedIn) {
+                    setLists((await (AppBackend as any).GetMangaDexLists()) || []);
+                }
+            } catch (err) {
+                console.error('Failed to load MangaDex lists:', err);
+            }
+        })();
+    }, []);
+
+    const run = async (key: string, action: () => Promise<void>) => {
+        setBusy(key);
+        try {
+            await action();
+        } catch (err: any) {
+            showToast(err.toString(), 'error');
+        } finally {
+            setBusy(null);
+        }
+    };
+
+    const handleImport = (listId: string) => run(`import-${listId}`, async () => {
+        const count = await (AppBackend as any).ImportMangaDexTitles(listId, false);
+        showToast(t('download.mangadexImported', { count }), count > 0 ? 'success' : 'info');
+    });
+
+    const handleFeed = () => run('feed', async () => {
+        const count = await (AppBackend as any).CheckMangaDexFeed();
+        if (count === 0) {
+            showToast(t('download.noNewChapters') || 'No new chapters', 'info');
+        }
+    });
+
+    const handleDownloadList = (list: MangaDexList) => run(`download-${list.id}`, async () => {
+        const batches = await (AppBackend as any).DownloadMangaDexList(list.id, { dedupe: true });
+        const chapters = (batches || []).reduce((sum: number, b: any) => sum + (b.jobIds?.length || 0), 0);
+        showToast(t('download.mangadexListQueued', { count: chapters, name: list.name }), 'success');
+    });
+
+    if (!account?.loggedIn) return null;
+
+    return (
+        <section className="mb-8">
+            <div className="flex items-center justify-between mb-4">
+                <h2 className="text-xl font-semibold" style={{ color: 'var(--color-text-primary)' }}>
+                    MangaDex
+                    <span className="ml-2 text-sm font-normal" style={{ color: 'var(--color-text-secondary)' }}>
+                        {account.username}
+                    </span>
+                </h2>
+                <div className="flex gap-2">
+                    <Button
+                        onClick={() => handleImport('')}
+                        variant="ghost"
+                        size="sm"
+                        isLoading={busy === 'import-'}
+                        disabled={busy !== null}
+                    >
+                        {t('download.mangadexImportFollows')}
+                    </Button>
+                    <Button
+                        onClick={handleFeed}
+                        variant="ghost"
+                        size="sm"
+                        isLoading={busy === 'feed'}
+                        disabled={busy !== null}
+                    >
+                        {t('download.mangadexCheckFeed')}
+                    </Button>
+                </div>
+            </div>
+            {lists.length > 0 && (
+                <div className="space-y-2">
+                    {lists.map(list => (
+                        <div key={list.id} className="card p-3 flex items-center gap-4">
+                            <div className="flex-1 min-w-0">
+                                <div className="font-medium truncate" style={{ color: 'var(--color-text-primary)' }}>
+                                    {list.name}
+                                </div>
+                                <div className="text-xs flex gap-2" style={{ color: 'var(--color-text-secondary)' }}>
+                                    <span>{t('download.mangadexListTitles', { count: list.titles })}</span>
+                                    {list.visibility === 'private' && (
+                                        <>
+                                            <span>•</span>
+                                            <span>{t('download.mangadexListPrivate')}</span>
+                                        </>
+                                    )}
+                                </div>
+                            </div>
+                            <Button
+                                onClick={() => handleImport(list.id)}
+                                variant="ghost"
+                                size="sm"
+                                isLoading={busy === `import-${list.id}`}
+                                disabled={busy !== null}
+                            >
+                                {t('download.mangadexSubscribeList')}
+                            </Button>
+                            <Button
+                                onClick={() => handleDownloadList(list)}
+                                variant="ghost"
+                                size="sm"
+                                isLoading={busy === `download-${list.id}`}
+                                disabled={busy !== null}
+                            >
+                                {t('download.mangadexDownloadList')}
+                            </Button>
+                        </div>
+                    ))}
+                </div>
+            )}
+        </section>
+    );
+}
+
+function SubscriptionsSection() {
+    const { t } = useTranslation();
+    const { showToast } = useToast();
+    const [subscriptions, setSubscriptions] = useState<Subscription[]>([]);
+    const [checking, setChecking] = useState<string | null>(null);
+
+    const loadSubscriptions = useCallback(async () => {
+        try {
+            setSubscriptions((await (AppBackend as any).GetSubscriptions()) || []);
+        } catch (err) {
+            console.error('Failed to load subscriptions:', err);
+        }
+    }, []);
+
+    useEffect(() => {
+        loadSubscriptions();
+        const unoff = EventsOn('subscriptions_updated', loadSubscriptions);
+        return () => unoff();
+    }, [loadSubscriptions]);
+
+    const handleCheck = async (id: string) => {
+        setChecking(id);
+        try {
+            const chapters = await (AppBackend as any).CheckSubscription(id);
+            if (!chapters || chapters.length === 0) {
+                showToast(t('download.noNewChapters') || 'No new chapters', 'info');
+            }
+        } catch (err: any) {
+            showToast(err.toString(), 'error');
+        } finally {
+            setChecking(null);
+        }
+    };
+
+    const handleCheckAll = async () => {
+        setChecking('all');
+        try {
+            const count = await (AppBackend as any).CheckAllSubscriptions();
+            if (count === 0) {
+                showToast(t('download.noNewChapters') || 'No new chapters', 'info');
+            }
+        } finally {
+            setChecking(null);
+        }
+    };
+
+    const handleUpdate = async (sub: Subscription, changes: Partial<Subscription>) => {
+        const next = { ...sub, ...changes };
+        try {
+            await (AppBackend as any).UpdateSubscription(sub.id, next.languages || [], next.groups || [], next.autoDownload);
+        } catch (err: any) {
+            showToast(err.toString(), 'error');
+        }
+    };
+
+    const handleRemove = async (id: string) => {
+        await (AppBackend as any).RemoveSubscription(id);
+    };
+
+    if (subscriptions.length === 0) return null;
+
+    const inputStyle = {
+        backgroundColor: 'var(--color-surface-tertiary)',
+        color: 'var(--color-text-primary)',
+        border: '1px solid var(--color-border)',
+    };
+
+    return (
+        <section className="mb-8">
+            <div className="flex items-center justify-between mb-4">
+                <h2 className="text-xl font-semibold" style={{ color: 'var(--color-text-primary)' }}>
+                    {t('download.subscriptions')}
+                </h2>
+                <Button
+                    onClick={handleCheckAll}
+                    variant="ghost"
+                    size="sm"
+                    className="text-sm font-medium hover:bg-transparent px-0"
+                    disabled={checking !== null}
+                >
+                    {t('download.checkAll')}
+                </Button>
+            </div>
+            <div className="space-y-2">
+                {subscriptions.map(sub => (
+                    <div key={sub.id} className="card p-3 flex items-center gap-4">
+                        <div className="flex-1 min-w-0">
+                            <div className="font-medium truncate" style={{ color: 'var(--color-text-primary)' }} title={sub.url}>
+                                {sub.seriesName}
+                            </div>
+                            <div className="text-xs flex gap-2" style={{ color: 'var(--color-text-secondary)' }}>
+                                <span>{sub.site}</span>
+                                {sub.lastCheckedAt && (
+                                    <>
+                                        <span>•</span>
+                                        <span>{t('download.lastChecked')}: {new Date(sub.lastCheckedAt).toLocaleString()}</span>
+                                    </>
+                                )}
+                                {sub.lastError && (
+                                    <>
+                                        <span>•</span>
+                                        <span className="text-red-400 truncate" title={sub.lastError}>{sub.lastError}</span>
+                                    </>
+                                )}
+                            </div>
+                        </div>
+                        <input
+                            type="text"
+                            defaultValue={(sub.languages || []).join(', ')}
+                            placeholder={t('download.filterLanguages')}
+                            onBlur={(e) => handleUpdate(sub, { languages: splitList(e.target.value) })}
+                            className="w-28 px-2 py-1 rounded text-sm"
+                            style={inputStyle}
+                        />
+                        <input
+                            type="text"
+                            defaultValue={(sub.groups || []).join(', ')}
+                            placeholder={t('download.filterGroups')}
+                            onBlur={(e) => handleUpdate(sub, { groups: splitList(e.target.value) })}
+                            className="w-36 px-2 py-1 rounded text-sm"
+                            style={inputStyle}
+                        />
+                        <div className="flex items-center gap-2">
+                            <Toggle
+                                checked={sub.autoDownload}
+                                onChange={(val) => handleUpdate(sub, { autoDownload: val })}
+                            />
+                            <span className="text-sm" style={{ color: 'var(--color-text-secondary)' }}>
+                                {t('download.autoDownload')}
+                            </span>
+                        </div>
+                        <Button
+                            onClick={() => handleCheck(sub.id)}
+                            variant="ghost"
+                            size="sm"
+                            isLoading={checking === sub.id}
+                            disabled={checking !== null}
+                        >
+                            {t('download.checkNow')}
+                        </Button>
+                        <button
+                            onClick={() => handleRemove(sub.id)}
+                            className="p-1 rounded hover:bg-red-500/10 text-red-500"
+                            title={t('download.unsubscribe')}
+                        >
+                            <svg width="16" height="16" viewBox="0 0 24 24" fill="none" stroke="currentColor" strokeWidth="2" strokeLinecap="round" strokeLinejoin="round">
+                                <path d="M18 6L6 18M6 6l12 12" />
+                            </svg>
+                        </button>
+                    </div>
+                ))}
+            </div>
+        </section>
+    );
+}
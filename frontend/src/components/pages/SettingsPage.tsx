@@ -15,6 +15,8 @@ import { Toggle } from '../common/Toggle';
 import { SectionHeader } from '../common/SectionHeader';
 import { HelpDialog } from '../common/HelpDialog';
 import { languages, changeLanguage } from '../../i18n';
+import { Settings, SiteLimit, SiteOptions, CookieSite, DownloadHook, HookEvent, DownloadWindow, DownloadSpace, MangaDexAccount } from '../../types';
+import * as AppBackend from '../../../wailsjs/go/main/App';
 
 export const SettingsPage: React.FC = () => {
     const { t } = useTranslation();
@@ -53,11 +55,38 @@ export const SettingsPage: React.FC = () => {
         tabMemorySaving,
         setTabMemorySaving,
         restoreTabs,
-        setRestoreTabs
+        setRestoreTabs,
+        siteLimits,
+        siteOptions,
+        downloadProxy,
+        downloadNamingTemplate,
+        packageCbz,
+        cbzDeleteImages,
+        animatedThumbnails,
+        autoAddToSeries,
+        hooks,
+        subscriptionCheckMinutes,
+        bandwidthLimitKbps,
+        downloadSchedule,
+        minFreeSpaceMb,
+        downloadQuotaMb,
+        duplicateAction,
+        mangadexLanguages,
+        mangadexDataSaver,
+        updateSettings
     } = useSettingsStore();
 
     const { showToast } = useToast();
     const [isResetOpen, setIsResetOpen] = useState(false);
+
+    const handleRebuildIndex = async () => {
+        try {
+            const count = await (AppBackend as any).RebuildContentIndex();
+            showToast(t('settings.rebuildIndexDone', { count }), 'success');
+        } catch (error) {
+            showToast(String(error), 'error');
+        }
+    };
     const [isClearCacheOpen, setIsClearCacheOpen] = useState(false);
     const [isHelpOpen, setIsHelpOpen] = useState(false);
 
@@ -455,6 +484,16 @@ export const SettingsPage: React.FC = () => {
                         />
                     </SettingRow>
 
+                    <SettingRow
+                        label={t('settings.animatedThumbnails', 'Animated thumbnails')}
+                        description={t('settings.animatedThumbnailsDesc', 'Play a short preview of animated GIF, WebP and AVIF pages in the thumbnail grid.')}
+                    >
+                        <Toggle
+                            checked={!!animatedThumbnails}
+                            onChange={(value) => updateSettings({ animatedThumbnails: value })}
+                        />
+                    </SettingRow>
+
                     <SettingRow label={t('settings.enableHistory')}>
                         <Toggle
                             checked={enableHistory}
@@ -516,6 +555,235 @@ export const SettingsPage: React.FC = () => {
                     </SettingRow>
                 </section>
 
+                {/* Downloads Section */}
+                <section className="animate-slide-up" style={{ animationDelay: '0.48s' }}>
+                    <SectionHeader title={t('settings.downloads', 'Downloads')} />
+                    <p className="text-sm mb-4" style={{ color: 'var(--color-text-muted)' }}>
+                        {t('settings.siteLimitsDesc', 'Limits per site. "default" applies to sites without their own row. 0 means unlimited for jobs and requests per second.')}
+                    </p>
+                    <SiteLimitsEditor
+                        limits={siteLimits || {}}
+                        options={siteOptions || {}}
+                        onChange={(limits) => updateSettings({ siteLimits: limits })}
+                        onOptionsChange={(options) => updateSettings({ siteOptions: options })}
+                    />
+
+                    <SettingRow
+                        label={t('settings.autoAddToSeries', 'Add downloads to Series')}
+                        description={t('settings.autoAddToSeriesDesc', 'Add each chapter to its series in the Series page as soon as it finishes downloading.')}
+                    >
+                        <Toggle
+                            checked={!!autoAddToSeries}
+                            onChange={(value) => updateSettings({ autoAddToSeries: value })}
+                        />
+                    </SettingRow>
+
+                    <SettingRow
+                        label={t('settings.packageCbz', 'Package chapters as CBZ')}
+                        description={t('settings.packageCbzDesc', 'Zip each completed chapter with a ComicInfo.xml. The CBZ column above overrides it per site.')}
+                    >
+                        <Toggle
+                            checked={!!packageCbz}
+                            onChange={(value) => updateSettings({ packageCbz: value })}
+                        />
+                    </SettingRow>
+
+                    <SettingRow
+                        label={t('settings.cbzDeleteImages', 'Delete images after packaging')}
+                        description={t('settings.cbzDeleteImagesDesc', 'Keep only the .cbz file once a chapter is packaged.')}
+                    >
+                        <Toggle
+                            checked={!!cbzDeleteImages}
+                            onChange={(value) => updateSettings({ cbzDeleteImages: value })}
+                        />
+                    </SettingRow>
+
+                    <SettingRow
+                        label={t('settings.duplicateAction', 'Already downloaded elsewhere')}
+                        description={t('settings.duplicateActionDesc', 'Downloads are compared by title and by their first pages with the download folder, the Library and Series, to catch the same gallery or chapter from another site.')}
+                    >
+                        <div className="flex items-center gap-2">
+                            <select
+                                value={duplicateAction || 'warn'}
+                                onChange={(e) => updateSettings({ duplicateAction: e.target.value as Settings['duplicateAction'] })}
+                                className="px-3 py-2 rounded-lg text-sm"
+                                style={{
+                                    backgroundColor: 'var(--color-surface-tertiary)',
+                                    color: 'var(--color-text-primary)',
+                                    border: '1px solid var(--color-border)',
+                                }}
+                            >
+                                <option value="warn">{t('settings.duplicateWarn', 'Warn')}</option>
+                                <option value="skip">{t('settings.duplicateSkip', 'Skip')}</option>
+                                <option value="off">{t('settings.duplicateOff', "Don't check")}</option>
+                            </select>
+                            <Button variant="outline" size="sm" onClick={handleRebuildIndex}>
+                                {t('settings.rebuildIndex', 'Rescan')}
+                            </Button>
+                        </div>
+                    </SettingRow>
+
+                    <SettingRow
+                        label={t('settings.downloadProxy', 'Proxy')}
+                        description={t('settings.downloadProxyDesc', 'HTTP or SOCKS5 proxy for downloads (http://host:port, socks5://host:port). Leave empty to use the system settings.')}
+                    >
+                        <CommitInput
+                            value={downloadProxy || ''}
+                            placeholder="socks5://127.0.0.1:1080"
+                            onChange={(proxy) => updateSettings({ downloadProxy: proxy })}
+                        />
+                    </SettingRow>
+
+                    <SettingRow
+                        label={t('settings.mangadexLanguages', 'MangaDex languages')}
+                        description={t('settings.mangadexLanguagesDesc', 'Language codes of the MangaDex chapters to list, separated by commas (en, es-la, pt-br). Leave empty for every language.')}
+                    >
+                        <CommitInput
+                            value={(mangadexLanguages || []).join(', ')}
+                            placeholder="en, es-la"
+                            onChange={(value) => updateSettings({
+                                mangadexLanguages: value.split(',').map((lang) => lang.trim()).filter(Boolean),
+                            })}
+                        />
+                    </SettingRow>
+
+                    <SettingRow
+                        label={t('settings.mangadexDataSaver', 'MangaDex data saver')}
+                        description={t('settings.mangadexDataSaverDesc', 'Download the smaller, recompressed pages MangaDex offers instead of the originals.')}
+                    >
+                        <Toggle
+                            checked={!!mangadexDataSaver}
+                            onChange={(value) => updateSettings({ mangadexDataSaver: value })}
+                        />
+                    </SettingRow>
+
+                    <SettingRow
+                        label={t('settings.bandwidthLimit', 'Bandwidth limit')}
+                        description={t('settings.bandwidthLimitDesc', 'Maximum speed for all downloads together in KB/s. 0 means unlimited. Each site can have its own cap in the table above.')}
+                    >
+                        <input
+                            type="number"
+                            min={0}
+                            step={100}
+                            value={bandwidthLimitKbps ?? 0}
+                            onChange={(e) => {
+                                const kbps = Number(e.target.value);
+                                if (!Number.isNaN(kbps) && kbps >= 0) updateSettings({ bandwidthLimitKbps: kbps });
+                            }}
+                            className="w-24 px-3 py-2 rounded-lg text-sm"
+                            style={{
+                                backgroundColor: 'var(--color-surface-tertiary)',
+                                color: 'var(--color-text-primary)',
+                                border: '1px solid var(--color-border)',
+                            }}
+                        />
+                    </SettingRow>
+
+                    <SettingRow
+                        label={t('settings.minFreeSpace', 'Keep free on disk (MB)')}
+                        description={t('settings.minFreeSpaceDesc', 'Before a download starts its size is estimated. If it would leave less than this free, the job is paused with an error instead.')}
+                    >
+                        <NumberInput
+                            value={minFreeSpaceMb ?? 500}
+                            step={100}
+                            onChange={(mb) => updateSettings({ minFreeSpaceMb: mb })}
+                        />
+                    </SettingRow>
+
+                    <SettingRow
+                        label={t('settings.downloadQuota', 'Download folder quota (MB)')}
+                        description={t('settings.downloadQuotaDesc', 'Downloads that would make the folder bigger than this are paused. 0 means no quota.')}
+                    >
+                        <NumberInput
+                            value={downloadQuotaMb ?? 0}
+                            step={1024}
+                            onChange={(mb) => updateSettings({ downloadQuotaMb: mb })}
+                        />
+                    </SettingRow>
+                    <DownloadSpaceInfo quotaMb={downloadQuotaMb ?? 0} />
+
+                    <div className="pt-4">
+                        <span className="font-medium" style={{ color: 'var(--color-text-primary)' }}>
+                            {t('settings.downloadSchedule', 'Download schedule')}
+                        </span>
+                        <p className="text-sm mt-1 mb-4" style={{ color: 'var(--color-text-muted)' }}>
+                            {t('settings.downloadScheduleDesc', 'Queued downloads only start inside these windows, the rest wait as scheduled. A download already running when a window closes finishes its chapter. A window ending before it starts runs past midnight. With no windows downloads run at any time.')}
+                        </p>
+                        <DownloadScheduleEditor
+                            windows={downloadSchedule || []}
+                            onChange={(windows) => updateSettings({ downloadSchedule: windows })}
+                        />
+                    </div>
+
+                    <div className="pt-4">
+                        <span className="font-medium" style={{ color: 'var(--color-text-primary)' }}>
+                            {t('settings.namingTemplate', 'File naming')}
+                        </span>
+                        <p className="text-sm mt-1 mb-4" style={{ color: 'var(--color-text-muted)' }}>
+                            {t('settings.namingTemplateDesc', 'Where pages are saved inside the download folder. Fields: {site} {series} {title} {chapter} {volume} {group} {lang} {page} {filename} {ext} {file} (the original file name). Must contain {chapter} or {title}. Numbers can be padded: {chapter:000.#}, {page:000}.')}
+                        </p>
+                        <NamingTemplateEditor
+                            value={downloadNamingTemplate || ''}
+                            onChange={(template) => updateSettings({ downloadNamingTemplate: template })}
+                        />
+                    </div>
+
+                    <SettingRow
+                        label={t('settings.subscriptionCheckMinutes', 'Check subscriptions every')}
+                        description={t('settings.subscriptionCheckMinutesDesc', 'Minutes between checks for new chapters. 0 checks only manually.')}
+                    >
+                        <input
+                            type="number"
+                            min={0}
+                            step={30}
+                            value={subscriptionCheckMinutes ?? 360}
+                            onChange={(e) => {
+                                const minutes = Number(e.target.value);
+                                if (!Number.isNaN(minutes) && minutes >= 0) updateSettings({ subscriptionCheckMinutes: minutes });
+                            }}
+                            className="w-24 px-3 py-2 rounded-lg text-sm"
+                            style={{
+                                backgroundColor: 'var(--color-surface-tertiary)',
+                                color: 'var(--color-text-primary)',
+                                border: '1px solid var(--color-border)',
+                            }}
+                        />
+                    </SettingRow>
+
+                    <div className="pt-4">
+                        <span className="font-medium" style={{ color: 'var(--color-text-primary)' }}>
+                            {t('settings.hooks', 'Hooks')}
+                        </span>
+                        <p className="text-sm mt-1 mb-4" style={{ color: 'var(--color-text-muted)' }}>
+                            {t('settings.hooksDesc', 'Run a command or POST the event as JSON when downloads finish. Placeholders: {event} {path} {series} {chapter} {site} {url} {error}.')}
+                        </p>
+                        <HooksEditor
+                            hooks={hooks || []}
+                            onChange={(next) => updateSettings({ hooks: next })}
+                        />
+                    </div>
+
+                    <div className="pt-4">
+                        <span className="font-medium" style={{ color: 'var(--color-text-primary)' }}>
+                            {t('settings.cookies', 'Cookies')}
+                        </span>
+                        <p className="text-sm mt-1 mb-4" style={{ color: 'var(--color-text-muted)' }}>
+                            {t('settings.cookiesDesc', 'Import a cookies.txt or JSON export from your browser for sites that need a login or Cloudflare clearance. Use the User-Agent of that same browser.')}
+                        </p>
+                        <CookiesEditor />
+                    </div>
+
+                    <div className="pt-4">
+                        <span className="font-medium" style={{ color: 'var(--color-text-primary)' }}>
+                            {t('settings.mangadexAccount', 'MangaDex account')}
+                        </span>
+                        <p className="text-sm mt-1 mb-4" style={{ color: 'var(--color-text-muted)' }}>
+                            {t('settings.mangadexAccountDesc', 'Log in with a personal API client (MangaDex settings → API Clients) to import your follows as subscriptions, check the follows feed and download your lists. Your password is not stored.')}
+                        </p>
+                        <MangaDexAccountEditor />
+                    </div>
+                </section>
+
                 {/* Danger Zone */}
                 <section className="animate-slide-up space-y-4" style={{ animationDelay: '0.5s' }}>
                     <div className="grid grid-cols-2 gap-4">
@@ -630,4 +898,714 @@ function SettingRow({
     );
 }
 
+// Per-site download limits table
+const LIMIT_FIELDS: { key: keyof Omit<SiteLimit, 'hosts'>; label: string; fallback: string; step: number; min: number }[] = [
+    { key: 'maxJobs', label: 'settings.limitJobs', fallback: 'Jobs', step: 1, min: 0 },
+    { key: 'pageConcurrency', label: 'settings.limitPages', fallback: 'Pages', step: 1, min: 1 },
+    { key: 'requestsPerSecond', label: 'settings.limitRps', fallback: 'Req/s', step: 0.5, min: 0 },
+    { key: 'maxRetries', label: 'settings.limitRetries', fallback: 'Retries', step: 1, min: 0 },
+    { key: 'backoffMs', label: 'settings.limitBackoff', fallback: 'Backoff (ms)', step: 500, min: 0 },
+];
+
+// Saved on blur so a half typed value is never used
+function CommitInput({
+    value,
+    placeholder,
+    onChange,
+}: {
+    value: string;
+    placeholder?: string;
+    onChange: (value: string) => void;
+}) {
+    const [draft, setDraft] = useState(value);
+
+    React.useEffect(() => setDraft(value), [value]);
+
+    const commit = () => {
+        const proxy = draft.trim();
+        if (proxy !== value) onChange(proxy);
+    };
+
+    return (
+        <input
+            type="text"
+            value={draft}
+            placeholder={placeholder}
+            onChange={(e) => setDraft(e.target.value)}
+            onBlur={commit}
+            onKeyDown={(e) => e.key === 'Enter' && commit()}
+            className="w-64 px-3 py-2 rounded-lg text-sm font-mono"
+            style={{
+                backgroundColor: 'var(--color-surface-tertiary)',
+                color: 'var(--color-text-primary)',
+                border: '1px solid var(--color-border)',
+            }}
+        />
+    );
+}
+
+function NamingTemplateEditor({
+    value,
+    onChange,
+}: {
+    value: string;
+    onChange: (value: string) => void;
+}) {
+    const { t } = useTranslation();
+    const { showToast } = useToast();
+    const [preview, setPreview] = useState('');
+    const [error, setError] = useState('');
+    const [isRenameOpen, setIsRenameOpen] = useState(false);
+
+    React.useEffect(() => {
+        (AppBackend as any).PreviewNamingTemplate(value)
+            .then((path: string) => {
+                setPreview(path);
+                setError('');
+            })
+            .catch((err: any) => {
+                setPreview('');
+                setError(String(err));
+            });
+    }, [value]);
+
+    const renameDownloads = async () => {
+        try {
+            const result = await (AppBackend as any).RenameDownloadsToTemplate();
+            showToast(t('settings.renameDownloadsDone', { renamed: result.renamed, errors: result.errors.length }), result.errors.length > 0 ? 'info' : 'success');
+            result.errors.forEach((err: string) => console.warn('Rename failed:', err));
+        } catch (err) {
+            showToast(`${t('settings.renameDownloadsFailed', 'Rename failed')}: ${err}`, 'error');
+        }
+    };
+
+    return (
+        <div className="space-y-2">
+            <div className="flex items-center gap-2">
+                <CommitInput
+                    value={value}
+                    placeholder="{site}/{series}/{title}/{file}"
+                    onChange={onChange}
+                />
+                <Button variant="outline" size="sm" onClick={() => setIsRenameOpen(true)} disabled={!!error}>
+                    {t('settings.renameDownloads', 'Rename existing downloads')}
+                </Button>
+            </div>
+            <p className="text-xs font-mono break-all" style={{ color: error ? '#ef4444' : 'var(--color-text-secondary)' }}>
+                {error || preview}
+            </p>
+
+            <ConfirmDialog
+                isOpen={isRenameOpen}
+                onClose={() => setIsRenameOpen(false)}
+                onConfirm={renameDownloads}
+                title={t('settings.renameDownloads', 'Rename existing downloads')}
+                message={t('settings.confirmRenameDownloads', 'Completed downloads will be moved to the paths of the current template. Chapters with missing pages are left as they are.')}
+                isDestructive={false}
+                confirmText={t('common.confirm') || 'Confirm'}
+                cancelText={t('common.cancel') || 'Cancel'}
+            />
+        </div>
+    );
+}
+
+// Non-negative number setting
+function NumberInput({ value, step, onChange }: { value: number; step: number; onChange: (value: number) => void }) {
+    return (
+        <input
+            type="number"
+            min={0}
+            step={step}
+            value={value}
+            onChange={(e) => {
+                const n = Number(e.target.value);
+                if (!Number.isNaN(n) && n >= 0) onChange(n);
+            }}
+            className="w-24 px-3 py-2 rounded-lg text-sm"
+            style={{
+                backgroundColor: 'var(--color-surface-tertiary)',
+                color: 'var(--color-text-primary)',
+                border: '1px solid var(--color-border)',
+            }}
+        />
+    );
+}
+
+const formatBytes = (bytes: number) => {
+    const units = ['B', 'KB', 'MB', 'GB', 'TB'];
+    let value = bytes;
+    let unit = 0;
+    while (value >= 1024 && unit < units.length - 1) {
+        value /= 1024;
+        unit++;
+    }
+    return `${value.toFixed(unit === 0 ? 0 : 1)} ${units[unit]}`;
+};
+
+// Size of the download folder and free space, refreshed when the quota changes
+function DownloadSpaceInfo({ quotaMb }: { quotaMb: number }) {
+    const { t } = useTranslation();
+    const [space, setSpace] = useState<DownloadSpace | null>(null);
+
+    React.useEffect(() => {
+        (AppBackend as any).GetDownloadSpace().then(setSpace).catch(() => setSpace(null));
+    }, [quotaMb]);
+
+    if (!space) return null;
+    return (
+        <p className="text-sm pb-2" style={{ color: 'var(--color-text-muted)' }}>
+            {t('settings.downloadSpaceUsed', { used: formatBytes(space.used) })}
+            {space.quota > 0 && ` / ${formatBytes(space.quota)}`}
+            {space.free >= 0 && ` · ${t('settings.downloadSpaceFree', { free: formatBytes(space.free) })}`}
+        </p>
+    );
+}
+
+// Download windows, days are 0 = Sunday like the backend
+function DownloadScheduleEditor({
+    windows,
+    onChange,
+}: {
+    windows: DownloadWindow[];
+    onChange: (windows: DownloadWindow[]) => void;
+}) {
+    const { t, i18n } = useTranslation();
+
+    const inputStyle = {
+        backgroundColor: 'var(--color-surface-tertiary)',
+        color: 'var(--color-text-primary)',
+        border: '1px solid var(--color-border)',
+    };
+
+    // 2023-01-01 was a Sunday
+    const dayName = (day: number) => new Date(2023, 0, 1 + day).toLocaleDateString(i18n.language, { weekday: 'short' });
+
+    const updateWindow = (index: number, changes: Partial<DownloadWindow>) => {
+        onChange(windows.map((w, i) => (i === index ? { ...w, ...changes } : w)));
+    };
+
+    const toggleDay = (index: number, day: number) => {
+        const days = windows[index].days || [];
+        const next = days.includes(day) ? days.filter(d => d !== day) : [...days, day].sort();
+        updateWindow(index, { days: next });
+    };
+
+    return (
+        <div className="space-y-2">
+            {windows.map((w, index) => (
+                <div key={index} className="flex items-center gap-2 flex-wrap">
+                    <input
+                        type="time"
+                        value={w.start}
+                        onChange={(e) => updateWindow(index, { start: e.target.value })}
+                        className="px-2 py-1 rounded text-sm"
+                        style={inputStyle}
+                    />
+                    <span style={{ color: 'var(--color-text-secondary)' }}>–</span>
+                    <input
+                        type="time"
+                        value={w.end}
+                        onChange={(e) => updateWindow(index, { end: e.target.value })}
+                        className="px-2 py-1 rounded text-sm"
+                        style={inputStyle}
+                    />
+                    {[0, 1, 2, 3, 4, 5, 6].map(day => (
+                        <label key={day} className="flex items-center gap-1 text-sm" style={{ color: 'var(--color-text-secondary)' }}>
+                            <input
+                                type="checkbox"
+                                checked={(w.days || []).includes(day)}
+                                onChange={() => toggleDay(index, day)}
+                            />
+                            {dayName(day)}
+                        </label>
+                    ))}
+                    <button
+                        onClick={() => onChange(windows.filter((_, i) => i !== index))}
+                        className="p-1 hover:bg-white/10 rounded transition-colors ml-auto"
+                        style={{ color: 'var(--color-text-secondary)' }}
+                        aria-label={t('common.remove') || 'Remove'}
+                    >
+                        <Trash2 className="w-4 h-4" />
+                    </button>
+                </div>
+            ))}
+            {windows.length > 0 && (
+                <p className="text-xs" style={{ color: 'var(--color-text-muted)' }}>
+                    {t('settings.downloadScheduleDays', 'No day checked means every day.')}
+                </p>
+            )}
+            <Button
+                variant="outline"
+                size="sm"
+                onClick={() => onChange([...windows, { start: '22:00', end: '07:00' }])}
+            >
+                {t('settings.downloadScheduleAdd', 'Add window')}
+            </Button>
+        </div>
+    );
+}
+
+const HOOK_EVENTS: { key: HookEvent; label: string; fallback: string }[] = [
+    { key: 'completed', label: 'settings.hookCompleted', fallback: 'Completed' },
+    { key: 'completed_with_errors', label: 'settings.hookCompletedWithErrors', fallback: 'Completed with missing pages' },
+    { key: 'failed', label: 'settings.hookFailed', fallback: 'Failed' },
+    { key: 'batch_completed', label: 'settings.hookBatchCompleted', fallback: 'Series batch done' },
+    { key: 'new_chapters', label: 'settings.hookNewChapters', fallback: 'New chapters' },
+];
+
+function HooksEditor({
+    hooks,
+    onChange,
+}: {
+    hooks: DownloadHook[];
+    onChange: (hooks: DownloadHook[]) => void;
+}) {
+    const { t } = useTranslation();
+    const { showToast } = useToast();
+    const [logs, setLogs] = useState<Record<string, string>>({});
+
+    const inputStyle = {
+        backgroundColor: 'var(--color-surface-tertiary)',
+        color: 'var(--color-text-primary)',
+        border: '1px solid var(--color-border)',
+    };
+
+    const updateHook = (id: string, changes: Partial<DownloadHook>) => {
+        onChange(hooks.map(h => (h.id === id ? { ...h, ...changes } : h)));
+    };
+
+    const toggleEvent = (hook: DownloadHook, event: HookEvent) => {
+        const events = hook.events.includes(event) ? hook.events.filter(e => e !== event) : [...hook.events, event];
+        updateHook(hook.id, { events });
+    };
+
+    const addHook = () => {
+        onChange([...hooks, { id: `hook-${Date.now()}`, name: t('settings.hookNew', 'New hook'), enabled: true, events: ['completed'], timeoutSeconds: 30 }]);
+    };
+
+    const testHook = async (id: string) => {
+        try {
+            await (AppBackend as any).TestHook(id);
+            showToast(t('settings.hookTestOk', 'Hook ran successfully'), 'success');
+        } catch (error) {
+            showToast(`${t('settings.hookTestFailed', 'Hook failed')}: ${error}`, 'error');
+        }
+        showLog(id, true);
+    };
+
+    const showLog = async (id: string, keepOpen = false) => {
+        if (logs[id] !== undefined && !keepOpen) {
+            const next = { ...logs };
+            delete next[id];
+            setLogs(next);
+            return;
+        }
+        const log = await (AppBackend as any).GetHookLog(id);
+        setLogs(prev => ({ ...prev, [id]: log || t('settings.hookLogEmpty', 'No runs yet') }));
+    };
+
+    return (
+        <div className="space-y-3">
+            {hooks.map(hook => (
+                <div key={hook.id} className="p-3 rounded-lg space-y-2" style={{ border: '1px solid var(--color-border)' }}>
+                    <div className="flex items-center gap-2">
+                        <Toggle checked={hook.enabled} onChange={(enabled) => updateHook(hook.id, { enabled })} />
+                        <CommitInput value={hook.name} onChange={(name) => updateHook(hook.id, { name })} />
+                        <input
+                            type="number"
+                            min={0}
+                            value={hook.timeoutSeconds ?? 30}
+                            onChange={(e) => {
+                                const seconds = Number(e.target.value);
+                                if (!Number.isNaN(seconds) && seconds >= 0) updateHook(hook.id, { timeoutSeconds: seconds });
+                            }}
+                            className="w-20 px-2 py-2 rounded-lg text-sm"
+                            style={inputStyle}
+                            title={t('settings.hookTimeout', 'Timeout (seconds)')}
+                        />
+                        <div className="ml-auto flex items-center gap-1">
+                            <Button variant="outline" size="sm" onClick={() => testHook(hook.id)}>
+                                {t('settings.hookTest', 'Test')}
+                            </Button>
+                            <Button variant="outline" size="sm" onClick={() => showLog(hook.id)}>
+                                {t('settings.hookLog', 'Log')}
+                            </Button>
+                            <button
+                                onClick={() => onChange(hooks.filter(h => h.id !== hook.id))}
+                                className="p-1 hover:bg-white/10 rounded transition-colors"
+                                style={{ color: 'var(--color-text-secondary)' }}
+                                aria-label={t('common.remove') || 'Remove'}
+                            >
+                                <Trash2 className="w-4 h-4" />
+                            </button>
+                        </div>
+                    </div>
+                    <div className="flex flex-wrap gap-3 text-sm" style={{ color: 'var(--color-text-secondary)' }}>
+                        {HOOK_EVENTS.map(event => (
+                            <label key={event.key} className="flex items-center gap-1">
+                                <input type="checkbox" checked={hook.events.includes(event.key)} onChange={() => toggleEvent(hook, event.key)} />
+                                {t(event.label, event.fallback)}
+                            </label>
+                        ))}
+                    </div>
+                    <CommitInput
+                        value={hook.command || ''}
+                        placeholder={t('settings.hookCommandPlaceholder', 'Command, e.g. rclone copy "{path}" nas:manga')}
+                        onChange={(command) => updateHook(hook.id, { command })}
+                    />
+                    <CommitInput
+                        value={hook.webhookUrl || ''}
+                        placeholder={t('settings.hookWebhookPlaceholder', 'Webhook URL, e.g. http://localhost:8080/hook')}
+                        onChange={(webhookUrl) => updateHook(hook.id, { webhookUrl })}
+                    />
+                    {logs[hook.id] !== undefined && (
+                        <pre className="text-xs p-2 rounded max-h-48 overflow-auto whitespace-pre-wrap" style={inputStyle}>
+                            {logs[hook.id]}
+                        </pre>
+                    )}
+                </div>
+            ))}
+            <Button onClick={addHook} variant="outline" size="sm">
+                {t('settings.hookAdd', 'Add hook')}
+            </Button>
+        </div>
+    );
+}
+
+function MangaDexAccountEditor() {
+    const { t } = useTranslation();
+    const { showToast } = useToast();
+    const [account, setAccount] = useState<MangaDexAccount | null>(null);
+    const [form, setForm] = useState({ clientId: '', clientSecret: '', username: '', password: '' });
+    const [loading, setLoading] = useState(false);
+
+    React.useEffect(() => {
+        (async () => {
+            try {
+                const acc: MangaDexAccount = await (AppBackend as any).GetMangaDexAccount();
+                setAccount(acc);
+                setForm(f => ({ ...f, clientId: acc.clientId || '' }));
+            } catch (error) {
+                console.error('Failed to load MangaDex account:', error);
+            }
+        })();
+    }, []);
+
+    const login = async () => {
+        setLoading(true);
+        try {
+            const acc = await (AppBackend as any).MangaDexLogin(form.clientId, form.clientSecret, form.username, form.password);
+            setAccount(acc);
+            setForm(f => ({ ...f, clientSecret: '', password: '' }));
+            showToast(t('settings.mangadexLoggedIn', { username: acc.username }), 'success');
+        } catch (error) {
+            showToast(`${error}`, 'error');
+        } finally {
+            setLoading(false);
+        }
+    };
+
+    const logout = async () => {
+        await (AppBackend as any).MangaDexLogout();
+        setAccount(await (AppBackend as any).GetMangaDexAccount());
+    };
+
+    const inputStyle = {
+        backgroundColor: 'var(--color-surface-tertiary)',
+        color: 'var(--color-text-primary)',
+        border: '1px solid var(--color-border)',
+    };
+
+    if (account?.loggedIn) {
+        return (
+            <div className="flex items-center gap-4 text-sm">
+                <span style={{ color: 'var(--color-text-primary)' }}>
+                    {t('settings.mangadexLoggedIn', { username: account.username })}
+                </span>
+                {account.feedCheckedAt && (
+                    <span style={{ color: 'var(--color-text-secondary)' }}>
+                        {t('settings.mangadexFeedChecked', 'Feed checked')}: {new Date(account.feedCheckedAt).toLocaleString()}
+                    </span>
+                )}
+                <Button onClick={logout} variant="outline" size="sm">
+                    {t('settings.mangadexLogout', 'Log out')}
+                </Button>
+            </div>
+        );
+    }
+
+    const fields: { key: keyof typeof form; label: string; type: string }[] = [
+        { key: 'clientId', label: t('settings.mangadexClientId', 'Client ID'), type: 'text' },
+        { key: 'clientSecret', label: t('settings.mangadexClientSecret', 'Client secret'), type: 'password' },
+        { key: 'username', label: t('settings.mangadexUsername', 'Username'), type: 'text' },
+        { key: 'password', label: t('settings.mangadexPassword', 'Password'), type: 'password' },
+    ];
+
+    return (
+        <div className="flex flex-wrap gap-2">
+            {fields.map(field => (
+                <input
+                    key={field.key}
+                    type={field.type}
+                    value={form[field.key]}
+                    placeholder={field.label}
+                    onChange={(e) => setForm(f => ({ ...f, [field.key]: e.target.value }))}
+                    onKeyDown={(e) => e.key === 'Enter' && login()}
+                    className="w-48 px-3 py-2 rounded-lg text-sm"
+                    style={inputStyle}
+                />
+            ))}
+            <Button onClick={login} variant="outline" size="sm" isLoading={loading}>
+                {t('settings.mangadexLogin', 'Log in')}
+            </Button>
+        </div>
+    );
+}
+
+function CookiesEditor() {
+    const { t } = useTranslation();
+    const { showToast } = useToast();
+    const [sites, setSites] = useState<CookieSite[]>([]);
+    const [userAgent, setUserAgent] = useState('');
+
+    const loadSites = async () => {
+        try {
+            setSites((await (AppBackend as any).GetCookieSites()) || []);
+        } catch (error) {
+            console.error('Failed to load cookies:', error);
+        }
+    };
+
+    React.useEffect(() => {
+        loadSites();
+    }, []);
+
+    const importCookies = async () => {
+        try {
+            const count = await (AppBackend as any).ImportCookies(userAgent.trim());
+            if (count > 0) {
+                showToast(t('settings.cookiesImported', { count }), 'success');
+                loadSites();
+            }
+        } catch (error) {
+            showToast(`${t('settings.cookiesImportFailed', 'Import failed')}: ${error}`, 'error');
+        }
+    };
+
+    const setSiteUserAgent = async (domain: string, ua: string) => {
+        await (AppBackend as any).SetSiteUserAgent(domain, ua);
+        loadSites();
+    };
+
+    const clearSite = async (domain: string) => {
+        await (AppBackend as any).ClearSiteCookies(domain);
+        loadSites();
+    };
+
+    return (
+        <div className="space-y-2">
+            {sites.length > 0 && (
+                <table className="w-full text-sm">
+                    <thead>
+                        <tr style={{ color: 'var(--color-text-secondary)' }}>
+                            <th className="text-left font-medium py-2">{t('settings.cookiesDomain', 'Domain')}</th>
+                            <th className="text-left font-medium py-2">{t('settings.cookiesCount', 'Cookies')}</th>
+                            <th className="text-left font-medium py-2">{t('settings.cookiesExpires', 'Expires')}</th>
+                            <th className="text-left font-medium py-2">User-Agent</th>
+                            <th />
+                        </tr>
+                    </thead>
+                    <tbody>
+                        {sites.map(site => (
+                            <tr key={site.domain}>
+                                <td className="py-1 pr-2 font-mono" style={{ color: 'var(--color-text-primary)' }}>{site.domain}</td>
+                                <td className="py-1 pr-2" style={{ color: 'var(--color-text-secondary)' }}>{site.cookies}</td>
+                                <td className="py-1 pr-2" style={{ color: 'var(--color-text-secondary)' }}>
+                                    {site.expiresAt ? new Date(site.expiresAt).toLocaleDateString() : t('settings.cookiesSession', 'Session')}
+                                </td>
+                                <td className="py-1 pr-2">
+                                    <CommitInput
+                                        value={site.userAgent}
+                                        placeholder={t('settings.cookiesDefaultUserAgent', 'Default')}
+                                        onChange={(ua) => setSiteUserAgent(site.domain, ua)}
+                                    />
+                                </td>
+                                <td className="py-1 text-right">
+                                    <button
+                                        onClick={() => clearSite(site.domain)}
+                                        className="p-1 rounded hover:bg-red-500/10 text-red-500"
+                                        title={t('settings.cookiesClear', 'Remove cookies')}
+                                    >
+                                        <Trash2 className="w-4 h-4" />
+                                    </button>
+                                </td>
+                            </tr>
+                        ))}
+                    </tbody>
+                </table>
+            )}
+            <div className="flex gap-2 pt-2">
+                <input
+                    type="text"
+                    value={userAgent}
+                    onChange={(e) => setUserAgent(e.target.value)}
+                    placeholder={t('settings.cookiesUserAgentPlaceholder', 'User-Agent of the browser (optional)')}
+                    className="flex-1 px-3 py-2 rounded-lg text-sm font-mono"
+                    style={{
+                        backgroundColor: 'var(--color-surface-tertiary)',
+                        color: 'var(--color-text-primary)',
+                        border: '1px solid var(--color-border)',
+                    }}
+                />
+                <Button onClick={importCookies} size="sm" variant="secondary">
+                    {t('settings.cookiesImport', 'Import cookies')}
+                </Button>
+            </div>
+        </div>
+    );
+}
+
+const NEW_SITE_LIMIT: SiteLimit = { maxJobs: 3, pageConcurrency: 2, requestsPerSecond: 0, maxRetries: 3, backoffMs: 2000 };
+
+function SiteLimitsEditor({
+    limits,
+    options,
+    onChange,
+    onOptionsChange,
+}: {
+    limits: Record<string, SiteLimit>;
+    options: Record<string, SiteOptions>;
+    onChange: (limits: Record<string, SiteLimit>) => void;
+    onOptionsChange: (options: Record<string, SiteOptions>) => void;
+}) {
+    const { t } = useTranslation();
+    const [newSite, setNewSite] = useState('');
+
+    // "default" first, then alphabetical
+    const sites = Object.keys(limits).sort((a, b) => (a === 'default' ? -1 : b === 'default' ? 1 : a.localeCompare(b)));
+
+    const inputStyle = {
+        backgroundColor: 'var(--color-surface-tertiary)',
+        color: 'var(--color-text-primary)',
+        border: '1px solid var(--color-border)',
+    };
+
+    const updateField = (site: string, key: keyof SiteLimit, value: number) => {
+        if (Number.isNaN(value)) return;
+        onChange({ ...limits, [site]: { ...limits[site], [key]: value } });
+    };
+
+    const updateOption = (site: string, value: SiteOptions) => {
+        onOptionsChange({ ...options, [site]: { ...options[site], ...value } });
+    };
+
+    const removeSite = (site: string) => {
+        const next = { ...limits };
+        delete next[site];
+        onChange(next);
+        if (options[site]) {
+            const nextOptions = { ...options };
+            delete nextOptions[site];
+            onOptionsChange(nextOptions);
+        }
+    };
+
+    const addSite = () => {
+        const site = newSite.trim();
+        if (!site || limits[site]) return;
+        onChange({ ...limits, [site]: { ...(limits['default'] || NEW_SITE_LIMIT), hosts: [] } });
+        setNewSite('');
+    };
+
+    return (
+        <div className="space-y-2">
+            <table className="w-full text-sm">
+                <thead>
+                    <tr style={{ color: 'var(--color-text-secondary)' }}>
+                        <th className="text-left font-medium py-2">{t('settings.limitSite', 'Site')}</th>
+                        {LIMIT_FIELDS.map(field => (
+                            <th key={field.key} className="text-left font-medium py-2">{t(field.label, field.fallback)}</th>
+                        ))}
+                        <th className="text-left font-medium py-2">{t('settings.limitBandwidth', 'KB/s')}</th>
+                        <th className="text-left font-medium py-2">{t('settings.limitPackageCbz', 'CBZ')}</th>
+                        <th />
+                    </tr>
+                </thead>
+                <tbody>
+                    {sites.map(site => (
+                        <tr key={site}>
+                            <td className="py-1 pr-2 font-mono" style={{ color: 'var(--color-text-primary)' }}>{site}</td>
+                            {LIMIT_FIELDS.map(field => (
+                                <td key={field.key} className="py-1 pr-2">
+                                    <input
+                                        type="number"
+                                        min={field.min}
+                                        step={field.step}
+                                        value={limits[site]?.[field.key] ?? 0}
+                                        onChange={(e) => updateField(site, field.key, Number(e.target.value))}
+                                        className="w-24 px-2 py-1 rounded"
+                                        style={inputStyle}
+                                    />
+                                </td>
+                            ))}
+                            <td className="py-1 pr-2">
+                                <input
+                                    type="number"
+                                    min={0}
+                                    step={100}
+                                    value={options[site]?.bandwidthKbps ?? 0}
+                                    onChange={(e) => {
+                                        const value = Number(e.target.value);
+                                        if (!Number.isNaN(value)) updateOption(site, { bandwidthKbps: value });
+                                    }}
+                                    className="w-24 px-2 py-1 rounded"
+                                    style={inputStyle}
+                                />
+                            </td>
+                            <td className="py-1 pr-2">
+                                <select
+                                    value={options[site]?.packageCbz === undefined ? '' : options[site]?.packageCbz ? 'on' : 'off'}
+                                    onChange={(e) => updateOption(site, { packageCbz: e.target.value === '' ? undefined : e.target.value === 'on' })}
+                                    className="px-2 py-1 rounded"
+                                    style={inputStyle}
+                                >
+                                    <option value="">{t('settings.limitPackageCbzGlobal', 'Global')}</option>
+                                    <option value="on">{t('settings.limitPackageCbzOn', 'On')}</option>
+                                    <option value="off">{t('settings.limitPackageCbzOff', 'Off')}</option>
+                                </select>
+                            </td>
+                            <td className="py-1 text-right">
+                                {site !== 'default' && (
+                                    <button
+                                        onClick={() => removeSite(site)}
+                                        className="p-1 hover:bg-white/10 rounded transition-colors"
+                                        style={{ color: 'var(--color-text-secondary)' }}
+                                        aria-label={t('common.remove') || 'Remove'}
+                                    >
+                                        <Trash2 className="w-4 h-4" />
+                                    </button>
+                                )}
+                            </td>
+                        </tr>
+                    ))}
+                </tbody>
+            </table>
+            <div className="flex items-center gap-2">
+                <input
+                    type="text"
+                    value={newSite}
+                    onChange={(e) => setNewSite(e.target.value)}
+                    onKeyDown={(e) => e.key === 'Enter' && addSite()}
+                    placeholder={t('settings.limitSitePlaceholder', 'Site ID, e.g. nhentai.net')}
+                    className="px-3 py-1.5 rounded text-sm"
+                    style={inputStyle}
+                />
+                <Button onClick={addSite} variant="outline" size="sm">
+                    {t('settings.limitAddSite', 'Add site')}
+                </Button>
+            </div>
+        </div>
+    );
+}
+
 export default SettingsPage;
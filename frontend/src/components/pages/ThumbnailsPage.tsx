@@ -200,8 +200,9 @@ export function ThumbnailsPage({ folderPath, isActive = true, tabId }: Thumbnail
                 // Don't load all thumbnails at once - they will be loaded lazy when visible
                 const initialThumbs: Record<string, string> = {};
                 for (const img of imageList) {
-                    if (img.thumbnailUrl) {
-                        initialThumbs[img.path] = img.thumbnailUrl;
+                    // Animated pages get their preview when enabled in settings
+                    if (img.previewUrl || img.thumbnailUrl) {
+                        initialThumbs[img.path] = img.previewUrl || img.thumbnailUrl;
                     }
                 }
                 setThumbnails(initialThumbs);
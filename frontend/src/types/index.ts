@@ -21,6 +21,10 @@ export interface ImageInfo {
     size: number;
     /** Index in the current folder */
     index: number;
+    /** True for multi-frame GIF/WebP/AVIF pages */
+    animated?: boolean;
+    /** Animated thumbnail URL (only when animated thumbnails are enabled) */
+    previewUrl?: string;
 }
 
 export interface FolderInfo {
@@ -122,6 +126,134 @@ export interface Settings {
     tabMemorySaving: boolean;
     /** Restore tabs on startup */
     restoreTabs: boolean;
+    /** Animated previews for GIF/WebP/AVIF pages in thumbnail grids */
+    animatedThumbnails?: boolean;
+    /** Download limits per site ID ("default" for the rest) */
+    siteLimits?: Record<string, SiteLimit>;
+    /** Output and bandwidth options per site ID ("default" for the rest) */
+    siteOptions?: Record<string, SiteOptions>;
+    /** Proxy for downloads (http:// or socks5://) */
+    downloadProxy?: string;
+    downloadNamingTemplate?: string;
+    /** Add completed chapters to the Series page right away */
+    autoAddToSeries?: boolean;
+    /** Commands and webhooks run on download events */
+    hooks?: DownloadHook[];
+    /** Package completed chapters as CBZ, sites can override it in siteOptions */
+    packageCbz?: boolean;
+    /** Delete the loose images after packaging a chapter as CBZ */
+    cbzDeleteImages?: boolean;
+    /** Minutes between subscription checks (0 = manual only) */
+    subscriptionCheckMinutes?: number;
+    /** Download speed cap for all downloads together in KB/s (0 = unlimited) */
+    bandwidthLimitKbps?: number;
+    /** Windows in which queued downloads may start (empty = any time) */
+    downloadSchedule?: DownloadWindow[];
+    /** Space in MB left free on the download disk */
+    minFreeSpaceMb?: number;
+    /** Maximum size of the download folder in MB (0 = no quota) */
+    downloadQuotaMb?: number;
+    /** What to do with downloads already on disk from another source */
+    duplicateAction?: 'warn' | 'skip' | 'off';
+    mangadexLanguages?: string[];
+    mangadexDataSaver?: boolean;
+}
+
+export interface DownloadWindow {
+    /** Days of the week, 0 = Sunday (empty = every day) */
+    days?: number[];
+    /** "HH:MM", local time. An end before the start runs past midnight */
+    start: string;
+    end: string;
+}
+
+export interface DownloadSpace {
+    /** Bytes used by the download folder */
+    used: number;
+    /** Bytes free on its disk, -1 when unknown */
+    free: number;
+    /** Quota in bytes (0 = none) */
+    quota: number;
+}
+
+export interface ScheduleStatus {
+    /** False when no valid window is configured */
+    enabled: boolean;
+    open: boolean;
+    /** When the window opens or closes next (ISO string) */
+    nextChange?: string;
+}
+
+export interface Subscription {
+    id: string;
+    url: string;
+    site: string;
+    seriesName: string;
+    languages?: string[];
+    groups?: string[];
+    autoDownload: boolean;
+    knownChapters: string[];
+    createdAt: string;
+    lastCheckedAt?: string;
+    lastError?: string;
+}
+
+export interface MangaDexAccount {
+    loggedIn: boolean;
+    username: string;
+    clientId: string;
+    feedCheckedAt: string;
+}
+
+export interface MangaDexList {
+    id: string;
+    name: string;
+    visibility: 'public' | 'private';
+    titles: number;
+}
+
+export interface CookieSite {
+    domain: string;
+    cookies: number;
+    userAgent: string;
+    expiresAt: string;
+}
+
+export type HookEvent = 'completed' | 'completed_with_errors' | 'failed' | 'batch_completed' | 'new_chapters';
+
+export interface DownloadHook {
+    id: string;
+    name: string;
+    enabled: boolean;
+    events: HookEvent[];
+    /** Command line run without a shell, with {event} {path} {series} {chapter} {site} {url} {error} placeholders */
+    command?: string;
+    /** URL the event is POSTed to as JSON */
+    webhookUrl?: string;
+    /** Seconds before the hook is stopped (0 = 30) */
+    timeoutSeconds?: number;
+}
+
+export interface SiteLimit {
+    /** Jobs downloaded at the same time (0 = unlimited) */
+    maxJobs: number;
+    /** Pages downloaded in parallel within one job */
+    pageConcurrency: number;
+    /** Requests per second to each host of the site (0 = unlimited) */
+    requestsPerSecond: number;
+    /** Retries for a failed page */
+    maxRetries: number;
+    /** Delay before the first retry, doubled on every attempt */
+    backoffMs: number;
+    /** Extra host suffixes (CDNs, APIs) belonging to the site */
+    hosts?: string[];
+}
+
+export interface SiteOptions {
+    /** Package completed chapters as CBZ, overriding the global setting (unset = follow it) */
+    packageCbz?: boolean;
+    /** Download speed cap for the site in KB/s (0 = unlimited) */
+    bandwidthKbps?: number;
 }
 
 
@@ -1,8 +1,8 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 import {persistence} from '../models';
-import {explorer} from '../models';
 import {downloader} from '../models';
+import {explorer} from '../models';
 import {series} from '../models';
 
 export function AddBaseFolder(arg1:string):Promise<void>;
@@ -17,6 +17,14 @@ export function AddHistory(arg1:persistence.HistoryEntry):Promise<void>;
 
 export function AddSeries(arg1:string,arg2:Array<persistence.FolderInfo>,arg3:boolean):Promise<persistence.AddFolderResult>;
 
+export function AddSubscription(arg1:string,arg2:Array<string>,arg3:Array<string>,arg4:boolean):Promise<persistence.Subscription>;
+
+export function CheckAllSubscriptions():Promise<number>;
+
+export function CheckMangaDexFeed():Promise<number>;
+
+export function CheckSubscription(arg1:string):Promise<Array<downloader.ChapterInfo>>;
+
 export function ClearAllData():Promise<void>;
 
 export function ClearDownloadHistory():Promise<void>;
@@ -27,18 +35,28 @@ export function ClearLibrary():Promise<void>;
 
 export function ClearSeries():Promise<void>;
 
+export function ClearSiteCookies(arg1:string):Promise<void>;
+
 export function ClearThumbnailCache():Promise<void>;
 
+export function DownloadMangaDexList(arg1:string,arg2:downloader.SeriesFilter):Promise<Array<downloader.SeriesBatch>>;
+
 export function ExploreFolder(arg1:string):Promise<Array<explorer.ExplorerEntry>>;
 
 export function FetchMangaInfo(arg1:string):Promise<downloader.SiteInfo>;
 
 export function GetBaseFolders():Promise<Array<explorer.BaseFolderEntry>>;
 
+export function GetBatchProgress(arg1:string):Promise<downloader.BatchProgress>;
+
 export function GetChapterNavigation(arg1:string):Promise<series.ChapterNavigation>;
 
+export function GetCookieSites():Promise<Array<downloader.CookieSite>>;
+
 export function GetDownloadHistory():Promise<Array<persistence.DownloadJob>>;
 
+export function GetDownloadSpace():Promise<downloader.DownloadSpace>;
+
 export function GetFolderInfo(arg1:string):Promise<persistence.FolderInfo>;
 
 export function GetFolderInfoShallow(arg1:string):Promise<persistence.FolderInfo>;
@@ -47,6 +65,8 @@ export function GetHistory():Promise<Array<persistence.HistoryEntry>>;
 
 export function GetHistoryEntry(arg1:string):Promise<persistence.HistoryEntry>;
 
+export function GetHookLog(arg1:string):Promise<string>;
+
 export function GetImageOrder(arg1:string):Promise<Array<string>>;
 
 export function GetImages(arg1:string):Promise<Array<persistence.ImageInfo>>;
@@ -55,14 +75,28 @@ export function GetImagesShallow(arg1:string):Promise<Array<persistence.ImageInf
 
 export function GetLibrary():Promise<Array<persistence.FolderInfo>>;
 
+export function GetMangaDexAccount():Promise<downloader.MangaDexAccount>;
+
+export function GetMangaDexLists():Promise<Array<downloader.MangaDexList>>;
+
 export function GetOriginalOrder(arg1:string):Promise<Array<string>>;
 
+export function GetPluginsPath():Promise<string>;
+
+export function GetQueuePositions():Promise<Record<string, number>>;
+
+export function GetScheduleStatus():Promise<downloader.ScheduleStatus>;
+
+export function GetScrapersPath():Promise<string>;
+
 export function GetSeries():Promise<Array<series.SeriesEntryWithURLs>>;
 
 export function GetSettings():Promise<persistence.Settings>;
 
 export function GetSubfolders(arg1:string):Promise<Array<persistence.FolderInfo>>;
 
+export function GetSubscriptions():Promise<Array<persistence.Subscription>>;
+
 export function GetTabs():Promise<persistence.TabsData>;
 
 export function GetThumbnail(arg1:string):Promise<string>;
@@ -71,12 +105,38 @@ export function GetViewerState(arg1:string):Promise<persistence.ViewerState>;
 
 export function HasCustomOrder(arg1:string):Promise<boolean>;
 
+export function ImportCookies(arg1:string):Promise<number>;
+
+export function ImportMangaDexTitles(arg1:string,arg2:boolean):Promise<number>;
+
 export function IsSeries(arg1:string):Promise<boolean>;
 
+export function MangaDexLogin(arg1:string,arg2:string,arg3:string,arg4:string):Promise<downloader.MangaDexAccount>;
+
+export function MangaDexLogout():Promise<void>;
+
+export function MoveDownloadJob(arg1:string,arg2:number):Promise<void>;
+
 export function OpenInFileManager(arg1:string):Promise<void>;
 
+export function PackageJobCBZ(arg1:string):Promise<void>;
+
+export function PauseAllDownloads():Promise<void>;
+
+export function PauseDownloadJob(arg1:string):Promise<void>;
+
 export function PreloadThumbnails(arg1:Array<string>):Promise<void>;
 
+export function PreviewNamingTemplate(arg1:string):Promise<string>;
+
+export function PrioritizeDownloadJob(arg1:string):Promise<void>;
+
+export function RebuildContentIndex():Promise<number>;
+
+export function ReloadPlugins():Promise<void>;
+
+export function ReloadScrapers():Promise<void>;
+
 export function RemoveBaseFolder(arg1:string):Promise<void>;
 
 export function RemoveDownloadJob(arg1:string):Promise<void>;
@@ -87,12 +147,22 @@ export function RemoveLibraryEntry(arg1:string):Promise<void>;
 
 export function RemoveSeries(arg1:string):Promise<void>;
 
+export function RemoveSubscription(arg1:string):Promise<void>;
+
+export function RenameDownloadsToTemplate():Promise<downloader.RenameResult>;
+
 export function ResetImageOrder(arg1:string):Promise<void>;
 
 export function ResolveFolder(arg1:string):Promise<string>;
 
+export function ResumeAllDownloads():Promise<void>;
+
+export function ResumeDownloadJob(arg1:string):Promise<void>;
+
 export function ResumeIncompleteDownloads(arg1:boolean):Promise<void>;
 
+export function RetryFailedPages(arg1:string):Promise<void>;
+
 export function SaveImageOrder(arg1:string,arg2:Array<string>,arg3:Array<string>):Promise<void>;
 
 export function SaveSettings(arg1:persistence.Settings):Promise<void>;
@@ -105,10 +175,20 @@ export function SaveWindowState():Promise<void>;
 
 export function SelectFolder():Promise<string>;
 
+export function SetDownloadJobPriority(arg1:string,arg2:number):Promise<void>;
+
+export function SetSiteUserAgent(arg1:string,arg2:string):Promise<void>;
+
 export function StartDownload(arg1:string,arg2:string,arg3:string):Promise<string>;
 
+export function StartSeriesDownload(arg1:string,arg2:downloader.SeriesFilter):Promise<downloader.SeriesBatch>;
+
+export function TestHook(arg1:string):Promise<void>;
+
 export function UpdateSettings(arg1:Record<string, any>):Promise<void>;
 
+export function UpdateSubscription(arg1:string,arg2:Array<string>,arg3:Array<string>,arg4:boolean):Promise<void>;
+
 export function UpdateTaskbarIcon(arg1:string):Promise<void>;
 
 export function WindowIsMaximised():Promise<boolean>;
@@ -26,6 +26,22 @@ export function AddSeries(arg1, arg2, arg3) {
   return window['go']['main']['App']['AddSeries'](arg1, arg2, arg3);
 }
 
+export function AddSubscription(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['AddSubscription'](arg1, arg2, arg3, arg4);
+}
+
+export function CheckAllSubscriptions() {
+  return window['go']['main']['App']['CheckAllSubscriptions']();
+}
+
+export function CheckMangaDexFeed() {
+  return window['go']['main']['App']['CheckMangaDexFeed']();
+}
+
+export function CheckSubscription(arg1) {
+  return window['go']['main']['App']['CheckSubscription'](arg1);
+}
+
 export function ClearAllData() {
   return window['go']['main']['App']['ClearAllData']();
 }
@@ -46,10 +62,18 @@ export function ClearSeries() {
   return window['go']['main']['App']['ClearSeries']();
 }
 
+export function ClearSiteCookies(arg1) {
+  return window['go']['main']['App']['ClearSiteCookies'](arg1);
+}
+
 export function ClearThumbnailCache() {
   return window['go']['main']['App']['ClearThumbnailCache']();
 }
 
+export function DownloadMangaDexList(arg1, arg2) {
+  return window['go']['main']['App']['DownloadMangaDexList'](arg1, arg2);
+}
+
 export function ExploreFolder(arg1) {
   return window['go']['main']['App']['ExploreFolder'](arg1);
 }
@@ -62,14 +86,26 @@ export function GetBaseFolders() {
   return window['go']['main']['App']['GetBaseFolders']();
 }
 
+export function GetBatchProgress(arg1) {
+  return window['go']['main']['App']['GetBatchProgress'](arg1);
+}
+
 export function GetChapterNavigation(arg1) {
   return window['go']['main']['App']['GetChapterNavigation'](arg1);
 }
 
+export function GetCookieSites() {
+  return window['go']['main']['App']['GetCookieSites']();
+}
+
 export function GetDownloadHistory() {
   return window['go']['main']['App']['GetDownloadHistory']();
 }
 
+export function GetDownloadSpace() {
+  return window['go']['main']['App']['GetDownloadSpace']();
+}
+
 export function GetFolderInfo(arg1) {
   return window['go']['main']['App']['GetFolderInfo'](arg1);
 }
@@ -86,6 +122,10 @@ export function GetHistoryEntry(arg1) {
   return window['go']['main']['App']['GetHistoryEntry'](arg1);
 }
 
+export function GetHookLog(arg1) {
+  return window['go']['main']['App']['GetHookLog'](arg1);
+}
+
 export function GetImageOrder(arg1) {
   return window['go']['main']['App']['GetImageOrder'](arg1);
 }
@@ -102,10 +142,34 @@ export function GetLibrary() {
   return window['go']['main']['App']['GetLibrary']();
 }
 
+export function GetMangaDexAccount() {
+  return window['go']['main']['App']['GetMangaDexAccount']();
+}
+
+export function GetMangaDexLists() {
+  return window['go']['main']['App']['GetMangaDexLists']();
+}
+
 export function GetOriginalOrder(arg1) {
   return window['go']['main']['App']['GetOriginalOrder'](arg1);
 }
 
+export function GetPluginsPath() {
+  return window['go']['main']['App']['GetPluginsPath']();
+}
+
+export function GetQueuePositions() {
+  return window['go']['main']['App']['GetQueuePositions']();
+}
+
+export function GetScheduleStatus() {
+  return window['go']['main']['App']['GetScheduleStatus']();
+}
+
+export function GetScrapersPath() {
+  return window['go']['main']['App']['GetScrapersPath']();
+}
+
 export function GetSeries() {
   return window['go']['main']['App']['GetSeries']();
 }
@@ -118,6 +182,10 @@ export function GetSubfolders(arg1) {
   return window['go']['main']['App']['GetSubfolders'](arg1);
 }
 
+export function GetSubscriptions() {
+  return window['go']['main']['App']['GetSubscriptions']();
+}
+
 export function GetTabs() {
   return window['go']['main']['App']['GetTabs']();
 }
@@ -134,18 +202,70 @@ export function HasCustomOrder(arg1) {
   return window['go']['main']['App']['HasCustomOrder'](arg1);
 }
 
+export function ImportCookies(arg1) {
+  return window['go']['main']['App']['ImportCookies'](arg1);
+}
+
+export function ImportMangaDexTitles(arg1, arg2) {
+  return window['go']['main']['App']['ImportMangaDexTitles'](arg1, arg2);
+}
+
 export function IsSeries(arg1) {
   return window['go']['main']['App']['IsSeries'](arg1);
 }
 
+export function MangaDexLogin(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['MangaDexLogin'](arg1, arg2, arg3, arg4);
+}
+
+export function MangaDexLogout() {
+  return window['go']['main']['App']['MangaDexLogout']();
+}
+
+export function MoveDownloadJob(arg1, arg2) {
+  return window['go']['main']['App']['MoveDownloadJob'](arg1, arg2);
+}
+
 export function OpenInFileManager(arg1) {
   return window['go']['main']['App']['OpenInFileManager'](arg1);
 }
 
+export function PackageJobCBZ(arg1) {
+  return window['go']['main']['App']['PackageJobCBZ'](arg1);
+}
+
+export function PauseAllDownloads() {
+  return window['go']['main']['App']['PauseAllDownloads']();
+}
+
+export function PauseDownloadJob(arg1) {
+  return window['go']['main']['App']['PauseDownloadJob'](arg1);
+}
+
 export function PreloadThumbnails(arg1) {
   return window['go']['main']['App']['PreloadThumbnails'](arg1);
 }
 
+export function PreviewNamingTemplate(arg1) {
+  return window['go']['main']['App']['PreviewNamingTemplate'](arg1);
+}
+
+export function PrioritizeDownloadJob(arg1) {
+  return window['go']['main']['App']['PrioritizeDownloadJob'](arg1);
+}
+
+export function RebuildContentIndex() {
+  return window['go']['main']['App']['RebuildContentIndex']();
+}
+
+export function ReloadPlugins() {
+  return window['go']['main']['App']['ReloadPlugins']();
+}
+
+export function ReloadScrapers() {
+  return window['go']['main']['App']['ReloadScrapers']();
+}
+
 export function RemoveBaseFolder(arg1) {
   return window['go']['main']['App']['RemoveBaseFolder'](arg1);
 }
@@ -166,6 +286,14 @@ export function RemoveSeries(arg1) {
   return window['go']['main']['App']['RemoveSeries'](arg1);
 }
 
+export function RemoveSubscription(arg1) {
+  return window['go']['main']['App']['RemoveSubscription'](arg1);
+}
+
+export function RenameDownloadsToTemplate() {
+  return window['go']['main']['App']['RenameDownloadsToTemplate']();
+}
+
 export function ResetImageOrder(arg1) {
   return window['go']['main']['App']['ResetImageOrder'](arg1);
 }
@@ -174,10 +302,22 @@ export function ResolveFolder(arg1) {
   return window['go']['main']['App']['ResolveFolder'](arg1);
 }
 
+export function ResumeAllDownloads() {
+  return window['go']['main']['App']['ResumeAllDownloads']();
+}
+
+export function ResumeDownloadJob(arg1) {
+  return window['go']['main']['App']['ResumeDownloadJob'](arg1);
+}
+
 export function ResumeIncompleteDownloads(arg1) {
   return window['go']['main']['App']['ResumeIncompleteDownloads'](arg1);
 }
 
+export function RetryFailedPages(arg1) {
+  return window['go']['main']['App']['RetryFailedPages'](arg1);
+}
+
 export function SaveImageOrder(arg1, arg2, arg3) {
   return window['go']['main']['App']['SaveImageOrder'](arg1, arg2, arg3);
 }
@@ -202,14 +342,34 @@ export function SelectFolder() {
   return window['go']['main']['App']['SelectFolder']();
 }
 
+export function SetDownloadJobPriority(arg1, arg2) {
+  return window['go']['main']['App']['SetDownloadJobPriority'](arg1, arg2);
+}
+
+export function SetSiteUserAgent(arg1, arg2) {
+  return window['go']['main']['App']['SetSiteUserAgent'](arg1, arg2);
+}
+
 export function StartDownload(arg1, arg2, arg3) {
   return window['go']['main']['App']['StartDownload'](arg1, arg2, arg3);
 }
 
+export function StartSeriesDownload(arg1, arg2) {
+  return window['go']['main']['App']['StartSeriesDownload'](arg1, arg2);
+}
+
+export function TestHook(arg1) {
+  return window['go']['main']['App']['TestHook'](arg1);
+}
+
 export function UpdateSettings(arg1) {
   return window['go']['main']['App']['UpdateSettings'](arg1);
 }
 
+export function UpdateSubscription(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['UpdateSubscription'](arg1, arg2, arg3, arg4);
+}
+
 export function UpdateTaskbarIcon(arg1) {
   return window['go']['main']['App']['UpdateTaskbarIcon'](arg1);
 }
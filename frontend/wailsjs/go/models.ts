@@ -1,5 +1,29 @@
 export namespace downloader {
 	
+	export class BatchProgress {
+	    batchId: string;
+	    total: number;
+	    completed: number;
+	    failed: number;
+	    active: number;
+	    pages: number;
+	    pagesDone: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new BatchProgress(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.batchId = source["batchId"];
+	        this.total = source["total"];
+	        this.completed = source["completed"];
+	        this.failed = source["failed"];
+	        this.active = source["active"];
+	        this.pages = source["pages"];
+	        this.pagesDone = source["pagesDone"];
+	    }
+	}
 	export class ChapterInfo {
 	    ID: string;
 	    Name: string;
@@ -7,6 +31,9 @@ export namespace downloader {
 	    Date: string;
 	    ScanGroup: string;
 	    Language: string;
+	    Number: string;
+	    Volume: string;
+	    LocalPath: string;
 	
 	    static createFrom(source: any = {}) {
 	        return new ChapterInfo(source);
@@ -20,6 +47,59 @@ export namespace downloader {
 	        this.Date = source["Date"];
 	        this.ScanGroup = source["ScanGroup"];
 	        this.Language = source["Language"];
+	        this.Number = source["Number"];
+	        this.Volume = source["Volume"];
+	        this.LocalPath = source["LocalPath"];
+	    }
+	}
+	export class CookieSite {
+	    domain: string;
+	    cookies: number;
+	    userAgent: string;
+	    expiresAt: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new CookieSite(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.domain = source["domain"];
+	        this.cookies = source["cookies"];
+	        this.userAgent = source["userAgent"];
+	        this.expiresAt = source["expiresAt"];
+	    }
+	}
+	export class DownloadSpace {
+	    used: number;
+	    free: number;
+	    quota: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new DownloadSpace(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.used = source["used"];
+	        this.free = source["free"];
+	        this.quota = source["quota"];
+	    }
+	}
+	export class DuplicateMatch {
+	    path: string;
+	    reason: string;
+	    source: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new DuplicateMatch(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.reason = source["reason"];
+	        this.source = source["source"];
 	    }
 	}
 	export class ImageDownload {
@@ -40,6 +120,116 @@ export namespace downloader {
 	        this.Headers = source["Headers"];
 	    }
 	}
+	export class MangaDexAccount {
+	    loggedIn: boolean;
+	    username: string;
+	    clientId: string;
+	    feedCheckedAt: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new MangaDexAccount(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.loggedIn = source["loggedIn"];
+	        this.username = source["username"];
+	        this.clientId = source["clientId"];
+	        this.feedCheckedAt = source["feedCheckedAt"];
+	    }
+	}
+	export class MangaDexList {
+	    id: string;
+	    name: string;
+	    visibility: string;
+	    titles: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new MangaDexList(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.visibility = source["visibility"];
+	        this.titles = source["titles"];
+	    }
+	}
+	export class RenameResult {
+	    renamed: number;
+	    skipped: number;
+	    errors: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new RenameResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.renamed = source["renamed"];
+	        this.skipped = source["skipped"];
+	        this.errors = source["errors"];
+	    }
+	}
+	export class ScheduleStatus {
+	    enabled: boolean;
+	    open: boolean;
+	    nextChange?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ScheduleStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.enabled = source["enabled"];
+	        this.open = source["open"];
+	        this.nextChange = source["nextChange"];
+	    }
+	}
+	export class SeriesBatch {
+	    batchId: string;
+	    seriesName: string;
+	    jobIds: string[];
+	    skipped: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new SeriesBatch(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.batchId = source["batchId"];
+	        this.seriesName = source["seriesName"];
+	        this.jobIds = source["jobIds"];
+	        this.skipped = source["skipped"];
+	    }
+	}
+	export class SeriesFilter {
+	    chapterIds?: string[];
+	    ranges?: string;
+	    languages?: string[];
+	    group?: string;
+	    latest?: number;
+	    dedupe?: boolean;
+	    preferGroup?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new SeriesFilter(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.chapterIds = source["chapterIds"];
+	        this.ranges = source["ranges"];
+	        this.languages = source["languages"];
+	        this.group = source["group"];
+	        this.latest = source["latest"];
+	        this.dedupe = source["dedupe"];
+	        this.preferGroup = source["preferGroup"];
+	    }
+	}
 	export class SiteInfo {
 	    SeriesName: string;
 	    ChapterName: string;
@@ -48,6 +238,11 @@ export namespace downloader {
 	    DownloadDelay: number;
 	    Type: string;
 	    Chapters: ChapterInfo[];
+	    ChapterNumber: string;
+	    Volume: string;
+	    ScanGroup: string;
+	    Language: string;
+	    Duplicates: DuplicateMatch[];
 	
 	    static createFrom(source: any = {}) {
 	        return new SiteInfo(source);
@@ -62,6 +257,11 @@ export namespace downloader {
 	        this.DownloadDelay = source["DownloadDelay"];
 	        this.Type = source["Type"];
 	        this.Chapters = this.convertValues(source["Chapters"], ChapterInfo);
+	        this.ChapterNumber = source["ChapterNumber"];
+	        this.Volume = source["Volume"];
+	        this.ScanGroup = source["ScanGroup"];
+	        this.Language = source["Language"];
+	        this.Duplicates = this.convertValues(source["Duplicates"], DuplicateMatch);
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -174,6 +374,48 @@ export namespace persistence {
 	        this.imageCount = source["imageCount"];
 	    }
 	}
+	export class DownloadHook {
+	    id: string;
+	    name: string;
+	    enabled: boolean;
+	    events: string[];
+	    command?: string;
+	    webhookUrl?: string;
+	    timeoutSeconds?: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new DownloadHook(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.enabled = source["enabled"];
+	        this.events = source["events"];
+	        this.command = source["command"];
+	        this.webhookUrl = source["webhookUrl"];
+	        this.timeoutSeconds = source["timeoutSeconds"];
+	    }
+	}
+	export class DownloadImage {
+	    url: string;
+	    filename: string;
+	    index: number;
+	    headers?: Record<string, string>;
+	
+	    static createFrom(source: any = {}) {
+	        return new DownloadImage(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.url = source["url"];
+	        this.filename = source["filename"];
+	        this.index = source["index"];
+	        this.headers = source["headers"];
+	    }
+	}
 	export class DownloadJob {
 	    id: string;
 	    url: string;
@@ -187,6 +429,20 @@ export namespace persistence {
 	    createdAt: string;
 	    completedAt?: string;
 	    path: string;
+	    images?: DownloadImage[];
+	    resolvedAt?: string;
+	    downloadDelayMs?: number;
+	    files?: string[];
+	    failedPages?: number[];
+	    batchId?: string;
+	    chapterNumber?: string;
+	    volume?: string;
+	    scanGroup?: string;
+	    language?: string;
+	    priority?: number;
+	    queueOrder?: number;
+	    bytes?: number;
+	    duplicateOf?: string;
 	
 	    static createFrom(source: any = {}) {
 	        return new DownloadJob(source);
@@ -206,6 +462,54 @@ export namespace persistence {
 	        this.createdAt = source["createdAt"];
 	        this.completedAt = source["completedAt"];
 	        this.path = source["path"];
+	        this.images = this.convertValues(source["images"], DownloadImage);
+	        this.resolvedAt = source["resolvedAt"];
+	        this.downloadDelayMs = source["downloadDelayMs"];
+	        this.files = source["files"];
+	        this.failedPages = source["failedPages"];
+	        this.batchId = source["batchId"];
+	        this.chapterNumber = source["chapterNumber"];
+	        this.volume = source["volume"];
+	        this.scanGroup = source["scanGroup"];
+	        this.language = source["language"];
+	        this.priority = source["priority"];
+	        this.queueOrder = source["queueOrder"];
+	        this.bytes = source["bytes"];
+	        this.duplicateOf = source["duplicateOf"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class DownloadWindow {
+	    days?: number[];
+	    start: string;
+	    end: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new DownloadWindow(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.days = source["days"];
+	        this.start = source["start"];
+	        this.end = source["end"];
 	    }
 	}
 	export class FolderInfo {
@@ -265,6 +569,8 @@ export namespace persistence {
 	    size: number;
 	    index: number;
 	    modTime: number;
+	    animated: boolean;
+	    previewUrl?: string;
 	
 	    static createFrom(source: any = {}) {
 	        return new ImageInfo(source);
@@ -280,6 +586,44 @@ export namespace persistence {
 	        this.size = source["size"];
 	        this.index = source["index"];
 	        this.modTime = source["modTime"];
+	        this.animated = source["animated"];
+	        this.previewUrl = source["previewUrl"];
+	    }
+	}
+	export class SiteOptions {
+	    packageCbz?: boolean;
+	    bandwidthKbps?: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new SiteOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.packageCbz = source["packageCbz"];
+	        this.bandwidthKbps = source["bandwidthKbps"];
+	    }
+	}
+	export class SiteLimit {
+	    maxJobs: number;
+	    pageConcurrency: number;
+	    requestsPerSecond: number;
+	    maxRetries: number;
+	    backoffMs: number;
+	    hosts?: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new SiteLimit(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.maxJobs = source["maxJobs"];
+	        this.pageConcurrency = source["pageConcurrency"];
+	        this.requestsPerSecond = source["requestsPerSecond"];
+	        this.maxRetries = source["maxRetries"];
+	        this.backoffMs = source["backoffMs"];
+	        this.hosts = source["hosts"];
 	    }
 	}
 	export class Settings {
@@ -306,11 +650,28 @@ export namespace persistence {
 	    lastPage: string;
 	    enabledMenuItems: Record<string, boolean>;
 	    downloadPath: string;
+	    downloadNamingTemplate: string;
 	    clipboardAutoMonitor: boolean;
 	    autoResumeDownloads: boolean;
 	    tabMemorySaving: boolean;
 	    restoreTabs: boolean;
 	    savedTabs: string;
+	    animatedThumbnails: boolean;
+	    siteLimits: Record<string, SiteLimit>;
+	    siteOptions: Record<string, SiteOptions>;
+	    downloadProxy: string;
+	    autoAddToSeries: boolean;
+	    packageCbz: boolean;
+	    cbzDeleteImages: boolean;
+	    bandwidthLimitKbps: number;
+	    downloadSchedule: DownloadWindow[];
+	    minFreeSpaceMb: number;
+	    downloadQuotaMb: number;
+	    duplicateAction: string;
+	    mangadexLanguages: string[];
+	    mangadexDataSaver: boolean;
+	    hooks: DownloadHook[];
+	    subscriptionCheckMinutes: number;
 	
 	    static createFrom(source: any = {}) {
 	        return new Settings(source);
@@ -341,11 +702,80 @@ export namespace persistence {
 	        this.lastPage = source["lastPage"];
 	        this.enabledMenuItems = source["enabledMenuItems"];
 	        this.downloadPath = source["downloadPath"];
+	        this.downloadNamingTemplate = source["downloadNamingTemplate"];
 	        this.clipboardAutoMonitor = source["clipboardAutoMonitor"];
 	        this.autoResumeDownloads = source["autoResumeDownloads"];
 	        this.tabMemorySaving = source["tabMemorySaving"];
 	        this.restoreTabs = source["restoreTabs"];
 	        this.savedTabs = source["savedTabs"];
+	        this.animatedThumbnails = source["animatedThumbnails"];
+	        this.siteLimits = this.convertValues(source["siteLimits"], SiteLimit, true);
+	        this.siteOptions = this.convertValues(source["siteOptions"], SiteOptions, true);
+	        this.downloadProxy = source["downloadProxy"];
+	        this.autoAddToSeries = source["autoAddToSeries"];
+	        this.packageCbz = source["packageCbz"];
+	        this.cbzDeleteImages = source["cbzDeleteImages"];
+	        this.bandwidthLimitKbps = source["bandwidthLimitKbps"];
+	        this.downloadSchedule = this.convertValues(source["downloadSchedule"], DownloadWindow);
+	        this.minFreeSpaceMb = source["minFreeSpaceMb"];
+	        this.downloadQuotaMb = source["downloadQuotaMb"];
+	        this.duplicateAction = source["duplicateAction"];
+	        this.mangadexLanguages = source["mangadexLanguages"];
+	        this.mangadexDataSaver = source["mangadexDataSaver"];
+	        this.hooks = this.convertValues(source["hooks"], DownloadHook);
+	        this.subscriptionCheckMinutes = source["subscriptionCheckMinutes"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	
+	export class Subscription {
+	    id: string;
+	    url: string;
+	    site: string;
+	    seriesName: string;
+	    languages?: string[];
+	    groups?: string[];
+	    autoDownload: boolean;
+	    knownChapters: string[];
+	    createdAt: string;
+	    lastCheckedAt?: string;
+	    lastError?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Subscription(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.url = source["url"];
+	        this.site = source["site"];
+	        this.seriesName = source["seriesName"];
+	        this.languages = source["languages"];
+	        this.groups = source["groups"];
+	        this.autoDownload = source["autoDownload"];
+	        this.knownChapters = source["knownChapters"];
+	        this.createdAt = source["createdAt"];
+	        this.lastCheckedAt = source["lastCheckedAt"];
+	        this.lastError = source["lastError"];
 	    }
 	}
 	export class Tab {
@@ -117,7 +117,16 @@ func (is *ImageServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var finalPath string
-	if isThumbnail {
+	if isThumbnail && r.URL.Query().Get("animated") == "1" && thumbnails.IsAnimated(originalImagePath) {
+		// Short animated preview for GIF/WebP/AVIF pages
+		previewPath, err := is.thumbGen.GetAnimatedPreview(originalImagePath)
+		if err != nil {
+			fmt.Printf("[ImageServer] Animated preview generation failed for %s: %v\n", originalImagePath, err)
+			http.Error(w, "Failed to generate thumbnail", http.StatusInternalServerError)
+			return
+		}
+		finalPath = previewPath
+	} else if isThumbnail {
 		// Ensure thumbnail exists and get its cache path
 		_, err := is.thumbGen.GetThumbnailBytes(originalImagePath)
 		if err != nil {
@@ -155,6 +164,10 @@ func (is *ImageServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Length", fmt.Sprintf("%d", fileInfo.Size()))
 	w.Header().Set("Cache-Control", "private, max-age=31536000") // Cache for 1 year
 	w.Header().Set("Accept-Ranges", "bytes")
+	if mimeType == "image/svg+xml" {
+		// SVG pages opened directly must never run embedded scripts inside the webview
+		w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; img-src data:")
+	}
 
 	// Get filename for content-disposition
 	filename := filepath.Base(finalPath)
@@ -12,6 +12,8 @@ import (
 	"strings"
 	"sync"
 	"unicode"
+
+	"manga-visor/internal/thumbnails"
 )
 
 // Supported image extensions
@@ -36,6 +38,7 @@ type ImageInfo struct {
 	Size      int64  `json:"size"`
 	Index     int    `json:"index"`
 	ModTime   int64  `json:"modTime"`
+	Animated  bool   `json:"animated"`
 }
 
 // FileLoader handles image file operations
@@ -130,7 +133,7 @@ func (fl *FileLoader) GetImages(folderPath string) ([]ImageInfo, error) {
 
 	// Sort by natural order of full paths to keep sequence across folders
 	sort.Slice(imageFiles, func(i, j int) bool {
-		return naturalLess(imageFiles[i].path, imageFiles[j].path)
+		return NaturalLess(imageFiles[i].path, imageFiles[j].path)
 	})
 
 	// Build result
@@ -143,6 +146,7 @@ func (fl *FileLoader) GetImages(folderPath string) ([]ImageInfo, error) {
 			Size:      file.info.Size(),
 			Index:     i,
 			ModTime:   file.info.ModTime().UnixMilli(),
+			Animated:  thumbnails.IsAnimatedFile(file.path, file.info),
 		})
 	}
 
@@ -249,7 +253,7 @@ func (fl *FileLoader) GetImagesShallow(folderPath string) ([]ImageInfo, error) {
 
 	// Sort by natural order
 	sort.Slice(imageFiles, func(i, j int) bool {
-		return naturalLess(imageFiles[i].name, imageFiles[j].name)
+		return NaturalLess(imageFiles[i].name, imageFiles[j].name)
 	})
 
 	// Build result
@@ -262,6 +266,7 @@ func (fl *FileLoader) GetImagesShallow(folderPath string) ([]ImageInfo, error) {
 			Size:      file.info.Size(),
 			Index:     i,
 			ModTime:   file.info.ModTime().UnixMilli(),
+			Animated:  thumbnails.IsAnimatedFile(file.path, file.info),
 		})
 	}
 
@@ -317,8 +322,8 @@ func (fl *FileLoader) GetImageReader(imagePath string) (io.ReadCloser, string, i
 	return file, mimeType, info.Size(), nil
 }
 
-// naturalLess compares strings in natural order (1, 2, 10 instead of 1, 10, 2)
-func naturalLess(a, b string) bool {
+// NaturalLess compares strings in natural order (1, 2, 10 instead of 1, 10, 2)
+func NaturalLess(a, b string) bool {
 	return compareNatural(a, b) < 0
 }
 
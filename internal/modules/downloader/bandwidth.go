@@ -0,0 +1,114 @@
+package downloader
+
+import (
+	"context"
+	"io"
+	"math"
+	"sync"
+	"time"
+)
+
+// throttleChunk is the most read at once from a throttled body, so the waits stay short
+const throttleChunk = 16 << 10
+
+// byteBucket caps throughput in bytes per second. Reads take what they got and then wait
+// off the debt, so concurrent readers share the rate.
+type byteBucket struct {
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func (b *byteBucket) take(ctx context.Context, n int, rate float64) error {
+	if rate <= 0 {
+		return nil
+	}
+	b.mu.Lock()
+	now := time.Now()
+	// Bursts of up to one second worth of bytes
+	b.tokens = math.Min(rate, b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+	b.tokens -= float64(n)
+	var delay time.Duration
+	if b.tokens < 0 {
+		delay = time.Duration(-b.tokens / rate * float64(time.Second))
+	}
+	b.mu.Unlock()
+
+	if delay == 0 {
+		return nil
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(delay):
+		return nil
+	}
+}
+
+// bandwidthLimiter holds the global bucket and one bucket per site of a clientFactory
+type bandwidthLimiter struct {
+	global byteBucket
+	mu     sync.Mutex
+	sites  map[string]*byteBucket
+}
+
+func (l *bandwidthLimiter) site(siteID string) *byteBucket {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b, ok := l.sites[siteID]
+	if !ok {
+		b = &byteBucket{}
+		l.sites[siteID] = b
+	}
+	return b
+}
+
+// throttledReader limits a page body to the global and the site bandwidth caps.
+// The caps are read from settings on every chunk, so changes apply to downloads in progress.
+type throttledReader struct {
+	ctx     context.Context
+	r       io.Reader
+	siteID  string
+	clients *clientFactory
+	site    *byteBucket
+}
+
+// throttle wraps a page body in the bandwidth caps of a site
+func (f *clientFactory) throttle(ctx context.Context, r io.Reader, siteID string) io.Reader {
+	if f == nil {
+		f = standaloneClients
+	}
+	return &throttledReader{ctx: ctx, r: r, siteID: siteID, clients: f, site: f.bandwidth.site(siteID)}
+}
+
+func (t *throttledReader) Read(p []byte) (int, error) {
+	globalRate, siteRate := t.rates()
+	if globalRate <= 0 && siteRate <= 0 {
+		return t.r.Read(p)
+	}
+	if len(p) > throttleChunk {
+		p = p[:throttleChunk]
+	}
+	n, err := t.r.Read(p)
+	if n > 0 {
+		if waitErr := t.clients.bandwidth.global.take(t.ctx, n, globalRate); waitErr != nil {
+			return n, waitErr
+		}
+		if waitErr := t.site.take(t.ctx, n, siteRate); waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err
+}
+
+// rates returns the global and site caps in bytes per second (0 = unlimited)
+func (t *throttledReader) rates() (float64, float64) {
+	settings := t.clients.currentSettings()
+	if settings == nil {
+		return 0, 0
+	}
+	global := float64(settings.BandwidthLimitKBps) * 1024
+	site := float64(siteOptionsFor(settings.SiteOptions, t.siteID).BandwidthKBps) * 1024
+	return global, site
+}
@@ -0,0 +1,230 @@
+package downloader
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"manga-visor/internal/fileloader"
+	"manga-visor/internal/persistence"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// comicInfo is the ComicInfo.xml read by comic readers (Komga, Kavita, ComicRack)
+type comicInfo struct {
+	XMLName         xml.Name `xml:"ComicInfo"`
+	Series          string   `xml:"Series,omitempty"`
+	Title           string   `xml:"Title,omitempty"`
+	Number          string   `xml:"Number,omitempty"`
+	Volume          string   `xml:"Volume,omitempty"`
+	Web             string   `xml:"Web,omitempty"`
+	LanguageISO     string   `xml:"LanguageISO,omitempty"`
+	ScanInformation string   `xml:"ScanInformation,omitempty"`
+	PageCount       int      `xml:"PageCount,omitempty"`
+	Manga           string   `xml:"Manga,omitempty"`
+}
+
+// packageCBZ zips the pages of a completed job into "<chapter folder>.cbz" and points the
+// job to it. With CBZDeleteImages the pages are removed afterwards, and the folder with
+// them when nothing else is left in it.
+func (m *Module) packageCBZ(jobID string) error {
+	job, ok := m.findJob(jobID)
+	if !ok {
+		return fmt.Errorf("job not found: %s", jobID)
+	}
+	if job.Path == "" || strings.EqualFold(filepath.Ext(job.Path), ".cbz") {
+		return nil
+	}
+	// "<series folder>.cbz" would collect every chapter written there
+	if m.isSharedFolder(job) {
+		return fmt.Errorf("%s is shared with other chapters, only chapters in their own folder can be packaged", job.Path)
+	}
+
+	pages, err := jobPageFiles(job)
+	if err != nil {
+		return err
+	}
+
+	info := comicInfo{
+		Series:          job.SeriesName,
+		Title:           job.ChapterName,
+		Number:          jobNamingFields(job, &SiteInfo{ChapterName: job.ChapterName}).Chapter,
+		Volume:          job.Volume,
+		Web:             job.URL,
+		LanguageISO:     job.Language,
+		ScanInformation: job.ScanGroup,
+		PageCount:       len(pages),
+		Manga:           "Yes",
+	}
+
+	cbzPath := filepath.Clean(job.Path) + ".cbz"
+	if err := writeCBZ(cbzPath, job.Path, pages, info); err != nil {
+		return err
+	}
+
+	m.pm.UpdateJob(job.ID, map[string]interface{}{"path": cbzPath, "files": pages})
+	fmt.Printf("[Downloader] Packaged %s as %s\n", job.ChapterName, cbzPath)
+
+	if m.sm.Get().CBZDeleteImages {
+		for _, name := range pages {
+			os.Remove(filepath.Join(job.Path, name))
+		}
+		// Only if nothing else was left in it
+		os.Remove(job.Path)
+	}
+	return nil
+}
+
+// jobPageFiles returns the page files of a completed job in page order. Jobs completed
+// before the files were recorded fall back to the images found in their folder.
+func jobPageFiles(job persistence.DownloadJob) ([]string, error) {
+	if len(job.Files) > 0 {
+		for _, name := range job.Files {
+			if _, err := os.Stat(filepath.Join(job.Path, name)); err != nil {
+				return nil, fmt.Errorf("page %s is missing", name)
+			}
+		}
+		return job.Files, nil
+	}
+
+	entries, err := os.ReadDir(job.Path)
+	if err != nil {
+		return nil, err
+	}
+	var pages []string
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		if _, ok := fileloader.SupportedExtensions[strings.ToLower(filepath.Ext(e.Name()))]; ok {
+			pages = append(pages, e.Name())
+		}
+	}
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("no pages found")
+	}
+	sort.Slice(pages, func(i, j int) bool { return fileloader.NaturalLess(pages[i], pages[j]) })
+	return pages, nil
+}
+
+// isSharedFolder reports whether a job's folder can hold other chapters too: the download
+// folder itself, a site or series folder, or a folder of the naming template above the
+// chapter level
+func (m *Module) isSharedFolder(job persistence.DownloadJob) bool {
+	dir := filepath.Clean(job.Path)
+	basePath := filepath.Clean(m.downloadBasePath())
+	if dir == basePath {
+		return true
+	}
+
+	site, series := sanitizeFilename(job.Site), sanitizeFilename(job.SeriesName)
+	for _, shared := range [][]string{{site}, {series}, {site, series}} {
+		if shared[len(shared)-1] != "" && dir == filepath.Join(append([]string{basePath}, shared...)...) {
+			return true
+		}
+	}
+
+	segments := strings.Split(m.namingTemplate(), "/")
+	fields := jobNamingFields(job, &SiteInfo{SiteID: job.Site, SeriesName: job.SeriesName, ChapterName: job.ChapterName})
+	prefix := basePath
+	for _, segment := range segments[:len(segments)-1] {
+		if templateNamesChapter(segment) {
+			break
+		}
+		if rendered := sanitizeFilename(renderSegment(segment, fields)); rendered != "" {
+			prefix = filepath.Join(prefix, rendered)
+		}
+		if dir == prefix {
+			return true
+		}
+	}
+	return false
+}
+
+// writeCBZ writes the archive next to its final path and renames it once complete,
+// so a crash never leaves a truncated .cbz behind
+func writeCBZ(cbzPath string, dir string, pages []string, info comicInfo) error {
+	partPath := cbzPath + ".part"
+	out, err := os.Create(partPath)
+	if err != nil {
+		return err
+	}
+
+	err = func() error {
+		zw := zip.NewWriter(out)
+
+		meta, err := xml.MarshalIndent(info, "", "  ")
+		if err != nil {
+			return err
+		}
+		w, err := zw.Create("ComicInfo.xml")
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(append([]byte(xml.Header), meta...)); err != nil {
+			return err
+		}
+
+		for _, name := range pages {
+			// Images are already compressed, storing them keeps packaging fast
+			w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store})
+			if err != nil {
+				return err
+			}
+			f, err := os.Open(filepath.Join(dir, name))
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(w, f)
+			f.Close()
+			if err != nil {
+				return err
+			}
+		}
+		return zw.Close()
+	}()
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(partPath)
+		return fmt.Errorf("failed to write CBZ: %v", err)
+	}
+	return os.Rename(partPath, cbzPath)
+}
+
+// countArchivePages returns the number of images inside a CBZ
+func countArchivePages(path string) (int, error) {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+
+	count := 0
+	for _, f := range r.File {
+		if _, ok := fileloader.SupportedExtensions[strings.ToLower(filepath.Ext(f.Name))]; ok && f.UncompressedSize64 > 0 {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// PackageJobCBZ packages an already completed download as CBZ
+func (m *Module) PackageJobCBZ(id string) error {
+	job, ok := m.findJob(id)
+	if !ok {
+		return fmt.Errorf("job not found: %s", id)
+	}
+	if job.Status != persistence.StatusCompleted {
+		return fmt.Errorf("only completed downloads can be packaged")
+	}
+	if err := m.packageCBZ(id); err != nil {
+		return err
+	}
+	m.notifyUpdate()
+	return nil
+}
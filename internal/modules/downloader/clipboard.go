@@ -2,6 +2,7 @@ package downloader
 
 import (
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/wailsapp/wails/v2/pkg/runtime"
@@ -49,19 +50,73 @@ func (m *Module) isValidURL(text string) bool {
 		return false
 	}
 
-	supported := []string{
-		"hitomi.la",
-		"manhwaweb.com",
-		"zonatmo.com",
-		"nhentai.net",
-		"mangadex.org",
-		"manga18.club",
-	}
+	m.algoLock.RLock()
+	builtins, scrapers, plugins := m.builtins, m.scrapers, m.plugins
+	m.algoLock.RUnlock()
 
-	for _, domain := range supported {
-		if strings.Contains(text, domain) {
-			return true
+	// Built-in and declarative downloaders only match patterns, try them before any plugin script
+	for _, list := range [][]DownloaderInterface{builtins, scrapers} {
+		for _, a := range list {
+			if a.CanHandle(text) {
+				return true
+			}
+		}
+	}
+	if matched, ok := m.clipboardPlugins.get(text); ok {
+		return matched
+	}
+	generation := m.clipboardPlugins.generation()
+	matched := false
+	for _, p := range plugins {
+		if p.CanHandle(text) {
+			matched = true
+			break
 		}
 	}
-	return false
+	m.clipboardPlugins.set(generation, text, matched)
+	return matched
+}
+
+// Texts remembered by pluginURLCache before it starts over
+const pluginURLCacheSize = 256
+
+// pluginURLCache remembers whether a plugin handles a URL, so copying the same link again
+// doesn't run the plugin scripts again. Reset when the plugins are reloaded.
+type pluginURLCache struct {
+	mu      sync.Mutex
+	gen     int
+	matches map[string]bool
+}
+
+func (c *pluginURLCache) get(url string) (bool, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	matched, ok := c.matches[url]
+	return matched, ok
+}
+
+func (c *pluginURLCache) generation() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.gen
+}
+
+// set stores a result found with the plugins of generation, dropped if they were reloaded since
+func (c *pluginURLCache) set(generation int, url string, matched bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if generation != c.gen {
+		return
+	}
+	if c.matches == nil || len(c.matches) >= pluginURLCacheSize {
+		c.matches = make(map[string]bool)
+	}
+	c.matches[url] = matched
+}
+
+func (c *pluginURLCache) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	c.matches = nil
 }
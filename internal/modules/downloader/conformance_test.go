@@ -0,0 +1,488 @@
+package downloader
+
+// Conformance tests for the built-in site downloaders. Every site has a folder under
+// testdata/conformance with a fixture.json listing the requests it makes (routes, with
+// their saved responses) and the cases to resolve (URL and expected result). Requests
+// to any host are sent to a local server that replays the routes, so the real
+// downloaders run unchanged and without network access.
+//
+//	go test -race -run TestSiteConformance ./internal/modules/downloader/
+//	go test -race -run TestSiteConformance ./internal/modules/downloader/ -conformance.report=report.md
+//
+// Run it with -race: the downloaders resolve pages concurrently, as they do in the app.
+//
+// -conformance.record fetches requests without a route from the live sites and saves
+// them as new routes (delete a route, or the whole fixture's routes, to record it again).
+// -conformance.update rewrites the expectations from the results.
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+var (
+	conformanceReport = flag.String("conformance.report", "", "write the conformance report (markdown) to this file")
+	conformanceRecord = flag.Bool("conformance.record", false, "record the fixtures from the live sites and update the expectations")
+	conformanceUpdate = flag.Bool("conformance.update", false, "rewrite the expectations of the fixtures from the current results")
+)
+
+const conformanceDir = "testdata/conformance"
+
+// conformanceFixture is the fixture.json of one site
+type conformanceFixture struct {
+	// Site ID of the built-in downloader under test
+	Site   string             `json:"site"`
+	Routes []conformanceRoute `json:"routes"`
+	Cases  []conformanceCase  `json:"cases"`
+	dir    string
+	byKey  map[string]conformanceRoute
+}
+
+// conformanceRoute is a saved response, matched on method and full URL
+type conformanceRoute struct {
+	Method  string            `json:"method,omitempty"` // GET when empty
+	URL     string            `json:"url"`
+	Status  int               `json:"status,omitempty"` // 200 when empty
+	Headers map[string]string `json:"headers,omitempty"`
+	// File in the site folder holding the body
+	Body string `json:"body,omitempty"`
+}
+
+func (r conformanceRoute) key() string {
+	method := r.Method
+	if method == "" {
+		method = "GET"
+	}
+	return method + " " + r.URL
+}
+
+type conformanceCase struct {
+	Name   string            `json:"name"`
+	URL    string            `json:"url"`
+	Expect conformanceExpect `json:"expect"`
+}
+
+// conformanceExpect is the part of SiteInfo the downloaders must get right
+type conformanceExpect struct {
+	Type        string               `json:"type,omitempty"`
+	SeriesName  string               `json:"seriesName"`
+	ChapterName string               `json:"chapterName,omitempty"`
+	Chapters    []conformanceChapter `json:"chapters,omitempty"`
+	Images      []string             `json:"images,omitempty"`
+}
+
+type conformanceChapter struct {
+	ID        string `json:"id,omitempty"`
+	Name      string `json:"name"`
+	URL       string `json:"url"`
+	Language  string `json:"language,omitempty"`
+	Number    string `json:"number,omitempty"`
+	ScanGroup string `json:"scanGroup,omitempty"`
+}
+
+func expectFromInfo(info *SiteInfo) conformanceExpect {
+	expect := conformanceExpect{
+		Type:        info.Type,
+		SeriesName:  info.SeriesName,
+		ChapterName: info.ChapterName,
+	}
+	for _, ch := range info.Chapters {
+		expect.Chapters = append(expect.Chapters, conformanceChapter{
+			ID:        ch.ID,
+			Name:      ch.Name,
+			URL:       ch.URL,
+			Language:  ch.Language,
+			Number:    ch.Number,
+			ScanGroup: ch.ScanGroup,
+		})
+	}
+	for _, img := range info.Images {
+		expect.Images = append(expect.Images, img.URL)
+	}
+	return expect
+}
+
+// conformanceResult is one row of the report
+type conformanceResult struct {
+	Site     string
+	Case     string
+	Series   string // "ok" or what went wrong
+	Chapters string
+	Images   string
+	Problems []string
+}
+
+func (r conformanceResult) passed() bool {
+	return len(r.Problems) == 0
+}
+
+// compareExpect checks a result against the expectation of its case
+func compareExpect(result *conformanceResult, want conformanceExpect, got conformanceExpect) {
+	result.Series = "ok"
+	if got.SeriesName != want.SeriesName || got.ChapterName != want.ChapterName || got.Type != want.Type {
+		result.Series = "mismatch"
+	}
+	if got.Type != want.Type {
+		result.Problems = append(result.Problems, fmt.Sprintf("type: got %q, want %q", got.Type, want.Type))
+	}
+	if got.SeriesName != want.SeriesName {
+		result.Problems = append(result.Problems, fmt.Sprintf("series name: got %q, want %q", got.SeriesName, want.SeriesName))
+	}
+	if got.ChapterName != want.ChapterName {
+		result.Problems = append(result.Problems, fmt.Sprintf("chapter name: got %q, want %q", got.ChapterName, want.ChapterName))
+	}
+
+	result.Chapters = countCell(len(got.Chapters), len(want.Chapters))
+	if len(got.Chapters) != len(want.Chapters) {
+		result.Problems = append(result.Problems, fmt.Sprintf("chapters: got %d, want %d", len(got.Chapters), len(want.Chapters)))
+	}
+	for i := 0; i < len(got.Chapters) && i < len(want.Chapters); i++ {
+		if got.Chapters[i] != want.Chapters[i] {
+			result.Problems = append(result.Problems, fmt.Sprintf("chapter %d: got %+v, want %+v", i+1, got.Chapters[i], want.Chapters[i]))
+			break
+		}
+	}
+
+	result.Images = countCell(len(got.Images), len(want.Images))
+	if len(got.Images) != len(want.Images) {
+		result.Problems = append(result.Problems, fmt.Sprintf("images: got %d, want %d", len(got.Images), len(want.Images)))
+	}
+	for i := 0; i < len(got.Images) && i < len(want.Images); i++ {
+		if got.Images[i] != want.Images[i] {
+			result.Problems = append(result.Problems, fmt.Sprintf("image %d: got %s, want %s", i+1, got.Images[i], want.Images[i]))
+			break
+		}
+	}
+}
+
+func countCell(got, want int) string {
+	if want == 0 && got == 0 {
+		return "-"
+	}
+	if got == want {
+		return fmt.Sprintf("%d", got)
+	}
+	return fmt.Sprintf("%d/%d", got, want)
+}
+
+// fixtureServer answers every downloader request, from the routes of the current site or,
+// when recording, from the live site
+type fixtureServer struct {
+	mu      sync.Mutex
+	fixture *conformanceFixture
+	record  bool
+	// Requests without a route during the current case
+	misses   []string
+	upstream *http.Client
+	// Clients of the downloaders under test, pointed at the server
+	clients *clientFactory
+}
+
+func (s *fixtureServer) use(fixture *conformanceFixture) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fixture = fixture
+	s.misses = nil
+}
+
+func (s *fixtureServer) takeMisses() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	misses := s.misses
+	s.misses = nil
+	return misses
+}
+
+func (s *fixtureServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	url := scheme + "://" + r.Host + r.URL.RequestURI()
+	key := r.Method + " " + url
+
+	s.mu.Lock()
+	fixture := s.fixture
+	route, ok := fixture.byKey[key]
+	s.mu.Unlock()
+
+	if !ok && s.record {
+		var err error
+		if route, err = s.recordRoute(fixture, r, url); err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		ok = true
+	}
+	if !ok {
+		s.mu.Lock()
+		s.misses = append(s.misses, key)
+		s.mu.Unlock()
+		http.NotFound(w, r)
+		return
+	}
+
+	for k, v := range route.Headers {
+		w.Header().Set(k, v)
+	}
+	var body []byte
+	if route.Body != "" {
+		data, err := os.ReadFile(filepath.Join(fixture.dir, route.Body))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		body = data
+	}
+	status := route.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+	if r.Method != "HEAD" {
+		w.Write(body)
+	}
+}
+
+// recordRoute fetches a request from the live site and adds it to the fixture
+func (s *fixtureServer) recordRoute(fixture *conformanceFixture, r *http.Request, url string) (conformanceRoute, error) {
+	req, err := http.NewRequestWithContext(r.Context(), r.Method, url, r.Body)
+	if err != nil {
+		return conformanceRoute{}, err
+	}
+	for _, k := range []string{"User-Agent", "Referer", "Accept", "Content-Type"} {
+		if v := r.Header.Get(k); v != "" {
+			req.Header.Set(k, v)
+		}
+	}
+	resp, err := s.upstream.Do(req)
+	if err != nil {
+		return conformanceRoute{}, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return conformanceRoute{}, err
+	}
+
+	route := conformanceRoute{URL: url, Status: resp.StatusCode}
+	if r.Method != "GET" {
+		route.Method = r.Method
+	}
+	if route.Status == http.StatusOK {
+		route.Status = 0
+	}
+	for _, k := range []string{"Location", "Content-Type"} {
+		if v := resp.Header.Get(k); v != "" {
+			if route.Headers == nil {
+				route.Headers = map[string]string{}
+			}
+			route.Headers[k] = v
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(body) > 0 && r.Method != "HEAD" {
+		ext := ".bin"
+		if exts, _ := mime.ExtensionsByType(resp.Header.Get("Content-Type")); len(exts) > 0 {
+			ext = exts[0]
+		}
+		route.Body = fmt.Sprintf("%03d%s", len(fixture.Routes)+1, ext)
+		if err := os.WriteFile(filepath.Join(fixture.dir, route.Body), body, 0644); err != nil {
+			return conformanceRoute{}, err
+		}
+	}
+	fixture.Routes = append(fixture.Routes, route)
+	fixture.byKey[route.key()] = route
+	return route, nil
+}
+
+// startFixtureServer starts a local server for every host, HTTPS included, and clients
+// whose transport dials it instead of the real sites
+func startFixtureServer(t *testing.T, record bool) *fixtureServer {
+	fs := &fixtureServer{
+		record: record,
+		upstream: &http.Client{
+			// Redirects are recorded as routes of their own
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		clients: newClientFactory(nil, nil),
+	}
+	tlsServer := httptest.NewTLSServer(fs)
+	plainServer := httptest.NewServer(fs)
+
+	transport := fs.clients.transport
+	transport.Proxy = nil
+	transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	transport.DialContext = func(ctx context.Context, network string, addr string) (net.Conn, error) {
+		target := plainServer.Listener.Addr().String()
+		if _, port, _ := net.SplitHostPort(addr); port == "443" {
+			target = tlsServer.Listener.Addr().String()
+		}
+		return (&net.Dialer{}).DialContext(ctx, network, target)
+	}
+
+	t.Cleanup(func() {
+		transport.CloseIdleConnections()
+		tlsServer.Close()
+		plainServer.Close()
+	})
+	return fs
+}
+
+func loadConformanceFixtures(t *testing.T) []*conformanceFixture {
+	paths, err := filepath.Glob(filepath.Join(conformanceDir, "*", "fixture.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(paths)
+
+	var fixtures []*conformanceFixture
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fixture := &conformanceFixture{}
+		if err := json.Unmarshal(data, fixture); err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		fixture.dir = filepath.Dir(path)
+		fixture.byKey = make(map[string]conformanceRoute, len(fixture.Routes))
+		for _, route := range fixture.Routes {
+			fixture.byKey[route.key()] = route
+		}
+		fixtures = append(fixtures, fixture)
+	}
+	return fixtures
+}
+
+func saveConformanceFixture(t *testing.T, fixture *conformanceFixture) {
+	data, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(fixture.dir, "fixture.json"), append(data, '\n'), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// builtinDownloader returns a fresh instance of a built-in downloader, so state cached
+// by one case (hitomi's gg.js) doesn't leak into the next
+func builtinDownloader(siteID string, clients *clientFactory) DownloaderInterface {
+	for _, d := range builtinAlgorithms() {
+		if d.GetSiteID() == siteID {
+			d.(clientUser).setClients(clients)
+			return d
+		}
+	}
+	return nil
+}
+
+func TestSiteConformance(t *testing.T) {
+	fixtures := loadConformanceFixtures(t)
+	if len(fixtures) == 0 {
+		t.Skip("no fixtures in " + conformanceDir)
+	}
+	server := startFixtureServer(t, *conformanceRecord)
+
+	var results []conformanceResult
+	for _, fixture := range fixtures {
+		changed := false
+		for i := range fixture.Cases {
+			tc := &fixture.Cases[i]
+			result := conformanceResult{Site: fixture.Site, Case: tc.Name, Series: "-", Chapters: "-", Images: "-"}
+
+			t.Run(fixture.Site+"/"+tc.Name, func(t *testing.T) {
+				d := builtinDownloader(fixture.Site, server.clients)
+				if d == nil {
+					result.Problems = append(result.Problems, "no built-in downloader for "+fixture.Site)
+					t.Fatal(result.Problems[0])
+				}
+				if !d.CanHandle(tc.URL) {
+					result.Problems = append(result.Problems, "CanHandle rejects "+tc.URL)
+				}
+
+				server.use(fixture)
+				info, err := d.GetImages(tc.URL)
+				for _, miss := range server.takeMisses() {
+					result.Problems = append(result.Problems, "no fixture for "+miss)
+				}
+				if err != nil {
+					result.Series = "error"
+					result.Problems = append(result.Problems, "GetImages: "+err.Error())
+				} else {
+					got := expectFromInfo(info)
+					if *conformanceRecord || *conformanceUpdate {
+						tc.Expect = got
+						changed = true
+					}
+					compareExpect(&result, tc.Expect, got)
+				}
+
+				for _, problem := range result.Problems {
+					t.Error(problem)
+				}
+			})
+			results = append(results, result)
+		}
+		if changed {
+			saveConformanceFixture(t, fixture)
+		}
+	}
+
+	report := conformanceReportText(results)
+	t.Log("\n" + report)
+	if *conformanceReport != "" {
+		if err := os.WriteFile(*conformanceReport, []byte(report), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// conformanceReportText renders the results as a markdown table, followed by the
+// problems of the failing cases
+func conformanceReportText(results []conformanceResult) string {
+	var sb strings.Builder
+	passed := 0
+	for _, r := range results {
+		if r.passed() {
+			passed++
+		}
+	}
+	fmt.Fprintf(&sb, "# Site downloader conformance\n\n%d of %d cases pass.\n\n", passed, len(results))
+	sb.WriteString("| Site | Case | Series | Chapters | Images | Result |\n")
+	sb.WriteString("|------|------|--------|----------|--------|--------|\n")
+	for _, r := range results {
+		status := "PASS"
+		if !r.passed() {
+			status = "FAIL"
+		}
+		fmt.Fprintf(&sb, "| %s | %s | %s | %s | %s | %s |\n", r.Site, r.Case, r.Series, r.Chapters, r.Images, status)
+	}
+	for _, r := range results {
+		if r.passed() {
+			continue
+		}
+		fmt.Fprintf(&sb, "\n## %s / %s\n\n", r.Site, r.Case)
+		for _, problem := range r.Problems {
+			fmt.Fprintf(&sb, "- %s\n", problem)
+		}
+	}
+	return sb.String()
+}
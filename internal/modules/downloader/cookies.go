@@ -0,0 +1,380 @@
+package downloader
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"manga-visor/internal/persistence"
+	"net"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// CookieSite summarizes the stored session of one domain
+type CookieSite struct {
+	Domain    string `json:"domain"`
+	Cookies   int    `json:"cookies"`
+	UserAgent string `json:"userAgent"`
+	// Earliest expiry among the cookies (RFC3339), empty if they are all session cookies
+	ExpiresAt string `json:"expiresAt"`
+}
+
+// domainMatch reports whether host is domain or one of its subdomains
+func domainMatch(host string, domain string) bool {
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// isPublicSuffix reports whether domain is a suffix under which anyone can register
+// names (com, co.uk, github.io), which no site may set cookies for
+func isPublicSuffix(domain string) bool {
+	suffix, _ := publicsuffix.PublicSuffix(domain)
+	return suffix == domain
+}
+
+// pathMatch is the RFC 6265 path-match: "/foo" matches "/foo" and "/foo/bar", not "/foobar"
+func pathMatch(requestPath string, cookiePath string) bool {
+	if cookiePath == "" || requestPath == cookiePath {
+		return true
+	}
+	if !strings.HasPrefix(requestPath, cookiePath) {
+		return false
+	}
+	return strings.HasSuffix(cookiePath, "/") || requestPath[len(cookiePath)] == '/'
+}
+
+// defaultCookiePath is the path of a cookie set without one: the request path up to its last "/"
+func defaultCookiePath(requestPath string) string {
+	i := strings.LastIndex(requestPath, "/")
+	if i <= 0 || !strings.HasPrefix(requestPath, "/") {
+		return "/"
+	}
+	return requestPath[:i]
+}
+
+// persistentJar is an http.CookieJar stored in the data directory, so sessions
+// (logins, Cloudflare clearance) survive restarts. Without a store requests carry no cookies.
+type persistentJar struct {
+	store *persistence.CookiesManager
+}
+
+func (j persistentJar) Cookies(u *neturl.URL) []*http.Cookie {
+	if j.store == nil {
+		return nil
+	}
+	host := strings.ToLower(u.Hostname())
+	path := u.Path
+	if path == "" {
+		path = "/"
+	}
+	now := time.Now()
+
+	var cookies []*http.Cookie
+	for _, c := range j.store.GetCookies() {
+		if c.Expired(now) || (c.Secure && u.Scheme != "https") {
+			continue
+		}
+		if (c.HostOnly && host != c.Domain) || (!c.HostOnly && !domainMatch(host, c.Domain)) {
+			continue
+		}
+		if !pathMatch(path, c.Path) {
+			continue
+		}
+		cookies = append(cookies, &http.Cookie{Name: c.Name, Value: c.Value})
+	}
+	return cookies
+}
+
+func (j persistentJar) SetCookies(u *neturl.URL, cookies []*http.Cookie) {
+	if j.store == nil || len(cookies) == 0 {
+		return
+	}
+	host := strings.ToLower(u.Hostname())
+	now := time.Now()
+
+	stored := make([]persistence.StoredCookie, 0, len(cookies))
+	for _, c := range cookies {
+		sc := persistence.StoredCookie{
+			Domain:   strings.ToLower(strings.TrimPrefix(c.Domain, ".")),
+			Path:     c.Path,
+			Name:     c.Name,
+			Value:    c.Value,
+			Secure:   c.Secure,
+			HttpOnly: c.HttpOnly,
+		}
+		if !strings.HasPrefix(sc.Path, "/") {
+			sc.Path = defaultCookiePath(u.Path)
+		}
+		if sc.Domain == "" || sc.Domain == host {
+			sc.Domain = host
+			sc.HostOnly = c.Domain == ""
+		} else if !domainMatch(host, sc.Domain) || net.ParseIP(host) != nil {
+			// A site may only set cookies for itself or a parent domain
+			continue
+		}
+		if !sc.HostOnly && isPublicSuffix(sc.Domain) {
+			if sc.Domain != host {
+				// Domain=co.uk would reach every site under it
+				continue
+			}
+			// The site is itself a public suffix (github.io), keep it to that host
+			sc.HostOnly = true
+		}
+		switch {
+		case c.MaxAge < 0:
+			sc.Expires = 1 // Delete
+		case c.MaxAge > 0:
+			sc.Expires = now.Add(time.Duration(c.MaxAge) * time.Second).Unix()
+		case !c.Expires.IsZero():
+			sc.Expires = max(c.Expires.Unix(), 1)
+		}
+		stored = append(stored, sc)
+	}
+	j.store.SetCookies(stored)
+}
+
+// userAgentFor returns the User-Agent override for a host, the longest matching domain wins
+func userAgentFor(store *persistence.CookiesManager, host string) string {
+	if store == nil {
+		return ""
+	}
+	host = strings.ToLower(host)
+	best, bestLen := "", 0
+	for domain, ua := range store.GetUserAgents() {
+		if domainMatch(host, domain) && len(domain) > bestLen {
+			best, bestLen = ua, len(domain)
+		}
+	}
+	return best
+}
+
+// userAgentTransport replaces the hardcoded User-Agent of the downloaders with the
+// override imported along with the site's cookies
+type userAgentTransport struct {
+	base    http.RoundTripper
+	cookies *persistence.CookiesManager
+}
+
+func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if ua := userAgentFor(t.cookies, req.URL.Hostname()); ua != "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("User-Agent", ua)
+	}
+	return t.base.RoundTrip(req)
+}
+
+// ImportCookies loads cookies from a browser export, either a Netscape cookies.txt or a
+// JSON export (Cookie-Editor, EditThisCookie, Playwright). If userAgent is set it becomes
+// the override of every imported domain. Returns the number of cookies imported.
+func (m *Module) ImportCookies(path string, userAgent string) (int, error) {
+	if m.cm == nil {
+		return 0, fmt.Errorf("cookie store not initialized")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read cookies file: %v", err)
+	}
+
+	var cookies []persistence.StoredCookie
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && (trimmed[0] == '[' || trimmed[0] == '{') {
+		cookies, err = parseJSONCookies(trimmed)
+	} else {
+		cookies, err = parseNetscapeCookies(data)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	valid := cookies[:0]
+	domains := map[string]bool{}
+	for _, c := range cookies {
+		if c.Domain == "" || c.Name == "" || c.Expired(now) {
+			continue
+		}
+		valid = append(valid, c)
+		domains[c.Domain] = true
+	}
+	if len(valid) == 0 {
+		return 0, fmt.Errorf("no valid cookies found in file")
+	}
+
+	m.cm.SetCookies(valid)
+	if userAgent = strings.TrimSpace(userAgent); userAgent != "" {
+		for domain := range domains {
+			m.cm.SetUserAgent(domain, userAgent)
+		}
+	}
+
+	fmt.Printf("[Downloader] Imported %d cookies for %d domains\n", len(valid), len(domains))
+	return len(valid), nil
+}
+
+// parseNetscapeCookies reads the cookies.txt format used by curl, yt-dlp and browser extensions
+func parseNetscapeCookies(data []byte) ([]persistence.StoredCookie, error) {
+	var cookies []persistence.StoredCookie
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		httpOnly := false
+		if strings.HasPrefix(line, "#HttpOnly_") {
+			line = strings.TrimPrefix(line, "#HttpOnly_")
+			httpOnly = true
+		}
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// domain, include subdomains, path, secure, expiry, name, value
+		fields := strings.Split(line, "\t")
+		if len(fields) < 7 {
+			fields = strings.Fields(line)
+		}
+		if len(fields) < 6 {
+			return nil, fmt.Errorf("invalid cookies.txt line: %q", line)
+		}
+		value := ""
+		if len(fields) >= 7 {
+			value = fields[6]
+		}
+		expires, _ := strconv.ParseInt(fields[4], 10, 64)
+
+		cookies = append(cookies, persistence.StoredCookie{
+			Domain:   strings.ToLower(strings.TrimPrefix(fields[0], ".")),
+			HostOnly: !strings.EqualFold(fields[1], "TRUE"),
+			Path:     fields[2],
+			Secure:   strings.EqualFold(fields[3], "TRUE"),
+			Expires:  expires,
+			Name:     fields[5],
+			Value:    value,
+			HttpOnly: httpOnly,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read cookies.txt: %v", err)
+	}
+	return cookies, nil
+}
+
+// jsonCookie covers the fields of the common browser export formats
+type jsonCookie struct {
+	Domain         string   `json:"domain"`
+	Path           string   `json:"path"`
+	Name           string   `json:"name"`
+	Value          string   `json:"value"`
+	Secure         bool     `json:"secure"`
+	HttpOnly       bool     `json:"httpOnly"`
+	HostOnly       bool     `json:"hostOnly"`
+	Session        bool     `json:"session"`
+	ExpirationDate float64  `json:"expirationDate"` // Cookie-Editor, EditThisCookie
+	Expires        *float64 `json:"expires"`        // Playwright, Puppeteer (-1 for session)
+}
+
+func parseJSONCookies(data []byte) ([]persistence.StoredCookie, error) {
+	var list []jsonCookie
+	if data[0] == '{' {
+		// Playwright storage state: {"cookies": [...], "origins": [...]}
+		var state struct {
+			Cookies []jsonCookie `json:"cookies"`
+		}
+		if err := json.Unmarshal(data, &state); err != nil {
+			return nil, fmt.Errorf("invalid cookies JSON: %v", err)
+		}
+		list = state.Cookies
+	} else if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("invalid cookies JSON: %v", err)
+	}
+
+	cookies := make([]persistence.StoredCookie, 0, len(list))
+	for _, c := range list {
+		expires := c.ExpirationDate
+		if c.Expires != nil {
+			expires = *c.Expires
+		}
+		if c.Session || expires < 0 {
+			expires = 0
+		}
+		cookies = append(cookies, persistence.StoredCookie{
+			Domain:   strings.ToLower(strings.TrimPrefix(c.Domain, ".")),
+			HostOnly: c.HostOnly,
+			Path:     c.Path,
+			Name:     c.Name,
+			Value:    c.Value,
+			Expires:  int64(expires),
+			Secure:   c.Secure,
+			HttpOnly: c.HttpOnly,
+		})
+	}
+	return cookies, nil
+}
+
+// GetCookieSites lists the domains with stored cookies or a User-Agent override
+func (m *Module) GetCookieSites() []CookieSite {
+	if m.cm == nil {
+		return []CookieSite{}
+	}
+	now := time.Now()
+	sites := map[string]*CookieSite{}
+	site := func(domain string) *CookieSite {
+		if s, ok := sites[domain]; ok {
+			return s
+		}
+		s := &CookieSite{Domain: domain}
+		sites[domain] = s
+		return s
+	}
+
+	earliest := map[string]int64{}
+	for _, c := range m.cm.GetCookies() {
+		if c.Expired(now) {
+			continue
+		}
+		site(c.Domain).Cookies++
+		if c.Expires != 0 && (earliest[c.Domain] == 0 || c.Expires < earliest[c.Domain]) {
+			earliest[c.Domain] = c.Expires
+		}
+	}
+	for domain, ua := range m.cm.GetUserAgents() {
+		site(domain).UserAgent = ua
+	}
+
+	result := make([]CookieSite, 0, len(sites))
+	for domain, s := range sites {
+		if e := earliest[domain]; e != 0 {
+			s.ExpiresAt = time.Unix(e, 0).Format(time.RFC3339)
+		}
+		result = append(result, *s)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Domain < result[j].Domain })
+	return result
+}
+
+// SetSiteUserAgent sets the User-Agent sent to a domain, empty to use the downloader default
+func (m *Module) SetSiteUserAgent(domain string, userAgent string) error {
+	if m.cm == nil {
+		return fmt.Errorf("cookie store not initialized")
+	}
+	if strings.TrimSpace(domain) == "" {
+		return fmt.Errorf("domain is required")
+	}
+	m.cm.SetUserAgent(domain, userAgent)
+	return nil
+}
+
+// ClearSiteCookies removes the cookies and User-Agent override of a domain
+func (m *Module) ClearSiteCookies(domain string) error {
+	if m.cm == nil {
+		return fmt.Errorf("cookie store not initialized")
+	}
+	m.cm.ClearDomain(domain)
+	return nil
+}
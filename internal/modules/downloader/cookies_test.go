@@ -0,0 +1,41 @@
+package downloader
+
+import "testing"
+
+func TestPathMatch(t *testing.T) {
+	tests := []struct {
+		request, cookie string
+		want            bool
+	}{
+		{"/", "/", true},
+		{"/foo", "/", true},
+		{"/foo", "/foo", true},
+		{"/foo/", "/foo", true},
+		{"/foo/bar", "/foo", true},
+		{"/foo/bar", "/foo/", true},
+		{"/foobar", "/foo", false},
+		{"/fo", "/foo", false},
+		{"/bar", "/foo", false},
+		{"/anything", "", true},
+	}
+	for _, tc := range tests {
+		if got := pathMatch(tc.request, tc.cookie); got != tc.want {
+			t.Errorf("pathMatch(%q, %q) = %v, want %v", tc.request, tc.cookie, got, tc.want)
+		}
+	}
+}
+
+func TestDefaultCookiePath(t *testing.T) {
+	for request, want := range map[string]string{
+		"":                "/",
+		"/":               "/",
+		"/login":          "/",
+		"/account/login":  "/account",
+		"/account/login/": "/account/login",
+		"login":           "/",
+	} {
+		if got := defaultCookiePath(request); got != want {
+			t.Errorf("defaultCookiePath(%q) = %q, want %q", request, got, want)
+		}
+	}
+}
@@ -0,0 +1,554 @@
+package downloader
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andybalholm/cascadia"
+	"golang.org/x/net/html"
+)
+
+// ScraperDefinition describes a site that can be downloaded without writing Go code.
+// Definitions are JSON files in the scrapers folder of the data dir, see docs/SCRAPERS.md.
+type ScraperDefinition struct {
+	ID              string            `json:"id"`
+	Name            string            `json:"name"`
+	URLPatterns     []string          `json:"urlPatterns"`
+	Headers         map[string]string `json:"headers,omitempty"`
+	DownloadDelayMs int               `json:"downloadDelayMs,omitempty"`
+	Series          *SeriesRule       `json:"series,omitempty"`
+	Chapter         *ChapterRule      `json:"chapter"`
+}
+
+// PageRule decides which URLs a rule handles and how the page is fetched
+type PageRule struct {
+	// Regex matched against the input URL, empty matches everything
+	Match string `json:"match,omitempty"`
+	// Optional URL to fetch instead of the input URL, $1..$n expand the Match groups (e.g. an API endpoint)
+	Fetch string `json:"fetch,omitempty"`
+	// "html" (default) or "json"
+	Format string `json:"format,omitempty"`
+}
+
+// ChapterRule extracts a single downloadable chapter
+type ChapterRule struct {
+	PageRule
+	SeriesName  Selector `json:"seriesName"`
+	ChapterName Selector `json:"chapterName"`
+	Images      Selector `json:"images"`
+}
+
+// SeriesRule extracts a chapter list. ChapterURL, ChapterName and ChapterDate
+// are evaluated relative to each element matched by Chapters.
+type SeriesRule struct {
+	PageRule
+	SeriesName  Selector `json:"seriesName"`
+	Chapters    Selector `json:"chapters"`
+	ChapterURL  Selector `json:"chapterUrl"`
+	ChapterName Selector `json:"chapterName"`
+	ChapterDate Selector `json:"chapterDate,omitempty"`
+	// Reverse the chapter list (sites listing oldest first)
+	Reverse bool `json:"reverse,omitempty"`
+}
+
+// Selector picks values out of a page
+type Selector struct {
+	// CSS selector for html pages
+	CSS string `json:"css,omitempty"`
+	// Attribute to read, text content when empty
+	Attr string `json:"attr,omitempty"`
+	// Dot separated path for json pages, arrays are walked transparently (e.g. "chapter.img")
+	Path string `json:"path,omitempty"`
+	// Optional regex applied to every value, the first capture group is kept
+	Regex string `json:"regex,omitempty"`
+	// Fixed value, used when the page has nothing to select
+	Value string `json:"value,omitempty"`
+
+	css   cascadia.Sel
+	regex *regexp.Regexp
+}
+
+// DeclarativeDownloader implements DownloaderInterface from a ScraperDefinition
+type DeclarativeDownloader struct {
+	withClients
+	def          ScraperDefinition
+	source       string
+	urlPatterns  []*regexp.Regexp
+	chapterMatch *regexp.Regexp
+	seriesMatch  *regexp.Regexp
+}
+
+// LoadScraperDefinitions loads every *.json definition in dir.
+// Broken files are skipped and reported so one bad definition doesn't disable the others.
+func LoadScraperDefinitions(dir string) ([]*DeclarativeDownloader, []error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, []error{err}
+	}
+
+	var loaded []*DeclarativeDownloader
+	var errs []error
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", filepath.Base(file), err))
+			continue
+		}
+
+		var def ScraperDefinition
+		if err := json.Unmarshal(data, &def); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", filepath.Base(file), err))
+			continue
+		}
+
+		d, err := NewDeclarativeDownloader(def)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", filepath.Base(file), err))
+			continue
+		}
+		d.source = file
+		loaded = append(loaded, d)
+	}
+	return loaded, errs
+}
+
+// NewDeclarativeDownloader validates a definition and compiles its patterns and selectors
+func NewDeclarativeDownloader(def ScraperDefinition) (*DeclarativeDownloader, error) {
+	if def.ID == "" {
+		return nil, fmt.Errorf("missing id")
+	}
+	if len(def.URLPatterns) == 0 {
+		return nil, fmt.Errorf("missing urlPatterns")
+	}
+	if def.Chapter == nil {
+		return nil, fmt.Errorf("missing chapter rule")
+	}
+
+	d := &DeclarativeDownloader{def: def}
+	for _, p := range def.URLPatterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid url pattern %q: %v", p, err)
+		}
+		d.urlPatterns = append(d.urlPatterns, re)
+	}
+
+	var err error
+	if d.chapterMatch, err = compileOptional(def.Chapter.Match); err != nil {
+		return nil, fmt.Errorf("chapter.match: %v", err)
+	}
+	for name, sel := range map[string]*Selector{
+		"chapter.seriesName":  &d.def.Chapter.SeriesName,
+		"chapter.chapterName": &d.def.Chapter.ChapterName,
+		"chapter.images":      &d.def.Chapter.Images,
+	} {
+		if err := sel.compile(); err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+	}
+	if def.Chapter.Images.CSS == "" && def.Chapter.Images.Path == "" {
+		return nil, fmt.Errorf("chapter.images needs a css selector or a json path")
+	}
+
+	if def.Series != nil {
+		if d.seriesMatch, err = compileOptional(def.Series.Match); err != nil {
+			return nil, fmt.Errorf("series.match: %v", err)
+		}
+		for name, sel := range map[string]*Selector{
+			"series.seriesName":  &d.def.Series.SeriesName,
+			"series.chapters":    &d.def.Series.Chapters,
+			"series.chapterUrl":  &d.def.Series.ChapterURL,
+			"series.chapterName": &d.def.Series.ChapterName,
+			"series.chapterDate": &d.def.Series.ChapterDate,
+		} {
+			if err := sel.compile(); err != nil {
+				return nil, fmt.Errorf("%s: %v", name, err)
+			}
+		}
+	}
+
+	return d, nil
+}
+
+func compileOptional(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	return regexp.Compile(pattern)
+}
+
+func (s *Selector) compile() error {
+	if s.CSS != "" {
+		sel, err := cascadia.Parse(s.CSS)
+		if err != nil {
+			return fmt.Errorf("invalid css selector %q: %v", s.CSS, err)
+		}
+		s.css = sel
+	}
+	if s.Regex != "" {
+		re, err := regexp.Compile(s.Regex)
+		if err != nil {
+			return fmt.Errorf("invalid regex %q: %v", s.Regex, err)
+		}
+		s.regex = re
+	}
+	return nil
+}
+
+func (d *DeclarativeDownloader) CanHandle(url string) bool {
+	for _, re := range d.urlPatterns {
+		if re.MatchString(url) {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *DeclarativeDownloader) GetSiteID() string {
+	return d.def.ID
+}
+
+// Source returns the definition file this downloader was loaded from
+func (d *DeclarativeDownloader) Source() string {
+	return d.source
+}
+
+func (d *DeclarativeDownloader) GetImages(url string) (*SiteInfo, error) {
+	if d.def.Series != nil && (d.seriesMatch == nil || d.seriesMatch.MatchString(url)) &&
+		(d.chapterMatch == nil || !d.chapterMatch.MatchString(url)) {
+		return d.getSeries(url)
+	}
+	return d.getChapter(url)
+}
+
+func (d *DeclarativeDownloader) getChapter(url string) (*SiteInfo, error) {
+	rule := d.def.Chapter
+	page, pageURL, err := d.fetchPage(url, rule.PageRule, d.chapterMatch)
+	if err != nil {
+		return nil, err
+	}
+
+	seriesName := firstOr(rule.SeriesName.values(page, pageURL), "Unknown")
+	chapterName := firstOr(rule.ChapterName.values(page, pageURL), "")
+
+	var images []ImageDownload
+	for _, imgURL := range rule.Images.values(page, pageURL) {
+		imgURL = resolveURL(pageURL, imgURL)
+		if imgURL == "" {
+			continue
+		}
+
+		ext := strings.TrimPrefix(strings.ToLower(path.Ext(urlPath(imgURL))), ".")
+		if ext == "" || len(ext) > 4 {
+			ext = "jpg"
+		}
+
+		images = append(images, ImageDownload{
+			URL:      imgURL,
+			Filename: fmt.Sprintf("%03d.%s", len(images)+1, ext),
+			Index:    len(images),
+			Headers:  d.headers(),
+		})
+	}
+
+	if len(images) == 0 {
+		return nil, fmt.Errorf("no images found")
+	}
+
+	return &SiteInfo{
+		SeriesName:    seriesName,
+		ChapterName:   chapterName,
+		Images:        images,
+		SiteID:        d.GetSiteID(),
+		DownloadDelay: time.Duration(d.def.DownloadDelayMs) * time.Millisecond,
+	}, nil
+}
+
+func (d *DeclarativeDownloader) getSeries(url string) (*SiteInfo, error) {
+	rule := d.def.Series
+	page, pageURL, err := d.fetchPage(url, rule.PageRule, d.seriesMatch)
+	if err != nil {
+		return nil, err
+	}
+
+	seriesName := firstOr(rule.SeriesName.values(page, pageURL), "Unknown Series")
+
+	var chapters []ChapterInfo
+	seen := make(map[string]bool)
+	for _, item := range rule.Chapters.items(page) {
+		chapterURL := resolveURL(pageURL, firstOr(rule.ChapterURL.values(item, pageURL), ""))
+		if chapterURL == "" || seen[chapterURL] {
+			continue
+		}
+		seen[chapterURL] = true
+
+		name := firstOr(rule.ChapterName.values(item, pageURL), "")
+		if name == "" {
+			name = fmt.Sprintf("Chapter %d", len(chapters)+1)
+		}
+
+		chapters = append(chapters, ChapterInfo{
+			ID:   chapterURL,
+			Name: name,
+			URL:  chapterURL,
+			Date: firstOr(rule.ChapterDate.values(item, pageURL), ""),
+		})
+	}
+
+	if rule.Reverse {
+		for i, j := 0, len(chapters)-1; i < j; i, j = i+1, j-1 {
+			chapters[i], chapters[j] = chapters[j], chapters[i]
+		}
+	}
+
+	if len(chapters) == 0 {
+		return nil, fmt.Errorf("no chapters found in series page")
+	}
+
+	return &SiteInfo{
+		SeriesName: seriesName,
+		SiteID:     d.GetSiteID(),
+		Type:       "series",
+		Chapters:   chapters,
+	}, nil
+}
+
+// fetchPage downloads and parses the page for a rule. The result is an *html.Node
+// for html pages or the decoded value for json pages.
+func (d *DeclarativeDownloader) fetchPage(url string, rule PageRule, match *regexp.Regexp) (interface{}, string, error) {
+	fetchURL := url
+	if rule.Fetch != "" {
+		fetchURL = rule.Fetch
+		if match != nil {
+			if groups := match.FindStringSubmatchIndex(url); groups != nil {
+				fetchURL = string(match.ExpandString(nil, rule.Fetch, url, groups))
+			}
+		}
+	}
+
+	req, err := http.NewRequest("GET", fetchURL, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	for k, v := range d.headers() {
+		req.Header.Set(k, v)
+	}
+
+	client := d.newHTTPClient()
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to fetch page: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("failed to fetch page, status: %d", resp.StatusCode)
+	}
+
+	pageURL := resp.Request.URL.String()
+	if rule.Format == "json" {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, "", err
+		}
+		var data interface{}
+		if err := json.Unmarshal(body, &data); err != nil {
+			return nil, "", fmt.Errorf("failed to parse JSON: %v", err)
+		}
+		return data, pageURL, nil
+	}
+
+	doc, err := html.Parse(resp.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to parse HTML: %v", err)
+	}
+	return doc, pageURL, nil
+}
+
+func (d *DeclarativeDownloader) headers() map[string]string {
+	headers := map[string]string{
+		"User-Agent": "Mozilla/5.0 (Windows NT 10.0; Win64; x64) Chrome/120.0.0.0",
+	}
+	for k, v := range d.def.Headers {
+		headers[k] = v
+	}
+	return headers
+}
+
+// items returns the nodes/values matched by the selector, used as scopes for nested selectors
+func (s *Selector) items(page interface{}) []interface{} {
+	var items []interface{}
+	switch p := page.(type) {
+	case *html.Node:
+		if s.css == nil {
+			return []interface{}{p}
+		}
+		for _, n := range cascadia.QueryAll(p, s.css) {
+			items = append(items, n)
+		}
+	default:
+		items = walkJSONPath(p, s.Path)
+	}
+	return items
+}
+
+// values returns the string values matched by the selector inside page
+func (s *Selector) values(page interface{}, pageURL string) []string {
+	if s.CSS == "" && s.Path == "" && s.Attr == "" && s.Value == "" {
+		return nil
+	}
+	if s.Value != "" {
+		return []string{s.Value}
+	}
+
+	var raw []string
+	for _, item := range s.items(page) {
+		switch v := item.(type) {
+		case *html.Node:
+			if s.Attr != "" {
+				raw = append(raw, nodeAttr(v, s.Attr))
+			} else {
+				raw = append(raw, nodeText(v))
+			}
+		default:
+			raw = append(raw, jsonString(v))
+		}
+	}
+
+	var values []string
+	for _, v := range raw {
+		v = strings.Join(strings.Fields(v), " ")
+		if s.regex != nil {
+			m := s.regex.FindStringSubmatch(v)
+			if m == nil {
+				continue
+			}
+			if len(m) > 1 {
+				v = m[1]
+			} else {
+				v = m[0]
+			}
+		}
+		if v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// walkJSONPath resolves a dot path, fanning out over arrays at any level
+func walkJSONPath(data interface{}, jsonPath string) []interface{} {
+	current := []interface{}{data}
+	if jsonPath != "" {
+		for _, key := range strings.Split(jsonPath, ".") {
+			var next []interface{}
+			for _, v := range current {
+				for _, item := range flattenJSON(v) {
+					obj, ok := item.(map[string]interface{})
+					if !ok {
+						continue
+					}
+					if child, ok := obj[key]; ok {
+						next = append(next, child)
+					}
+				}
+			}
+			current = next
+		}
+	}
+
+	var result []interface{}
+	for _, v := range current {
+		result = append(result, flattenJSON(v)...)
+	}
+	return result
+}
+
+func flattenJSON(v interface{}) []interface{} {
+	if arr, ok := v.([]interface{}); ok {
+		return arr
+	}
+	return []interface{}{v}
+}
+
+func jsonString(v interface{}) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(t)
+	case nil:
+		return ""
+	default:
+		b, _ := json.Marshal(t)
+		return string(b)
+	}
+}
+
+func nodeAttr(n *html.Node, key string) string {
+	for _, attr := range n.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+func nodeText(n *html.Node) string {
+	var sb strings.Builder
+	var walk func(*html.Node)
+	walk = func(node *html.Node) {
+		if node.Type == html.TextNode {
+			sb.WriteString(node.Data)
+			sb.WriteString(" ")
+		}
+		for c := node.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return sb.String()
+}
+
+func firstOr(values []string, fallback string) string {
+	if len(values) > 0 {
+		return values[0]
+	}
+	return fallback
+}
+
+// resolveURL makes ref absolute relative to base
+func resolveURL(base, ref string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return ""
+	}
+	baseURL, err := neturl.Parse(base)
+	if err != nil {
+		return ref
+	}
+	refURL, err := neturl.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return baseURL.ResolveReference(refURL).String()
+}
+
+func urlPath(rawURL string) string {
+	if u, err := neturl.Parse(rawURL); err == nil {
+		return u.Path
+	}
+	return rawURL
+}
@@ -0,0 +1,316 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"manga-visor/internal/persistence"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// Assumed page size for a site nothing was downloaded from yet
+	defaultPageSize = 512 << 10
+	// Pages asked for their size with HEAD before a job starts
+	sizeSamplePages   = 3
+	sizeSampleTimeout = 15 * time.Second
+	// How long the download folder size is reused before walking it again
+	usageCacheTTL = 30 * time.Second
+)
+
+// downloadUsage caches the size of the download folder, walking it for every job
+// would be slow on big libraries
+type downloadUsage struct {
+	mu    sync.Mutex
+	path  string
+	bytes int64
+	at    time.Time
+}
+
+func (u *downloadUsage) get(path string) int64 {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.path == path && time.Since(u.at) < usageCacheTTL {
+		return u.bytes
+	}
+	var total int64
+	filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			if info, err := d.Info(); err == nil {
+				total += info.Size()
+			}
+		}
+		return nil
+	})
+	u.path, u.bytes, u.at = path, total, time.Now()
+	return total
+}
+
+// add counts bytes written since the folder was walked
+func (u *downloadUsage) add(n int64) {
+	u.mu.Lock()
+	if !u.at.IsZero() {
+		u.bytes += n
+	}
+	u.mu.Unlock()
+}
+
+func (u *downloadUsage) invalidate() {
+	u.mu.Lock()
+	u.at = time.Time{}
+	u.mu.Unlock()
+}
+
+// DownloadSpace is the disk state of the download folder shown in settings
+type DownloadSpace struct {
+	Used int64 `json:"used"`
+	// -1 when it can't be read
+	Free  int64 `json:"free"`
+	Quota int64 `json:"quota"` // 0 = no quota
+}
+
+// GetDownloadSpace returns the size of the download folder, the free space and the quota
+func (m *Module) GetDownloadSpace() DownloadSpace {
+	basePath := m.downloadBasePath()
+	space := DownloadSpace{
+		Used:  m.usage.get(basePath),
+		Free:  -1,
+		Quota: int64(m.sm.Get().DownloadQuotaMB) << 20,
+	}
+	if free, err := diskFree(existingDir(basePath)); err == nil {
+		space.Free = int64(free)
+	}
+	return space
+}
+
+// spaceReservation is the space a running job was estimated to need, minus what it wrote
+type spaceReservation struct {
+	estimate int64
+	written  atomic.Int64
+}
+
+// remaining returns the bytes the job is still expected to write
+func (r *spaceReservation) remaining() int64 {
+	return max(r.estimate-r.written.Load(), 0)
+}
+
+// pendingSpace sums what the running jobs are still expected to write
+func (m *Module) pendingSpace() int64 {
+	var pending int64
+	m.pendingBytes.Range(func(_, v interface{}) bool {
+		pending += v.(*spaceReservation).remaining()
+		return true
+	})
+	return pending
+}
+
+// preflightJob checks that the missing pages of a job fit on the disk and in the quota.
+// The estimate is reserved until releasePreflight, so jobs starting together don't count
+// the same free space twice; pageWritten checks again as the pages come in.
+func (m *Module) preflightJob(ctx context.Context, job persistence.DownloadJob, info *SiteInfo, pagePath func(int, ImageDownload) string) error {
+	var missing []int
+	for i, img := range info.Images {
+		if fInfo, err := os.Stat(pagePath(i, img)); err != nil || fInfo.Size() == 0 {
+			missing = append(missing, i)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	estimate := m.estimateSize(ctx, info, missing)
+	others := m.pendingSpace()
+
+	settings := m.sm.Get()
+	basePath := m.downloadBasePath()
+
+	if free, err := diskFree(existingDir(basePath)); err == nil {
+		reserve := int64(settings.MinFreeSpaceMB) << 20
+		if estimate+others+reserve > int64(free) {
+			return fmt.Errorf("not enough disk space: about %s needed, %s free (keeping %s free)",
+				formatBytes(estimate), formatBytes(int64(free)-others), formatBytes(reserve))
+		}
+	} else {
+		fmt.Printf("[Downloader] Could not read free space of %s: %v\n", basePath, err)
+	}
+
+	if settings.DownloadQuotaMB > 0 {
+		quota := int64(settings.DownloadQuotaMB) << 20
+		used := m.usage.get(basePath) + others
+		if used+estimate > quota {
+			return fmt.Errorf("download folder quota exceeded: %s used, about %s needed, quota %s",
+				formatBytes(used), formatBytes(estimate), formatBytes(quota))
+		}
+	}
+
+	m.pendingBytes.Store(job.ID, &spaceReservation{estimate: estimate})
+	return nil
+}
+
+// pageWritten counts a written page against the job's reservation and checks the disk and
+// the quota again. While the jobs stay within their estimates the preflight still holds;
+// a job that outgrows its estimate, or another program filling the disk, can break it,
+// and the job is paused then instead of filling the disk or the quota.
+func (m *Module) pageWritten(jobID string, path string) {
+	fInfo, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	m.usage.add(fInfo.Size())
+	v, ok := m.pendingBytes.Load(jobID)
+	if !ok {
+		return
+	}
+	v.(*spaceReservation).written.Add(fInfo.Size())
+
+	pending := m.pendingSpace()
+	settings := m.sm.Get()
+	basePath := m.downloadBasePath()
+	reason := ""
+	if free, err := diskFree(existingDir(basePath)); err == nil {
+		if reserve := int64(settings.MinFreeSpaceMB) << 20; pending+reserve > int64(free) {
+			reason = fmt.Sprintf("not enough disk space: %s free, keeping %s free", formatBytes(int64(free)), formatBytes(reserve))
+		}
+	}
+	if settings.DownloadQuotaMB > 0 {
+		quota := int64(settings.DownloadQuotaMB) << 20
+		if used := m.usage.get(basePath); used+pending > quota {
+			reason = fmt.Sprintf("download folder quota exceeded: %s used, quota %s", formatBytes(used), formatBytes(quota))
+		}
+	}
+	if reason != "" {
+		m.pauseForSpace(jobID, reason)
+	}
+}
+
+// pauseForSpace pauses a running job like PauseJob, recording why
+func (m *Module) pauseForSpace(jobID string, reason string) {
+	m.queueLock.Lock()
+	data, ok := m.activeJobs.Load(jobID)
+	if !ok || data.(*activeJob).pausing {
+		m.queueLock.Unlock()
+		return
+	}
+	aj := data.(*activeJob)
+	aj.pausing = true
+	aj.resumeAfterPause = false
+	aj.pauseReason = reason
+	m.queueLock.Unlock()
+
+	fmt.Printf("[Downloader] Pausing job %s: %s\n", jobID, reason)
+	aj.cancel()
+}
+
+// releasePreflight drops the estimate of a job once it stopped writing
+func (m *Module) releasePreflight(jobID string) {
+	m.pendingBytes.Delete(jobID)
+	m.usage.invalidate()
+}
+
+// estimateSize guesses the size of the given pages from the Content-Length of a few
+// of them, or else from what the site's pages weighed in earlier downloads
+func (m *Module) estimateSize(ctx context.Context, info *SiteInfo, pages []int) int64 {
+	client := m.clients.newClient()
+	client.Timeout = sizeSampleTimeout
+
+	step := len(pages) / sizeSamplePages
+	if step < 1 {
+		step = 1
+	}
+	var sampled, total int64
+	for i := 0; i < len(pages) && sampled < sizeSamplePages; i += step {
+		if size := headSize(ctx, client, info.Images[pages[i]]); size > 0 {
+			sampled++
+			total += size
+		}
+	}
+	if sampled > 0 {
+		return total / sampled * int64(len(pages))
+	}
+	return m.siteAverageSize(info.SiteID) * int64(len(pages))
+}
+
+// headSize returns the Content-Length of a page, 0 when the server doesn't tell
+func headSize(ctx context.Context, client *http.Client, img ImageDownload) int64 {
+	req, err := http.NewRequestWithContext(ctx, "HEAD", img.URL, nil)
+	if err != nil {
+		return 0
+	}
+	if img.Headers != nil {
+		for k, v := range img.Headers {
+			req.Header.Set(k, v)
+		}
+	} else {
+		req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) Chrome/120.0.0.0")
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.ContentLength < 0 {
+		return 0
+	}
+	return resp.ContentLength
+}
+
+// siteAverageSize returns the average page size of the completed jobs of a site
+func (m *Module) siteAverageSize(siteID string) int64 {
+	var bytes, pages int64
+	for _, job := range m.pm.GetJobs() {
+		if job.Site == siteID && job.Bytes > 0 && job.TotalPages > 0 {
+			bytes += job.Bytes
+			pages += int64(job.TotalPages)
+		}
+	}
+	if pages == 0 {
+		return defaultPageSize
+	}
+	return bytes / pages
+}
+
+// holdJob pauses a job that can't start yet, keeping its resolved images for ResumeJob
+func (m *Module) holdJob(job persistence.DownloadJob, info *SiteInfo, reason string) {
+	m.queueLock.Lock()
+	m.paused[job.ID] = &queuedJob{job: job, info: info}
+	m.queueLock.Unlock()
+
+	fmt.Printf("[Downloader] Paused job %s: %s\n", job.ID, reason)
+	m.pm.UpdateJob(job.ID, map[string]interface{}{
+		"status": persistence.StatusPaused,
+		"error":  reason,
+	})
+	m.notifyUpdate()
+}
+
+// existingDir returns the closest existing directory of a path, for disk queries
+func existingDir(path string) string {
+	for {
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			return path
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path
+		}
+		path = parent
+	}
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
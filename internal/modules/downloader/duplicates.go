@@ -0,0 +1,519 @@
+package downloader
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"manga-visor/internal/fileloader"
+	"manga-visor/internal/persistence"
+	"math/bits"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	_ "github.com/gen2brain/avif"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// Duplicate actions from settings
+const (
+	DuplicateOff  = "off"
+	DuplicateWarn = "warn"
+	DuplicateSkip = "skip"
+)
+
+const (
+	// Pages hashed per indexed entry, and fetched from the site to compare
+	indexedPages = 4
+	remotePages  = 2
+	// Differing bits under which two page hashes are the same image
+	hashDistance = 5
+	// Titles this long are unique enough to match without the series
+	uniqueTitleLength = 16
+	// Folders below an index root that are looked into
+	indexDepth = 4
+	// Largest page read to hash it
+	maxHashedPage = 32 << 20
+	// Time the site gets to serve the pages hashed for a check, the title check runs without them
+	remoteHashTimeout = 15 * time.Second
+)
+
+// errDuplicate is returned by startDownload, or recorded on the job by comparePages,
+// when the duplicate action is skip
+var errDuplicate = errors.New("already downloaded")
+
+// DuplicateMatch is a local chapter or gallery a download seems to be a copy of
+type DuplicateMatch struct {
+	Path string `json:"path"`
+	// "title" or "pages"
+	Reason string `json:"reason"`
+	// "downloads" or "library"
+	Source string `json:"source"`
+}
+
+var bracketedRe = regexp.MustCompile(`\[[^\]]*\]|\([^)]*\)|\{[^}]*\}`)
+
+// normalizeTitle reduces a title to what stays the same across sites: no tags in
+// brackets, no punctuation, lower case
+func normalizeTitle(title string) string {
+	title = bracketedRe.ReplaceAllString(title, " ")
+	title = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return ' '
+	}, title)
+	return strings.Join(strings.Fields(title), " ")
+}
+
+// pageHash is a 64 bit difference hash: it survives re-encoding and resizing,
+// so the same page from two sites hashes (almost) the same
+func pageHash(img image.Image) uint64 {
+	small := image.NewGray(image.Rect(0, 0, 9, 8))
+	draw.ApproxBiLinear.Scale(small, small.Bounds(), img, img.Bounds(), draw.Src, nil)
+	var hash uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			hash <<= 1
+			if small.GrayAt(x, y).Y < small.GrayAt(x+1, y).Y {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// usefulHash leaves out near blank pages, which all hash alike
+func usefulHash(hash uint64) bool {
+	n := bits.OnesCount64(hash)
+	return n >= 8 && n <= 56
+}
+
+func hashReader(r io.Reader) (uint64, bool) {
+	img, _, err := image.Decode(io.LimitReader(r, maxHashedPage))
+	if err != nil {
+		return 0, false
+	}
+	hash := pageHash(img)
+	return hash, usefulHash(hash)
+}
+
+func formatHashes(hashes []uint64) []string {
+	out := make([]string, len(hashes))
+	for i, h := range hashes {
+		out[i] = strconv.FormatUint(h, 16)
+	}
+	return out
+}
+
+// indexEntry builds the index entry of a folder of images or a .cbz, false if it has no pages
+func indexEntry(path string, source string) (persistence.ContentEntry, bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return persistence.ContentEntry{}, false
+	}
+
+	var pages int
+	var hashes []uint64
+	name := filepath.Base(path)
+	if info.IsDir() {
+		pages, hashes = hashFolder(path)
+	} else {
+		name = strings.TrimSuffix(name, filepath.Ext(name))
+		pages, hashes = hashArchive(path)
+	}
+	if pages == 0 {
+		return persistence.ContentEntry{}, false
+	}
+	return persistence.ContentEntry{
+		Path:    path,
+		Title:   normalizeTitle(name),
+		Series:  normalizeTitle(filepath.Base(filepath.Dir(path))),
+		Number:  nameNumber(name),
+		Pages:   pages,
+		Hashes:  formatHashes(hashes),
+		ModTime: info.ModTime().Format(time.RFC3339Nano),
+		Source:  source,
+	}, true
+}
+
+// nameNumber returns the chapter number in a name, "" if it has none
+func nameNumber(name string) string {
+	if n, ok := chapterNumber(ChapterInfo{Name: name}); ok {
+		return strconv.FormatFloat(n, 'f', -1, 64)
+	}
+	return ""
+}
+
+func isPage(name string) bool {
+	_, ok := fileloader.SupportedExtensions[strings.ToLower(filepath.Ext(name))]
+	return ok
+}
+
+func hashFolder(dir string) (int, []uint64) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, nil
+	}
+	var pages []string
+	for _, e := range entries {
+		if !e.IsDir() && isPage(e.Name()) {
+			pages = append(pages, e.Name())
+		}
+	}
+	sort.Slice(pages, func(i, j int) bool { return fileloader.NaturalLess(pages[i], pages[j]) })
+
+	var hashes []uint64
+	for i := 0; i < len(pages) && i < indexedPages; i++ {
+		f, err := os.Open(filepath.Join(dir, pages[i]))
+		if err != nil {
+			continue
+		}
+		if hash, ok := hashReader(f); ok {
+			hashes = append(hashes, hash)
+		}
+		f.Close()
+	}
+	return len(pages), hashes
+}
+
+func hashArchive(path string) (int, []uint64) {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return 0, nil
+	}
+	defer r.Close()
+
+	var pages []*zip.File
+	for _, f := range r.File {
+		if isPage(f.Name) && f.UncompressedSize64 > 0 {
+			pages = append(pages, f)
+		}
+	}
+	sort.Slice(pages, func(i, j int) bool { return fileloader.NaturalLess(pages[i].Name, pages[j].Name) })
+
+	var hashes []uint64
+	for i := 0; i < len(pages) && i < indexedPages; i++ {
+		rc, err := pages[i].Open()
+		if err != nil {
+			continue
+		}
+		if hash, ok := hashReader(rc); ok {
+			hashes = append(hashes, hash)
+		}
+		rc.Close()
+	}
+	return len(pages), hashes
+}
+
+// SetIndexRoots sets where the library folders indexed for duplicates come from
+func (m *Module) SetIndexRoots(roots func() []string) {
+	m.indexRoots = roots
+}
+
+// RebuildContentIndex rescans the download folder and the library. Entries whose
+// modification time didn't change are kept without hashing them again.
+func (m *Module) RebuildContentIndex() int {
+	m.indexLock.Lock()
+	defer m.indexLock.Unlock()
+
+	known := make(map[string]persistence.ContentEntry)
+	for _, entry := range m.ci.GetEntries() {
+		known[entry.Path] = entry
+	}
+	// Only the jobs know the language and group of a download
+	releases := make(map[string]persistence.DownloadJob)
+	for _, job := range m.pm.GetJobs() {
+		if job.Path != "" {
+			releases[filepath.Clean(job.Path)] = job
+		}
+	}
+
+	var entries []persistence.ContentEntry
+	seen := make(map[string]bool)
+	add := func(path string, source string) {
+		if seen[path] {
+			return
+		}
+		seen[path] = true
+		if info, err := os.Stat(path); err == nil {
+			if entry, ok := known[path]; ok && entry.ModTime == info.ModTime().Format(time.RFC3339Nano) {
+				entry.Source = source
+				entries = append(entries, entry)
+				return
+			}
+		}
+		if entry, ok := indexEntry(path, source); ok {
+			if job, ok := releases[filepath.Clean(path)]; ok {
+				entry.Language, entry.Group = job.Language, job.ScanGroup
+			}
+			entries = append(entries, entry)
+		}
+	}
+
+	walkIndexRoot(m.downloadBasePath(), func(path string) { add(path, "downloads") })
+	if m.indexRoots != nil {
+		for _, root := range m.indexRoots() {
+			walkIndexRoot(root, func(path string) { add(path, "library") })
+		}
+	}
+
+	m.ci.SetEntries(entries)
+	fmt.Printf("[Downloader] Content index rebuilt: %d entries\n", len(entries))
+	return len(entries)
+}
+
+// walkIndexRoot calls fn for every folder with pages and every .cbz under root
+func walkIndexRoot(root string, fn func(path string)) {
+	var walk func(dir string, depth int)
+	walk = func(dir string, depth int) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return
+		}
+		hasPages := false
+		for _, e := range entries {
+			name := e.Name()
+			if strings.HasPrefix(name, ".") {
+				continue
+			}
+			switch {
+			case e.IsDir():
+				if depth < indexDepth {
+					walk(filepath.Join(dir, name), depth+1)
+				}
+			case strings.EqualFold(filepath.Ext(name), ".cbz"):
+				fn(filepath.Join(dir, name))
+			case isPage(name):
+				hasPages = true
+			}
+		}
+		if hasPages {
+			fn(dir)
+		}
+	}
+	walk(root, 0)
+}
+
+// indexJob adds the files of a completed job to the index
+func (m *Module) indexJob(jobID string) {
+	job, ok := m.findJob(jobID)
+	if !ok || job.Path == "" {
+		return
+	}
+	if entry, ok := indexEntry(job.Path, "downloads"); ok {
+		entry.Language, entry.Group = job.Language, job.ScanGroup
+		m.ci.PutEntry(entry)
+	}
+}
+
+// remoteHashes hashes the first pages of a chapter straight from the site, as many as
+// it gets before ctx is done
+func (f *clientFactory) remoteHashes(ctx context.Context, info *SiteInfo) []uint64 {
+	ctx, cancel := context.WithTimeout(ctx, remoteHashTimeout)
+	defer cancel()
+	client := f.newClient()
+	client.Timeout = 0 // ctx bounds the whole check
+
+	var hashes []uint64
+	for i := 0; i < len(info.Images) && i < remotePages; i++ {
+		img := info.Images[i]
+		req, err := http.NewRequestWithContext(ctx, "GET", img.URL, nil)
+		if err != nil {
+			continue
+		}
+		if img.Headers != nil {
+			for k, v := range img.Headers {
+				req.Header.Set(k, v)
+			}
+		} else {
+			req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) Chrome/120.0.0.0")
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			continue
+		}
+		data, err := io.ReadAll(io.LimitReader(resp.Body, maxHashedPage))
+		resp.Body.Close()
+		if err != nil || resp.StatusCode != http.StatusOK {
+			continue
+		}
+		if hash, ok := hashReader(bytes.NewReader(data)); ok {
+			hashes = append(hashes, hash)
+		}
+	}
+	return hashes
+}
+
+// titleMatches reports whether an entry has the name of the chapter: same series and
+// same title or chapter number, or the same long title (galleries)
+func titleMatches(entry persistence.ContentEntry, series string, title string, number string) bool {
+	if title == "" {
+		// Galleries have no chapter, their folder is named after the series
+		return entry.Title == series && len(series) >= 8
+	}
+	if entry.Title == title && len(title) >= uniqueTitleLength {
+		return true
+	}
+	if entry.Series != series || series == "" {
+		return false
+	}
+	if entry.Title == title {
+		return true
+	}
+	return number != "" && entry.Number == number
+}
+
+// sameRelease reports whether an entry can be the same release: a download in another
+// language or by another group is not a duplicate. Unknown values match anything.
+func sameRelease(entry persistence.ContentEntry, language string, group string) bool {
+	if entry.Language != "" && language != "" && !strings.EqualFold(entry.Language, language) {
+		return false
+	}
+	if entry.Group != "" && group != "" && normalizeTitle(entry.Group) != normalizeTitle(group) {
+		return false
+	}
+	return true
+}
+
+// pagesMatch reports whether the remote pages are among the first pages of an entry
+func pagesMatch(entry persistence.ContentEntry, remote []uint64) bool {
+	if len(remote) == 0 {
+		return false
+	}
+	matched := 0
+	for _, r := range remote {
+		for _, text := range entry.Hashes {
+			if h, err := strconv.ParseUint(text, 16, 64); err == nil && bits.OnesCount64(h^r) <= hashDistance {
+				matched++
+				break
+			}
+		}
+	}
+	return matched == len(remote)
+}
+
+// findDuplicates looks for a chapter in the index by name and, when given, by page hashes
+func findDuplicates(entries []persistence.ContentEntry, info *SiteInfo, remote []uint64) []DuplicateMatch {
+	series := normalizeTitle(info.SeriesName)
+	title := normalizeTitle(info.ChapterName)
+	number := info.ChapterNumber
+	if number == "" && info.ChapterName != "" {
+		number = nameNumber(info.ChapterName)
+	} else if number != "" {
+		// "12.50" and "12.5" are the same chapter
+		number = nameNumber(number)
+	}
+
+	var matches []DuplicateMatch
+	for _, entry := range entries {
+		if !sameRelease(entry, info.Language, info.ScanGroup) {
+			continue
+		}
+		reason := ""
+		if titleMatches(entry, series, title, number) {
+			reason = "title"
+		} else if pagesMatch(entry, remote) {
+			reason = "pages"
+		}
+		if reason == "" {
+			continue
+		}
+		// Deleted since it was indexed
+		if _, err := os.Stat(entry.Path); err != nil {
+			continue
+		}
+		matches = append(matches, DuplicateMatch{Path: entry.Path, Reason: reason, Source: entry.Source})
+	}
+	return matches
+}
+
+// jobDuplicate returns the first local copy of a job's chapter other than the job's own
+// folder, by name and by the remote page hashes when given. A series listing may know the
+// release better than the chapter page, job fills the gaps.
+func (m *Module) jobDuplicate(info *SiteInfo, job *persistence.DownloadJob, remote []uint64) string {
+	check := *info
+	ownPath := ""
+	if job != nil {
+		ownPath = job.Path
+		if check.ChapterNumber == "" {
+			check.ChapterNumber = job.ChapterNumber
+		}
+		if check.Language == "" {
+			check.Language = job.Language
+		}
+		if check.ScanGroup == "" {
+			check.ScanGroup = job.ScanGroup
+		}
+	}
+	for _, match := range findDuplicates(m.ci.GetEntries(), &check, remote) {
+		if ownPath == "" || filepath.Clean(match.Path) != filepath.Clean(ownPath) {
+			return match.Path
+		}
+	}
+	return ""
+}
+
+// comparePages runs the page hash check startDownload left to the job, ctx is the job's.
+// Reports whether the job goes on, false when it was skipped as a duplicate.
+func (m *Module) comparePages(ctx context.Context, job persistence.DownloadJob, info *SiteInfo) bool {
+	remote := m.clients.remoteHashes(ctx, info)
+	if ctx.Err() != nil {
+		// Cancelled or paused, a resumed job checks again
+		return true
+	}
+	info.comparePages = false
+	duplicateOf := m.jobDuplicate(info, &job, remote)
+	if duplicateOf == "" {
+		return true
+	}
+	if m.duplicateAction() == DuplicateSkip {
+		fmt.Printf("[Downloader] Skipping %s, already downloaded at %s\n", job.URL, duplicateOf)
+		m.pm.UpdateJob(job.ID, map[string]interface{}{
+			"status": persistence.StatusCancelled,
+			"error":  fmt.Errorf("%w: %s", errDuplicate, duplicateOf).Error(),
+		})
+		m.notifyUpdate()
+		return false
+	}
+	fmt.Printf("[Downloader] %s looks like a duplicate of %s\n", job.URL, duplicateOf)
+	m.pm.UpdateJob(job.ID, map[string]interface{}{"duplicateOf": duplicateOf})
+	m.notifyUpdate()
+	return true
+}
+
+// markLocalChapters fills LocalPath for the chapters of a series already on disk, by name only
+func (m *Module) markLocalChapters(info *SiteInfo) {
+	entries := m.ci.GetEntries()
+	for i := range info.Chapters {
+		ch := &info.Chapters[i]
+		details := &SiteInfo{SeriesName: info.SeriesName, ChapterName: ch.Name, ChapterNumber: ch.Number, Language: ch.Language, ScanGroup: ch.ScanGroup}
+		if matches := findDuplicates(entries, details, nil); len(matches) > 0 {
+			ch.LocalPath = matches[0].Path
+		}
+	}
+}
+
+// duplicateAction returns the configured action, warn when unset
+func (m *Module) duplicateAction() string {
+	switch action := m.sm.Get().DuplicateAction; action {
+	case DuplicateOff, DuplicateSkip:
+		return action
+	default:
+		return DuplicateWarn
+	}
+}
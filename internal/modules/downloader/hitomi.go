@@ -16,10 +16,15 @@ import (
 )
 
 type HitomiDownloader struct {
+	withClients
+	mu        sync.Mutex // guards gg and cdnDomain, galleries and list pages resolve concurrently
 	gg        *GG
 	cdnDomain string
 }
 
+// defaultHitomiCDN is used for gallery data until RefreshGG found the current CDN
+const defaultHitomiCDN = "ltn.gold-usergeneratedcontent.net"
+
 type GG struct {
 	MDefault int         `json:"m_default"`
 	MMap     map[int]int `json:"m_map"`
@@ -43,7 +48,7 @@ func (d *HitomiDownloader) GetSiteID() string {
 }
 
 func (d *HitomiDownloader) RefreshGG(galleryURL string) error {
-	client := &http.Client{}
+	client := d.newHTTPClient()
 
 	// First, fetch the gallery page to find the CDN domain
 	req, _ := http.NewRequest("GET", galleryURL, nil)
@@ -115,11 +120,24 @@ func (d *HitomiDownloader) RefreshGG(galleryURL string) error {
 		gg.B = matchB[1]
 	}
 
+	d.mu.Lock()
 	d.gg = gg
 	d.cdnDomain = cdnDomain
+	d.mu.Unlock()
 	return nil
 }
 
+// cdn returns the gg.js values and the CDN domain found by the last RefreshGG.
+// gg is nil until RefreshGG succeeded; the domain falls back to defaultHitomiCDN.
+func (d *HitomiDownloader) cdn() (*GG, string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.cdnDomain == "" {
+		return d.gg, defaultHitomiCDN
+	}
+	return d.gg, d.cdnDomain
+}
+
 type HitomiGallery struct {
 	Files []struct {
 		Hash    string `json:"hash"`
@@ -185,10 +203,12 @@ func (d *HitomiDownloader) GetImages(url string) (*SiteInfo, error) {
 		return d.handleSearchURL(url)
 	}
 
-	if d.gg == nil {
+	gg, cdnDomain := d.cdn()
+	if gg == nil {
 		if err := d.RefreshGG(url); err != nil {
 			return nil, err
 		}
+		gg, cdnDomain = d.cdn()
 	}
 
 	// Extract ID from URL
@@ -223,20 +243,20 @@ func (d *HitomiDownloader) GetImages(url string) (*SiteInfo, error) {
 
 			// Calculate subdomain using gg.m()
 			// Subdomain = "a" + (1 + gg.m(g))
-			mResult := d.gg.m(int(num))
+			mResult := gg.m(int(num))
 			subdomain = fmt.Sprintf("a%d", 1+mResult)
 		}
 
 		// Build the CDN domain with calculated subdomain
 		// e.g., "a2.gold-usergeneratedcontent.net"
-		baseDomain := strings.TrimPrefix(d.cdnDomain, "ltn.")
+		baseDomain := strings.TrimPrefix(cdnDomain, "ltn.")
 		imageDomain := subdomain + "." + baseDomain
 
 		// Hitomi serves images in AVIF format
 		ext := "avif"
 
 		// Construct URL: https://a{n}.domain/{gg.B}{subdir}/{hash}.{ext}
-		imageURL := fmt.Sprintf("https://%s/%s%s/%s.%s", imageDomain, d.gg.B, subdir, hash, ext)
+		imageURL := fmt.Sprintf("https://%s/%s%s/%s.%s", imageDomain, gg.B, subdir, hash, ext)
 
 		images[i] = ImageDownload{
 			URL:      imageURL,
@@ -354,18 +374,12 @@ func (d *HitomiDownloader) handleSearchURL(rawURL string) (*SiteInfo, error) {
 }
 
 func (d *HitomiDownloader) fetchGalleryData(galleryID string) (*HitomiGallery, error) {
-	// Ensure CDN domain is available
-	if d.cdnDomain == "" {
-		// Provide a fallback default if not yet initialized, though RefreshGG should have been called.
-		// For bulk title fetching, we might not have called RefreshGG yet.
-		// Let's use the known new default.
-		d.cdnDomain = "ltn.gold-usergeneratedcontent.net"
-	}
+	// Bulk title fetching may run before RefreshGG, cdn falls back to the known default then
+	_, cdnDomain := d.cdn()
 
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
-	jsonURL := fmt.Sprintf("https://%s/galleries/%s.js", d.cdnDomain, galleryID)
+	client := d.newHTTPClient()
+	client.Timeout = 10 * time.Second
+	jsonURL := fmt.Sprintf("https://%s/galleries/%s.js", cdnDomain, galleryID)
 	req, _ := http.NewRequest("GET", jsonURL, nil)
 	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) Chrome/120.0.0.0")
 	req.Header.Set("Referer", "https://hitomi.la/")
@@ -393,7 +407,7 @@ func (d *HitomiDownloader) fetchGalleryData(galleryID string) (*HitomiGallery, e
 }
 
 func (d *HitomiDownloader) getArbitraryList(url string) (*SiteInfo, error) {
-	client := &http.Client{}
+	client := d.newHTTPClient()
 
 	allChapters := []ChapterInfo{}
 	page := 1
@@ -608,7 +622,7 @@ func (d *HitomiDownloader) getNozomiList(url string) (*SiteInfo, error) {
 	// The /ids/ path segment is removed on the new domain.
 	nozomiURL := fmt.Sprintf("https://ltn.gold-usergeneratedcontent.net/%s/%s.nozomi", typeName, name)
 
-	client := &http.Client{}
+	client := d.newHTTPClient()
 	req, _ := http.NewRequest("GET", nozomiURL, nil)
 	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) Chrome/120.0.0.0")
 	req.Header.Set("Referer", "https://hitomi.la/")
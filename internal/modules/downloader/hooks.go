@@ -0,0 +1,343 @@
+package downloader
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"manga-visor/internal/persistence"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Hook events
+const (
+	HookCompleted           = "completed"
+	HookCompletedWithErrors = "completed_with_errors" // Finished with pages missing, RetryFailedPages can fetch them
+	HookFailed              = "failed"
+	HookBatchCompleted      = "batch_completed"
+	HookNewChapters         = "new_chapters"
+)
+
+const (
+	defaultHookTimeout = 30 * time.Second
+	// Logs are rotated to <id>.log.1 past this size
+	maxHookLogSize = 1 << 20
+	// Output kept per run in the log
+	maxHookOutput = 64 << 10
+	// Hooks running at the same time, the rest wait in the queue
+	hookWorkers = 2
+	// Runs waiting past this are dropped, so a stuck hook can't pile up work
+	hookQueueSize = 100
+)
+
+// hookRun is a hook waiting in the queue with the event it was fired for
+type hookRun struct {
+	hook persistence.DownloadHook
+	ev   HookEvent
+}
+
+// HookEvent is what a hook receives: as placeholders in its command, as JSON in its webhook
+type HookEvent struct {
+	Event   string `json:"event"`
+	JobID   string `json:"jobId,omitempty"`
+	BatchID string `json:"batchId,omitempty"`
+	Site    string `json:"site,omitempty"`
+	Series  string `json:"series,omitempty"`
+	Chapter string `json:"chapter,omitempty"`
+	URL     string `json:"url,omitempty"`
+	Path    string `json:"path,omitempty"`
+	Status  string `json:"status,omitempty"`
+	Error   string `json:"error,omitempty"`
+	// Pages that could not be downloaded, for completed_with_errors
+	FailedPages int `json:"failedPages,omitempty"`
+	// New chapter names, for new_chapters
+	Chapters []string `json:"chapters,omitempty"`
+	Time     string   `json:"time"` // RFC3339
+}
+
+// jobHookEvent builds the event of a job from its stored state
+func (m *Module) jobHookEvent(event string, jobID string) (HookEvent, bool) {
+	job, ok := m.findJob(jobID)
+	if !ok {
+		return HookEvent{}, false
+	}
+	return HookEvent{
+		Event:   event,
+		JobID:   job.ID,
+		BatchID: job.BatchID,
+		Site:    job.Site,
+		Series:  job.SeriesName,
+		Chapter: job.ChapterName,
+		URL:     job.URL,
+		Path:    job.Path,
+		Status:  string(job.Status),
+		Error:   job.Error,
+
+		FailedPages: len(job.FailedPages),
+	}, true
+}
+
+// jobFinished fires the hooks of a job that reached a final state, and the batch
+// hooks if it was the last job of its batch
+func (m *Module) jobFinished(jobID string, event string) {
+	ev, ok := m.jobHookEvent(event, jobID)
+	if !ok {
+		return
+	}
+	m.fireHooks(ev)
+
+	if ev.BatchID == "" {
+		return
+	}
+	progress := m.GetBatchProgress(ev.BatchID)
+	if progress.Active > 0 {
+		return
+	}
+	// Two jobs finishing together may both see the batch done
+	if _, fired := m.finishedBatches.LoadOrStore(ev.BatchID, true); fired {
+		return
+	}
+	batchEv := HookEvent{
+		Event:   HookBatchCompleted,
+		BatchID: ev.BatchID,
+		Site:    ev.Site,
+		Series:  ev.Series,
+		Path:    filepath.Dir(ev.Path),
+		Status:  string(persistence.StatusCompleted),
+	}
+	if progress.Failed > 0 {
+		batchEv.Status = string(persistence.StatusCompletedWithErrors)
+		batchEv.Error = fmt.Sprintf("%d of %d chapters failed", progress.Failed, progress.Total)
+	}
+	m.fireHooks(batchEv)
+}
+
+// startHookWorkers starts the goroutines that run queued hooks
+func (m *Module) startHookWorkers() {
+	for i := 0; i < hookWorkers; i++ {
+		go func() {
+			for run := range m.hookQueue {
+				m.runHook(run.hook, run.ev)
+			}
+		}()
+	}
+}
+
+// fireHooks queues every enabled hook listening to the event, they run in the background
+func (m *Module) fireHooks(ev HookEvent) {
+	if ev.Time == "" {
+		ev.Time = time.Now().Format(time.RFC3339)
+	}
+	for _, hook := range m.sm.Get().Hooks {
+		if !hook.Enabled || !containsFold(hook.Events, ev.Event) {
+			continue
+		}
+		select {
+		case m.hookQueue <- hookRun{hook: hook, ev: ev}:
+		default:
+			fmt.Printf("[Downloader] Hook queue full, skipped hook %s for %s\n", hook.Name, ev.Event)
+			appendHookLog(hook.ID, fmt.Sprintf("[%s] %s: %s %s\nskipped: too many hooks waiting\n\n", ev.Time, ev.Event, ev.Series, ev.Chapter))
+		}
+	}
+}
+
+// runHook runs the command and posts the webhook of a hook, logging the result
+func (m *Module) runHook(hook persistence.DownloadHook, ev HookEvent) error {
+	timeout := defaultHookTimeout
+	if hook.TimeoutSeconds > 0 {
+		timeout = time.Duration(hook.TimeoutSeconds) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var log strings.Builder
+	fmt.Fprintf(&log, "[%s] %s: %s %s\n", ev.Time, ev.Event, ev.Series, ev.Chapter)
+
+	var errs []string
+	if strings.TrimSpace(hook.Command) != "" {
+		if err := runHookCommand(ctx, hook.Command, ev, &log); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if strings.TrimSpace(hook.WebhookURL) != "" {
+		if err := postHookWebhook(ctx, hook.WebhookURL, ev, &log); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	if len(errs) > 0 {
+		fmt.Fprintf(&log, "failed: %s\n\n", strings.Join(errs, "; "))
+		fmt.Printf("[Downloader] Hook %s failed: %s\n", hook.Name, strings.Join(errs, "; "))
+	} else {
+		log.WriteString("ok\n\n")
+	}
+	appendHookLog(hook.ID, log.String())
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func runHookCommand(ctx context.Context, commandLine string, ev HookEvent, log *strings.Builder) error {
+	args, err := splitCommandLine(commandLine)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return nil
+	}
+	// Placeholders are expanded per argument, so a path with spaces stays one argument
+	for i := range args {
+		args[i] = expandHookPlaceholders(args[i], ev)
+	}
+	fmt.Fprintf(log, "$ %s\n", strings.Join(args, " "))
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	output, err := cmd.CombinedOutput()
+	if len(output) > maxHookOutput {
+		output = append(output[:maxHookOutput], "\n[output truncated]"...)
+	}
+	log.Write(output)
+	if len(output) > 0 && output[len(output)-1] != '\n' {
+		log.WriteString("\n")
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("command timed out")
+	}
+	if err != nil {
+		return fmt.Errorf("command failed: %v", err)
+	}
+	return nil
+}
+
+func postHookWebhook(ctx context.Context, url string, ev HookEvent, log *strings.Builder) error {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid webhook URL: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	fmt.Fprintf(log, "POST %s\n", url)
+	// Webhooks are usually local, they don't go through the download proxy
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	reply, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	fmt.Fprintf(log, "%s\n", resp.Status)
+	if text := strings.TrimSpace(string(reply)); text != "" {
+		fmt.Fprintf(log, "%s\n", text)
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+func expandHookPlaceholders(arg string, ev HookEvent) string {
+	return strings.NewReplacer(
+		"{event}", ev.Event,
+		"{path}", ev.Path,
+		"{series}", ev.Series,
+		"{chapter}", ev.Chapter,
+		"{site}", ev.Site,
+		"{url}", ev.URL,
+		"{error}", ev.Error,
+	).Replace(arg)
+}
+
+// splitCommandLine splits a command line on spaces, keeping quoted parts together
+func splitCommandLine(line string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	inArg := false
+	var quote rune
+	for _, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unclosed quote in command")
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
+
+func hookLogPath(id string) string {
+	return filepath.Join(persistence.GetHooksDir(), sanitizeFilename(id)+".log")
+}
+
+func appendHookLog(id string, entry string) {
+	path := hookLogPath(id)
+	if info, err := os.Stat(path); err == nil && info.Size() > maxHookLogSize {
+		os.Rename(path, path+".1")
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		fmt.Printf("[Downloader] Failed to write hook log: %v\n", err)
+		return
+	}
+	defer f.Close()
+	f.WriteString(entry)
+}
+
+// GetHookLog returns the output log of a hook
+func (m *Module) GetHookLog(id string) (string, error) {
+	data, err := os.ReadFile(hookLogPath(id))
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	return string(data), err
+}
+
+// TestHook runs a hook right away with a sample event and waits for it
+func (m *Module) TestHook(id string) error {
+	for _, hook := range m.sm.Get().Hooks {
+		if hook.ID == id {
+			return m.runHook(hook, HookEvent{
+				Event:   "test",
+				Site:    "example.com",
+				Series:  "Test Series",
+				Chapter: "Chapter 1",
+				URL:     "https://example.com/chapter/1",
+				Path:    m.downloadBasePath(),
+				Status:  string(persistence.StatusCompleted),
+				Time:    time.Now().Format(time.RFC3339),
+			})
+		}
+	}
+	return fmt.Errorf("hook not found: %s", id)
+}
@@ -0,0 +1,208 @@
+package downloader
+
+import (
+	"encoding/json"
+	"manga-visor/internal/persistence"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setHooks saves the hooks in the settings, removing them again when the test ends
+func setHooks(t *testing.T, sm *persistence.SettingsManager, hooks ...persistence.DownloadHook) {
+	t.Helper()
+	data, err := json.Marshal(hooks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var value []interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		t.Fatal(err)
+	}
+	if err := sm.Update(map[string]interface{}{"hooks": value}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		sm.Update(map[string]interface{}{"hooks": []interface{}{}})
+	})
+}
+
+// newHookModule returns a Module with no hook workers, so queued runs stay in hookQueue
+func newHookModule(t *testing.T) *Module {
+	t.Helper()
+	pm := persistence.NewDownloaderManager()
+	pm.ClearJobs()
+	return &Module{
+		pm:        pm,
+		sm:        persistence.NewSettingsManager(),
+		hookQueue: make(chan hookRun, hookQueueSize),
+	}
+}
+
+// queuedRuns empties the hook queue of m
+func queuedRuns(m *Module) []hookRun {
+	var runs []hookRun
+	for {
+		select {
+		case run := <-m.hookQueue:
+			runs = append(runs, run)
+		default:
+			return runs
+		}
+	}
+}
+
+func TestJobFinishedQueuesHooks(t *testing.T) {
+	m := newHookModule(t)
+	setHooks(t, m.sm,
+		persistence.DownloadHook{ID: "jobs", Enabled: true, Events: []string{HookCompleted, HookCompletedWithErrors}},
+		persistence.DownloadHook{ID: "failures", Enabled: true, Events: []string{HookFailed}},
+		persistence.DownloadHook{ID: "batches", Enabled: true, Events: []string{"BATCH_COMPLETED"}},
+		persistence.DownloadHook{ID: "disabled", Enabled: false, Events: []string{HookCompleted}},
+	)
+	for _, job := range []persistence.DownloadJob{
+		{ID: "a", BatchID: "b", SeriesName: "Series", ChapterName: "Chapter 1", Status: persistence.StatusCompleted},
+		{ID: "c", BatchID: "b", SeriesName: "Series", ChapterName: "Chapter 2", Status: persistence.StatusCompletedWithErrors, FailedPages: []int{3, 4}},
+		{ID: "d", BatchID: "b", SeriesName: "Series", ChapterName: "Chapter 3", Status: persistence.StatusRunning},
+	} {
+		if err := m.pm.AddJob(job); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The batch still has a job downloading
+	m.jobFinished("a", HookCompleted)
+	runs := queuedRuns(m)
+	if len(runs) != 1 || runs[0].hook.ID != "jobs" || runs[0].ev.JobID != "a" || runs[0].ev.Event != HookCompleted {
+		t.Fatalf("completed job queued %+v, want one run of hook jobs for job a", runs)
+	}
+	if runs[0].ev.Time == "" {
+		t.Error("queued event has no time")
+	}
+
+	m.jobFinished("c", HookCompletedWithErrors)
+	runs = queuedRuns(m)
+	if len(runs) != 1 || runs[0].hook.ID != "jobs" || runs[0].ev.Event != HookCompletedWithErrors {
+		t.Fatalf("job with missing pages queued %+v, want one completed_with_errors run of hook jobs", runs)
+	}
+	if runs[0].ev.FailedPages != 2 {
+		t.Errorf("completed_with_errors event has %d failed pages, want 2", runs[0].ev.FailedPages)
+	}
+
+	// Two jobs reaching a final state together both see the batch done, it fires once
+	m.pm.UpdateJob("d", map[string]interface{}{"status": persistence.StatusFailed})
+	m.jobFinished("d", HookFailed)
+	m.jobFinished("a", HookCompleted)
+	var batches []HookEvent
+	for _, run := range queuedRuns(m) {
+		if run.hook.ID == "batches" {
+			batches = append(batches, run.ev)
+		}
+	}
+	if len(batches) != 1 {
+		t.Fatalf("batch_completed queued %d times, want once", len(batches))
+	}
+	if batches[0].BatchID != "b" || batches[0].Status != string(persistence.StatusCompletedWithErrors) || batches[0].Error != "1 of 3 chapters failed" {
+		t.Errorf("batch event = %+v, want batch b completed with 1 of 3 chapters failed", batches[0])
+	}
+}
+
+func TestFireHooksDropsRunsWhenQueueFull(t *testing.T) {
+	m := newHookModule(t)
+	hook := persistence.DownloadHook{ID: "queue-full", Name: "full", Enabled: true, Events: []string{HookCompleted}}
+	setHooks(t, m.sm, hook)
+	os.Remove(hookLogPath(hook.ID))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < hookQueueSize+3; i++ {
+			m.fireHooks(HookEvent{Event: HookCompleted, Chapter: "Chapter"})
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("fireHooks blocked on a full queue")
+	}
+
+	if got := len(m.hookQueue); got != hookQueueSize {
+		t.Errorf("hook queue holds %d runs, want %d", got, hookQueueSize)
+	}
+	log, err := m.GetHookLog(hook.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(log, "skipped: too many hooks waiting"); got != 3 {
+		t.Errorf("hook log records %d skipped runs, want 3:\n%s", got, log)
+	}
+}
+
+func TestHookWorkersPostWebhooks(t *testing.T) {
+	received := make(chan HookEvent, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var ev HookEvent
+		if err := json.NewDecoder(r.Body).Decode(&ev); err != nil {
+			t.Errorf("webhook body: %v", err)
+		}
+		received <- ev
+	}))
+	defer srv.Close()
+
+	m := newTestModule(t, &fakeDownloader{prefix: "https://fake.test/"})
+	setHooks(t, m.sm, persistence.DownloadHook{ID: "webhook", Enabled: true, Events: []string{HookNewChapters}, WebhookURL: srv.URL})
+
+	m.fireHooks(HookEvent{Event: HookNewChapters, Series: "Series", Chapters: []string{"Chapter 5"}})
+	select {
+	case ev := <-received:
+		if ev.Event != HookNewChapters || ev.Series != "Series" || !reflect.DeepEqual(ev.Chapters, []string{"Chapter 5"}) {
+			t.Errorf("webhook received %+v", ev)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("webhook was not posted")
+	}
+	waitFor(t, "the hook log", func() bool {
+		log, _ := m.GetHookLog("webhook")
+		return strings.Contains(log, "ok\n")
+	})
+}
+
+func TestSplitCommandLine(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		want    []string
+		wantErr bool
+	}{
+		{name: "empty", line: "", want: nil},
+		{name: "blanks only", line: " \t ", want: nil},
+		{name: "plain", line: "notify-send done", want: []string{"notify-send", "done"}},
+		{name: "repeated blanks", line: "  a \t b  ", want: []string{"a", "b"}},
+		{name: "double quotes", line: `cmd "a b" c`, want: []string{"cmd", "a b", "c"}},
+		{name: "single quotes", line: `cmd 'a "b"'`, want: []string{"cmd", `a "b"`}},
+		{name: "quote inside an argument", line: `--path="{path}"`, want: []string{"--path={path}"}},
+		{name: "empty quoted argument", line: `cmd ""`, want: []string{"cmd", ""}},
+		{name: "unclosed quote", line: `cmd "a b`, wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := splitCommandLine(tc.line)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("splitCommandLine(%q) = %q, want an error", tc.line, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("splitCommandLine(%q): %v", tc.line, err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("splitCommandLine(%q) = %q, want %q", tc.line, got, tc.want)
+			}
+		})
+	}
+}
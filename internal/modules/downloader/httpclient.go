@@ -0,0 +1,141 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"manga-visor/internal/persistence"
+	"net"
+	"net/http"
+	neturl "net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// Overall timeout for metadata requests (pages, APIs)
+	requestTimeout = 60 * time.Second
+	// Time a page download may go without receiving any data. Bandwidth caps and rate
+	// limits don't count, so a slow cap never times out a large page.
+	pageIdleTimeout = 45 * time.Second
+)
+
+// clientFactory builds the HTTP clients of one Module. Its transport, per-host limiter,
+// bandwidth buckets and cookie jar read that Module's settings and cookie store, so two
+// Modules, or a test, never share them. Downloaders get it through setClients.
+type clientFactory struct {
+	settings func() *persistence.Settings // nil: built-in defaults
+	cookies  *persistence.CookiesManager  // nil: no cookies are sent or kept
+
+	// Used by every request of the Module, so connections are kept alive and reused
+	// across jobs and sites
+	transport *http.Transport
+	limiter   *hostLimiter
+	bandwidth *bandwidthLimiter
+}
+
+func newClientFactory(settings func() *persistence.Settings, cookies *persistence.CookiesManager) *clientFactory {
+	f := &clientFactory{
+		settings:  settings,
+		cookies:   cookies,
+		limiter:   &hostLimiter{buckets: make(map[string]*tokenBucket)},
+		bandwidth: &bandwidthLimiter{sites: make(map[string]*byteBucket)},
+	}
+	f.transport = &http.Transport{
+		Proxy: f.proxy,
+		DialContext: (&net.Dialer{
+			Timeout:   15 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   16,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: 30 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+	return f
+}
+
+// standaloneClients serves downloaders used outside a Module: default limits, no cookies
+var standaloneClients = newClientFactory(nil, nil)
+
+// currentSettings returns the live settings, nil when the factory has none
+func (f *clientFactory) currentSettings() *persistence.Settings {
+	if f == nil || f.settings == nil {
+		return nil
+	}
+	return f.settings()
+}
+
+// proxy routes requests through the proxy from settings (http://, https:// or socks5://),
+// falling back to the HTTP_PROXY/HTTPS_PROXY environment variables
+func (f *clientFactory) proxy(req *http.Request) (*neturl.URL, error) {
+	if settings := f.currentSettings(); settings != nil {
+		if proxy := strings.TrimSpace(settings.DownloadProxy); proxy != "" {
+			u, err := neturl.Parse(proxy)
+			if err != nil || u.Host == "" {
+				return nil, fmt.Errorf("invalid proxy %q", proxy)
+			}
+			return u, nil
+		}
+	}
+	return http.ProxyFromEnvironment(req)
+}
+
+// newClient returns a client on the factory's transport whose requests go through the
+// per-host limiter and carry the stored cookies and User-Agent overrides.
+// Every downloader request (metadata and pages) should use one.
+func (f *clientFactory) newClient() *http.Client {
+	if f == nil {
+		f = standaloneClients
+	}
+	return &http.Client{
+		Transport: &limitedTransport{base: &userAgentTransport{base: f.transport, cookies: f.cookies}, clients: f},
+		Jar:       persistentJar{store: f.cookies},
+		Timeout:   requestTimeout,
+	}
+}
+
+// withClients is embedded by the downloaders. It holds the clients of the Module that
+// loaded them; the zero value uses standaloneClients.
+type withClients struct {
+	clients *clientFactory
+}
+
+// setClients is called by the Module before the downloader is used
+func (w *withClients) setClients(f *clientFactory) {
+	w.clients = f
+}
+
+func (w *withClients) newHTTPClient() *http.Client {
+	return w.clients.newClient()
+}
+
+// currentSettings returns the Module's settings, nil outside a Module
+func (w *withClients) currentSettings() *persistence.Settings {
+	return w.clients.currentSettings()
+}
+
+// clientUser is a downloader that makes its requests through the Module's clients
+type clientUser interface {
+	setClients(f *clientFactory)
+}
+
+// idleTimeoutReader cancels a request when a single Read of its body blocks for longer than
+// timeout. Only the time spent waiting for the network counts.
+type idleTimeoutReader struct {
+	r       io.Reader
+	timeout time.Duration
+	cancel  context.CancelFunc
+}
+
+func (r *idleTimeoutReader) Read(p []byte) (int, error) {
+	timer := time.AfterFunc(r.timeout, r.cancel)
+	n, err := r.r.Read(p)
+	if !timer.Stop() {
+		err = fmt.Errorf("no data received for %v", r.timeout)
+	}
+	return n, err
+}
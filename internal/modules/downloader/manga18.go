@@ -11,7 +11,9 @@ import (
 	"golang.org/x/net/html/atom"
 )
 
-type Manga18Downloader struct{}
+type Manga18Downloader struct {
+	withClients
+}
 
 // normalizeSeriesName normaliza el nombre de la serie para consistencia
 // entre descargas de capítulos individuales y series
@@ -68,7 +70,7 @@ func (d *Manga18Downloader) getChapter(url string) (*SiteInfo, error) {
 	chapter := match[2]     // "chap-79"
 
 	// Obtener HTML de la página
-	client := &http.Client{}
+	client := d.newHTTPClient()
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return nil, err
@@ -217,7 +219,7 @@ func (d *Manga18Downloader) getSeries(url string) (*SiteInfo, error) {
 	}
 
 	// Obtener HTML de la página de la serie
-	client := &http.Client{}
+	client := d.newHTTPClient()
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return nil, err
@@ -429,7 +431,7 @@ func (d *Manga18Downloader) extractChapterNumber(id string) int {
 
 // findTotalPagesByProbing prueba números secuencialmente hasta obtener 404
 func (d *Manga18Downloader) findTotalPagesByProbing(series, chapter string) int {
-	client := &http.Client{}
+	client := d.newHTTPClient()
 	baseURL := fmt.Sprintf("https://s1.manga18.club/manga/%s/chapters/%s", series, chapter)
 
 	// Probar hasta 500 páginas (límite razonable)
@@ -1,15 +1,21 @@
 package downloader
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
+	"manga-visor/internal/persistence"
 	"net/http"
+	neturl "net/url"
 	"regexp"
 	"strings"
+	"time"
 )
 
-type MangaDexDownloader struct{}
+type MangaDexDownloader struct {
+	withClients
+}
 
 // API response types
 type mangaDexAtHomeResponse struct {
@@ -22,6 +28,16 @@ type mangaDexAtHomeResponse struct {
 	} `json:"chapter"`
 }
 
+// mangaDexRelationship links a chapter to its manga and groups. Attributes are only
+// filled for the types asked for with includes[].
+type mangaDexRelationship struct {
+	ID         string `json:"id"`
+	Type       string `json:"type"`
+	Attributes struct {
+		Name string `json:"name"`
+	} `json:"attributes"`
+}
+
 type mangaDexChapterResponse struct {
 	Result string `json:"result"`
 	Data   struct {
@@ -32,10 +48,7 @@ type mangaDexChapterResponse struct {
 			Title              string `json:"title"`
 			TranslatedLanguage string `json:"translatedLanguage"`
 		} `json:"attributes"`
-		Relationships []struct {
-			ID   string `json:"id"`
-			Type string `json:"type"`
-		} `json:"relationships"`
+		Relationships []mangaDexRelationship `json:"relationships"`
 	} `json:"data"`
 }
 
@@ -59,10 +72,7 @@ type mangaDexFeedResponse struct {
 			TranslatedLanguage string `json:"translatedLanguage"`
 			PublishAt          string `json:"publishAt"`
 		} `json:"attributes"`
-		Relationships []struct {
-			ID   string `json:"id"`
-			Type string `json:"type"`
-		} `json:"relationships"`
+		Relationships []mangaDexRelationship `json:"relationships"`
 	} `json:"data"`
 	Total  int `json:"total"`
 	Limit  int `json:"limit"`
@@ -89,7 +99,7 @@ func (d *MangaDexDownloader) GetImages(url string) (*SiteInfo, error) {
 	if len(matchTitle) >= 2 {
 		// It's a series
 		mangaID := matchTitle[1]
-		client := &http.Client{}
+		client := d.newHTTPClient()
 
 		mangaInfo, err := d.getMangaInfo(client, mangaID)
 		if err != nil {
@@ -104,7 +114,7 @@ func (d *MangaDexDownloader) GetImages(url string) (*SiteInfo, error) {
 		return &SiteInfo{
 			SeriesName: d.extractTitle(mangaInfo.Data.Attributes.Title),
 			Type:       "series",
-			Chapters:   chapters, // All chapters, multi-language
+			Chapters:   chapters, // Every chapter in the configured languages
 			SiteID:     d.GetSiteID(),
 		}, nil
 	}
@@ -114,7 +124,7 @@ func (d *MangaDexDownloader) GetImages(url string) (*SiteInfo, error) {
 	}
 	chapterID := match[1]
 
-	client := &http.Client{}
+	client := d.newHTTPClient()
 
 	// 1. Get chapter metadata
 	chapterInfo, err := d.getChapterInfo(client, chapterID)
@@ -145,11 +155,16 @@ func (d *MangaDexDownloader) GetImages(url string) (*SiteInfo, error) {
 		return nil, fmt.Errorf("failed to get image server: %v", err)
 	}
 
-	// Build image list using high quality (data) images
-	images := make([]ImageDownload, len(atHome.Chapter.Data))
-	for i, filename := range atHome.Chapter.Data {
-		// URL format: {baseUrl}/data/{hash}/{filename}
-		imageURL := fmt.Sprintf("%s/data/%s/%s", atHome.BaseURL, atHome.Chapter.Hash, filename)
+	// Build image list, high quality (data) unless data-saver is enabled
+	_, dataSaver := mangaDexSettings(d.currentSettings())
+	quality, files := "data", atHome.Chapter.Data
+	if dataSaver && len(atHome.Chapter.DataSaver) > 0 {
+		quality, files = "data-saver", atHome.Chapter.DataSaver
+	}
+	images := make([]ImageDownload, len(files))
+	for i, filename := range files {
+		// URL format: {baseUrl}/{quality}/{hash}/{filename}
+		imageURL := fmt.Sprintf("%s/%s/%s/%s", atHome.BaseURL, quality, atHome.Chapter.Hash, filename)
 
 		// Extract extension from filename
 		ext := "jpg"
@@ -179,15 +194,19 @@ func (d *MangaDexDownloader) GetImages(url string) (*SiteInfo, error) {
 	}
 
 	return &SiteInfo{
-		SeriesName:  mangaTitle,
-		ChapterName: chapterName,
-		Images:      images,
-		SiteID:      d.GetSiteID(),
+		SeriesName:    mangaTitle,
+		ChapterName:   chapterName,
+		Images:        images,
+		SiteID:        d.GetSiteID(),
+		ChapterNumber: chapterInfo.Data.Attributes.Chapter,
+		Volume:        chapterInfo.Data.Attributes.Volume,
+		Language:      chapterInfo.Data.Attributes.TranslatedLanguage,
+		ScanGroup:     mangaDexGroups(chapterInfo.Data.Relationships),
 	}, nil
 }
 
 func (d *MangaDexDownloader) getChapterInfo(client *http.Client, chapterID string) (*mangaDexChapterResponse, error) {
-	url := fmt.Sprintf("https://api.mangadex.org/chapter/%s", chapterID)
+	url := fmt.Sprintf("https://api.mangadex.org/chapter/%s?includes[]=scanlation_group", chapterID)
 	req, _ := http.NewRequest("GET", url, nil)
 	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) Chrome/120.0.0.0")
 
@@ -265,3 +284,60 @@ func (d *MangaDexDownloader) extractTitle(titles map[string]string) string {
 	}
 	return "Unknown Manga"
 }
+
+// mangaDexSettings returns the chapter languages to fetch and whether to use data-saver pages
+func mangaDexSettings(settings *persistence.Settings) ([]string, bool) {
+	if settings == nil {
+		return nil, false
+	}
+	return settings.MangaDexLanguages, settings.MangaDexDataSaver
+}
+
+// mangaDexGroups joins the names of the scanlation groups of a chapter
+func mangaDexGroups(relationships []mangaDexRelationship) string {
+	var groups []string
+	for _, rel := range relationships {
+		if rel.Type == "scanlation_group" && rel.Attributes.Name != "" {
+			groups = append(groups, rel.Attributes.Name)
+		}
+	}
+	return strings.Join(groups, " & ")
+}
+
+// mangaDexReportURL receives the result of every page loaded from a MangaDex@Home node
+const mangaDexReportURL = "https://api.mangadex.network/report"
+
+// reportAtHome tells MangaDex how a page load went, as its API rules ask. Only pages
+// served by MangaDex@Home nodes are reported, not those from MangaDex's own servers.
+func (f *clientFactory) reportAtHome(siteID string, pageURL string, success bool, cached bool, size int64, duration time.Duration) {
+	if siteID != "mangadex.org" {
+		return
+	}
+	u, err := neturl.Parse(pageURL)
+	if err != nil || u.Hostname() == "mangadex.org" || strings.HasSuffix(u.Hostname(), ".mangadex.org") {
+		return
+	}
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"url":      pageURL,
+		"success":  success,
+		"cached":   cached,
+		"bytes":    size,
+		"duration": duration.Milliseconds(),
+	})
+	go func() {
+		client := f.newClient()
+		client.Timeout = 10 * time.Second
+		req, err := http.NewRequest("POST", mangaDexReportURL, bytes.NewReader(body))
+		if err != nil {
+			return
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := client.Do(req)
+		if err != nil {
+			fmt.Printf("[Downloader] MangaDex@Home report failed: %v\n", err)
+			return
+		}
+		resp.Body.Close()
+	}()
+}
@@ -0,0 +1,451 @@
+package downloader
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"manga-visor/internal/persistence"
+	"net/http"
+	neturl "net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const mangaDexTokenURL = "https://auth.mangadex.org/realms/mangadex/protocol/openid-connect/token"
+
+// How long before its expiry the access token is refreshed
+const mangaDexTokenMargin = 30 * time.Second
+
+var mangaDexTitleRe = regexp.MustCompile(`/title/([0-9a-f-]{36})`)
+
+// MangaDexAccount is the login state shown in settings
+type MangaDexAccount struct {
+	LoggedIn bool   `json:"loggedIn"`
+	Username string `json:"username"`
+	ClientID string `json:"clientId"`
+	// Last read of the follows feed (RFC3339)
+	FeedCheckedAt string `json:"feedCheckedAt"`
+}
+
+// MangaDexList is a custom list of the logged in user
+type MangaDexList struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Visibility string `json:"visibility"` // "public" or "private"
+	Titles     int    `json:"titles"`
+}
+
+type mangaDexTokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	RefreshToken     string `json:"refresh_token"`
+	ExpiresIn        int    `json:"expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+type mangaDexErrorResponse struct {
+	Errors []struct {
+		Title  string `json:"title"`
+		Detail string `json:"detail"`
+	} `json:"errors"`
+}
+
+type mangaDexFollowsResponse struct {
+	Data []struct {
+		ID string `json:"id"`
+	} `json:"data"`
+	Total int `json:"total"`
+}
+
+type mangaDexListData struct {
+	ID         string `json:"id"`
+	Attributes struct {
+		Name       string `json:"name"`
+		Visibility string `json:"visibility"`
+	} `json:"attributes"`
+	Relationships []mangaDexRelationship `json:"relationships"`
+}
+
+type mangaDexListsResponse struct {
+	Data  []mangaDexListData `json:"data"`
+	Total int                `json:"total"`
+}
+
+type mangaDexListResponse struct {
+	Data mangaDexListData `json:"data"`
+}
+
+// GetMangaDexAccount returns who is logged in to MangaDex
+func (m *Module) GetMangaDexAccount() MangaDexAccount {
+	if m.mda == nil {
+		return MangaDexAccount{}
+	}
+	auth := m.mda.Get()
+	return MangaDexAccount{
+		LoggedIn:      auth.RefreshToken != "",
+		Username:      auth.Username,
+		ClientID:      auth.ClientID,
+		FeedCheckedAt: auth.FeedCheckedAt,
+	}
+}
+
+// MangaDexLogin signs in with a MangaDex personal API client. Only the tokens are
+// kept, the password is sent once and forgotten.
+func (m *Module) MangaDexLogin(clientID string, clientSecret string, username string, password string) (MangaDexAccount, error) {
+	if m.mda == nil {
+		return MangaDexAccount{}, fmt.Errorf("MangaDex login is not available")
+	}
+	clientID, clientSecret, username = strings.TrimSpace(clientID), strings.TrimSpace(clientSecret), strings.TrimSpace(username)
+	if clientID == "" || clientSecret == "" || username == "" || password == "" {
+		return MangaDexAccount{}, fmt.Errorf("client ID, client secret, username and password are required")
+	}
+
+	token, err := m.requestMangaDexToken(neturl.Values{
+		"grant_type":    {"password"},
+		"username":      {username},
+		"password":      {password},
+		"client_id":     {clientID},
+		"client_secret": {clientSecret},
+	})
+	if err != nil {
+		return MangaDexAccount{}, err
+	}
+
+	previous := m.mda.Get()
+	auth := persistence.MangaDexAuth{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Username:     username,
+		AccessToken:  token.AccessToken,
+		RefreshToken: token.RefreshToken,
+		ExpiresAt:    time.Now().Add(time.Duration(token.ExpiresIn) * time.Second).Format(time.RFC3339),
+	}
+	if previous.Username == username {
+		auth.FeedCheckedAt = previous.FeedCheckedAt
+	}
+	if err := m.mda.Set(auth); err != nil {
+		return MangaDexAccount{}, fmt.Errorf("failed to store the MangaDex session: %v", err)
+	}
+	fmt.Printf("[Downloader] Logged in to MangaDex as %s\n", username)
+	return m.GetMangaDexAccount(), nil
+}
+
+// MangaDexLogout forgets the MangaDex session
+func (m *Module) MangaDexLogout() error {
+	if m.mda == nil {
+		return nil
+	}
+	m.mdaLock.Lock()
+	defer m.mdaLock.Unlock()
+	return m.mda.Clear()
+}
+
+// mangaDexLoggedIn reports whether there is a MangaDex session to use
+func (m *Module) mangaDexLoggedIn() bool {
+	return m.mda != nil && m.mda.Get().RefreshToken != ""
+}
+
+// requestMangaDexToken calls the MangaDex token endpoint with a password or refresh grant
+func (m *Module) requestMangaDexToken(form neturl.Values) (*mangaDexTokenResponse, error) {
+	req, err := http.NewRequest("POST", mangaDexTokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) Chrome/120.0.0.0")
+
+	resp, err := m.clients.newClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	var token mangaDexTokenResponse
+	if err := json.Unmarshal(body, &token); err != nil {
+		return nil, fmt.Errorf("MangaDex login failed: %s", resp.Status)
+	}
+	if token.Error != "" {
+		if token.ErrorDescription != "" {
+			return &token, fmt.Errorf("MangaDex login failed: %s", token.ErrorDescription)
+		}
+		return &token, fmt.Errorf("MangaDex login failed: %s", token.Error)
+	}
+	if resp.StatusCode != http.StatusOK || token.AccessToken == "" {
+		return nil, fmt.Errorf("MangaDex login failed: %s", resp.Status)
+	}
+	return &token, nil
+}
+
+// mangaDexAccessToken returns a valid access token, refreshing it when it's about to expire.
+// A refresh token MangaDex no longer accepts ends the session.
+func (m *Module) mangaDexAccessToken() (string, error) {
+	m.mdaLock.Lock()
+	defer m.mdaLock.Unlock()
+
+	if m.mda == nil {
+		return "", fmt.Errorf("not logged in to MangaDex")
+	}
+	auth := m.mda.Get()
+	if auth.RefreshToken == "" {
+		return "", fmt.Errorf("not logged in to MangaDex")
+	}
+	if expires, err := time.Parse(time.RFC3339, auth.ExpiresAt); err == nil && time.Until(expires) > mangaDexTokenMargin {
+		return auth.AccessToken, nil
+	}
+
+	token, err := m.requestMangaDexToken(neturl.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {auth.RefreshToken},
+		"client_id":     {auth.ClientID},
+		"client_secret": {auth.ClientSecret},
+	})
+	if err != nil {
+		if token != nil && token.Error == "invalid_grant" {
+			m.mda.Clear()
+			return "", fmt.Errorf("MangaDex session expired, log in again")
+		}
+		return "", err
+	}
+
+	auth.AccessToken = token.AccessToken
+	if token.RefreshToken != "" {
+		auth.RefreshToken = token.RefreshToken
+	}
+	auth.ExpiresAt = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second).Format(time.RFC3339)
+	if err := m.mda.Set(auth); err != nil {
+		fmt.Printf("[Downloader] Failed to store the MangaDex session: %v\n", err)
+	}
+	return auth.AccessToken, nil
+}
+
+// mangaDexUserGet fetches an API endpoint as the logged in user and decodes the response
+func (m *Module) mangaDexUserGet(client *http.Client, url string, target interface{}) error {
+	token, err := m.mangaDexAccessToken()
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) Chrome/120.0.0.0")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		var apiErr mangaDexErrorResponse
+		if json.Unmarshal(body, &apiErr) == nil && len(apiErr.Errors) > 0 {
+			return fmt.Errorf("MangaDex: %s", apiErr.Errors[0].Detail)
+		}
+		return fmt.Errorf("MangaDex: %s", resp.Status)
+	}
+	return json.Unmarshal(body, target)
+}
+
+// mangaDexFollowedTitles returns the IDs of the titles the user follows
+func (m *Module) mangaDexFollowedTitles(client *http.Client) ([]string, error) {
+	var ids []string
+	limit := 100
+	for offset := 0; ; offset += limit {
+		var result mangaDexFollowsResponse
+		url := fmt.Sprintf("https://api.mangadex.org/user/follows/manga?limit=%d&offset=%d", limit, offset)
+		if err := m.mangaDexUserGet(client, url, &result); err != nil {
+			return nil, err
+		}
+		for _, manga := range result.Data {
+			ids = append(ids, manga.ID)
+		}
+		if len(result.Data) == 0 || offset+limit >= result.Total {
+			return ids, nil
+		}
+	}
+}
+
+// mangaDexListTitles returns the name and title IDs of a custom list
+func (m *Module) mangaDexListTitles(client *http.Client, listID string) (string, []string, error) {
+	var result mangaDexListResponse
+	if err := m.mangaDexUserGet(client, fmt.Sprintf("https://api.mangadex.org/list/%s", listID), &result); err != nil {
+		return "", nil, err
+	}
+	var ids []string
+	for _, rel := range result.Data.Relationships {
+		if rel.Type == "manga" {
+			ids = append(ids, rel.ID)
+		}
+	}
+	return result.Data.Attributes.Name, ids, nil
+}
+
+// GetMangaDexLists returns the custom lists of the logged in user, private ones included
+func (m *Module) GetMangaDexLists() ([]MangaDexList, error) {
+	client := m.clients.newClient()
+	lists := []MangaDexList{}
+	limit := 100
+	for offset := 0; ; offset += limit {
+		var result mangaDexListsResponse
+		url := fmt.Sprintf("https://api.mangadex.org/user/list?limit=%d&offset=%d", limit, offset)
+		if err := m.mangaDexUserGet(client, url, &result); err != nil {
+			return nil, err
+		}
+		for _, data := range result.Data {
+			list := MangaDexList{ID: data.ID, Name: data.Attributes.Name, Visibility: data.Attributes.Visibility}
+			for _, rel := range data.Relationships {
+				if rel.Type == "manga" {
+					list.Titles++
+				}
+			}
+			lists = append(lists, list)
+		}
+		if len(result.Data) == 0 || offset+limit >= result.Total {
+			return lists, nil
+		}
+	}
+}
+
+// mangaDexTitleURL is the series URL subscriptions and series downloads use for a title
+func mangaDexTitleURL(mangaID string) string {
+	return fmt.Sprintf("https://mangadex.org/title/%s", mangaID)
+}
+
+// ImportMangaDexTitles subscribes to the followed titles, or to those of a custom list
+// when listID is set. Titles already subscribed are left alone. Returns how many were added.
+func (m *Module) ImportMangaDexTitles(listID string, autoDownload bool) (int, error) {
+	client := m.clients.newClient()
+	var ids []string
+	var err error
+	if listID == "" {
+		ids, err = m.mangaDexFollowedTitles(client)
+	} else {
+		_, ids, err = m.mangaDexListTitles(client, listID)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	subscribed := make(map[string]bool)
+	for _, sub := range m.subs.GetSubscriptions() {
+		if match := mangaDexTitleRe.FindStringSubmatch(sub.URL); match != nil {
+			subscribed[match[1]] = true
+		}
+	}
+
+	added := 0
+	for _, id := range ids {
+		if subscribed[id] {
+			continue
+		}
+		if _, err := m.AddSubscription(mangaDexTitleURL(id), nil, nil, autoDownload); err != nil {
+			fmt.Printf("[Downloader] Failed to subscribe to MangaDex title %s: %v\n", id, err)
+			continue
+		}
+		added++
+	}
+	fmt.Printf("[Downloader] Imported %d of %d MangaDex titles\n", added, len(ids))
+	return added, nil
+}
+
+// DownloadMangaDexList queues the chapters of every title in a custom list that match
+// the filter, one series batch per title
+func (m *Module) DownloadMangaDexList(listID string, filter SeriesFilter) ([]*SeriesBatch, error) {
+	name, ids, err := m.mangaDexListTitles(m.clients.newClient(), listID)
+	if err != nil {
+		return nil, err
+	}
+
+	batches := []*SeriesBatch{}
+	for _, id := range ids {
+		batch, err := m.StartSeriesDownload(mangaDexTitleURL(id), filter)
+		if err != nil {
+			fmt.Printf("[Downloader] Skipped MangaDex title %s of list %s: %v\n", id, name, err)
+			continue
+		}
+		batches = append(batches, batch)
+	}
+	return batches, nil
+}
+
+// CheckMangaDexFeed reads the follows feed since the last read and checks the
+// subscriptions it has new chapters for. The other MangaDex subscriptions are marked
+// as checked, the feed already covered them. Returns the number of new chapters.
+func (m *Module) CheckMangaDexFeed() (int, error) {
+	if !m.mangaDexLoggedIn() {
+		return 0, fmt.Errorf("not logged in to MangaDex")
+	}
+	since := time.Now().Add(-24 * time.Hour)
+	if last, err := time.Parse(time.RFC3339, m.mda.Get().FeedCheckedAt); err == nil {
+		// Overlap a little, chapters already known are ignored anyway
+		since = last.Add(-time.Hour)
+	}
+	started := time.Now()
+
+	filter := "&includes[]=scanlation_group"
+	languages, _ := mangaDexSettings(m.sm.Get())
+	for _, lang := range languages {
+		filter += "&translatedLanguage[]=" + neturl.QueryEscape(lang)
+	}
+
+	client := m.clients.newClient()
+	updated := make(map[string][]string) // manga ID -> chapter IDs
+	limit := 100
+	for offset := 0; ; offset += limit {
+		var result mangaDexFeedResponse
+		url := fmt.Sprintf("https://api.mangadex.org/user/follows/manga/feed?limit=%d&offset=%d&order[readableAt]=desc&readableAtSince=%s%s",
+			limit, offset, since.UTC().Format("2006-01-02T15:04:05"), filter)
+		if err := m.mangaDexUserGet(client, url, &result); err != nil {
+			return 0, err
+		}
+		for _, ch := range result.Data {
+			for _, rel := range ch.Relationships {
+				if rel.Type == "manga" {
+					updated[rel.ID] = append(updated[rel.ID], ch.ID)
+				}
+			}
+		}
+		if len(result.Data) == 0 || offset+limit >= result.Total {
+			break
+		}
+	}
+
+	total := 0
+	now := time.Now().Format(time.RFC3339)
+	for _, sub := range m.subs.GetSubscriptions() {
+		match := mangaDexTitleRe.FindStringSubmatch(sub.URL)
+		if sub.Site != "mangadex.org" || match == nil {
+			continue
+		}
+		if hasUnknownChapter(sub, updated[match[1]]) {
+			if fresh, err := m.CheckSubscription(sub.ID); err == nil {
+				total += len(fresh)
+			}
+			continue
+		}
+		m.subs.UpdateSubscription(sub.ID, map[string]interface{}{"lastCheckedAt": now})
+	}
+
+	m.mda.SetFeedCheckedAt(started.Format(time.RFC3339))
+	m.notifySubscriptions()
+	fmt.Printf("[Downloader] MangaDex feed: %d titles updated, %d new chapters\n", len(updated), total)
+	return total, nil
+}
+
+// hasUnknownChapter reports whether any of the chapter IDs is not known to the subscription yet
+func hasUnknownChapter(sub persistence.Subscription, chapterIDs []string) bool {
+	known := make(map[string]bool, len(sub.KnownChapters))
+	for _, k := range sub.KnownChapters {
+		known[k] = true
+	}
+	for _, id := range chapterIDs {
+		if !known[id] {
+			return true
+		}
+	}
+	return false
+}
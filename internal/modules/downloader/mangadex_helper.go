@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	neturl "net/url"
 )
 
 func (d *MangaDexDownloader) getMangaChapters(client *http.Client, mangaID string) ([]ChapterInfo, error) {
@@ -12,8 +13,15 @@ func (d *MangaDexDownloader) getMangaChapters(client *http.Client, mangaID strin
 	offset := 0
 	limit := 500
 
+	// Group names come with the feed, only the languages asked for are returned
+	filter := "&includes[]=scanlation_group"
+	languages, _ := mangaDexSettings(d.currentSettings())
+	for _, lang := range languages {
+		filter += "&translatedLanguage[]=" + neturl.QueryEscape(lang)
+	}
+
 	for {
-		url := fmt.Sprintf("https://api.mangadex.org/manga/%s/feed?limit=%d&offset=%d&order[chapter]=asc", mangaID, limit, offset)
+		url := fmt.Sprintf("https://api.mangadex.org/manga/%s/feed?limit=%d&offset=%d&order[chapter]=asc%s", mangaID, limit, offset, filter)
 		req, _ := http.NewRequest("GET", url, nil)
 		req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) Chrome/120.0.0.0")
 
@@ -21,9 +29,8 @@ func (d *MangaDexDownloader) getMangaChapters(client *http.Client, mangaID strin
 		if err != nil {
 			return nil, err
 		}
-		defer resp.Body.Close()
-
 		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
 
 		var result mangaDexFeedResponse
 		if err := json.Unmarshal(body, &result); err != nil {
@@ -38,21 +45,15 @@ func (d *MangaDexDownloader) getMangaChapters(client *http.Client, mangaID strin
 				title = fmt.Sprintf("Chapter %s - %s", ch.Attributes.Chapter, title)
 			}
 
-			// Find scan group
-			scanGroup := ""
-			for _, rel := range ch.Relationships {
-				if rel.Type == "scanlation_group" {
-					scanGroup = "Unknown Group" // Placeholder, fetching group name would require extra calls
-				}
-			}
-
 			chapters = append(chapters, ChapterInfo{
 				ID:        ch.ID,
 				Name:      fmt.Sprintf("%s [%s]", title, ch.Attributes.TranslatedLanguage),
 				URL:       fmt.Sprintf("https://mangadex.org/chapter/%s", ch.ID),
 				Date:      ch.Attributes.PublishAt,
-				ScanGroup: scanGroup,
+				ScanGroup: mangaDexGroups(ch.Relationships),
 				Language:  ch.Attributes.TranslatedLanguage,
+				Number:    ch.Attributes.Chapter,
+				Volume:    ch.Attributes.Volume,
 			})
 		}
 
@@ -8,7 +8,9 @@ import (
 	"strings"
 )
 
-type ManhwaWebDownloader struct{}
+type ManhwaWebDownloader struct {
+	withClients
+}
 
 type ManhwaChapter struct {
 	Name    string `json:"name"`
@@ -54,7 +56,7 @@ func (d *ManhwaWebDownloader) getSeries(url string) (*SiteInfo, error) {
 	req.Header.Set("Referer", "https://manhwaweb.com/")
 	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) Chrome/120.0.0.0")
 
-	client := &http.Client{}
+	client := d.newHTTPClient()
 	resp, err := client.Do(req)
 	if err != nil {
 		return nil, err
@@ -79,9 +81,10 @@ func (d *ManhwaWebDownloader) getSeries(url string) (*SiteInfo, error) {
 	var chapters []ChapterInfo
 	for _, ch := range data.Chapters {
 		chapters = append(chapters, ChapterInfo{
-			ID:   fmt.Sprintf("%v", ch.Chapter),
-			Name: fmt.Sprintf("%s %v", seriesName, ch.Chapter),
-			URL:  ch.Link,
+			ID:     fmt.Sprintf("%v", ch.Chapter),
+			Name:   fmt.Sprintf("%s %v", seriesName, ch.Chapter),
+			URL:    ch.Link,
+			Number: fmt.Sprintf("%v", ch.Chapter),
 		})
 	}
 
@@ -111,7 +114,7 @@ func (d *ManhwaWebDownloader) getChapter(url string) (*SiteInfo, error) {
 	req.Header.Set("Referer", "https://manhwaweb.com/")
 	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) Chrome/120.0.0.0")
 
-	client := &http.Client{}
+	client := d.newHTTPClient()
 	resp, err := client.Do(req)
 	if err != nil {
 		return nil, err
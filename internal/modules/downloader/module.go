@@ -2,6 +2,7 @@ package downloader
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"manga-visor/internal/persistence"
@@ -9,8 +10,10 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 	"unicode/utf8"
 
@@ -21,18 +24,65 @@ type Module struct {
 	ctx        context.Context
 	pm         *persistence.DownloaderManager
 	sm         *persistence.SettingsManager
+	subs       *persistence.SubscriptionsManager
+	ci         *persistence.ContentIndexManager
 	algorithms []DownloaderInterface
-	activeJobs sync.Map // map[string]*activeJob
+	builtins   []DownloaderInterface // hand-written downloaders, built once by NewModule
+	scrapers   []DownloaderInterface // declarative definitions from the scrapers folder
+	plugins    []DownloaderInterface // script plugins from the plugins folder
+	algoLock   sync.RWMutex          // guards algorithms, scrapers and plugins
+	activeJobs sync.Map              // map[string]*activeJob
+
+	// HTTP clients of the module and its downloaders, with the proxy, limits and cookies from cm
+	clients *clientFactory
+	cm      *persistence.CookiesManager
+	// MangaDex login, mdaLock keeps concurrent requests from refreshing the token twice
+	mda     *persistence.MangaDexAuthManager
+	mdaLock sync.Mutex
 
 	// Queue management
-	queueLock      sync.Mutex
-	queues         map[string][]*queuedJob // map[siteID]queue
-	activeCounts   map[string]int          // map[siteID]count
-	maxConcurrency map[string]int          // map[siteID]limit (0 = unlimited)
+	queueLock    sync.Mutex
+	queues       map[string][]*queuedJob // map[siteID]queue
+	activeCounts map[string]int          // map[siteID]count
+	paused       map[string]*queuedJob   // map[jobID]paused job with its resolved images
+
+	// Serializes subscription checks
+	subLock sync.Mutex
+
+	// Plugin matches of the texts seen by the clipboard monitor
+	clipboardPlugins pluginURLCache
+
+	// Batches whose batch_completed hooks already ran
+	finishedBatches sync.Map
+	// Hook runs waiting for a hook worker
+	hookQueue chan hookRun
+
+	// Download folder size, cached by downloadUsage
+	usage downloadUsage
+	// Space reserved by the jobs that passed the preflight, map[jobID]*spaceReservation
+	pendingBytes sync.Map
+
+	// Library folders indexed for duplicates besides the download folder, set by the app
+	indexRoots func() []string
+	// Serializes content index rebuilds
+	indexLock sync.Mutex
+
+	// Registers completed chapters in the Series page and follows renamed ones, set by the app
+	seriesModule interface {
+		AddChapter(seriesPath string, chapterPath string) error
+		MoveChapter(oldPath string, newPath string) error
+	}
 }
 
 type activeJob struct {
 	cancel context.CancelFunc
+	// Pause flags below are guarded by queueLock
+	// Set by PauseJob before cancelling, runDownload then parks the job instead of cancelling it
+	pausing bool
+	// Set by ResumeJob while a pausing job is still winding down
+	resumeAfterPause bool
+	// Why the job pauses when it wasn't the user, shown as its error
+	pauseReason string
 }
 
 type queuedJob struct {
@@ -40,29 +90,131 @@ type queuedJob struct {
 	info *SiteInfo
 }
 
-func NewModule(pm *persistence.DownloaderManager, sm *persistence.SettingsManager) *Module {
-	return &Module{
+func NewModule(pm *persistence.DownloaderManager, sm *persistence.SettingsManager, cm *persistence.CookiesManager, subs *persistence.SubscriptionsManager, ci *persistence.ContentIndexManager, mda *persistence.MangaDexAuthManager) *Module {
+	m := &Module{
 		pm:           pm,
 		sm:           sm,
+		subs:         subs,
+		ci:           ci,
+		clients:      newClientFactory(sm.Get, cm),
+		cm:           cm,
+		mda:          mda,
 		queues:       make(map[string][]*queuedJob),
 		activeCounts: make(map[string]int),
-		maxConcurrency: map[string]int{
-			"hitomi.la": 2, // modificado a 2 para probar
-		},
-		algorithms: []DownloaderInterface{
-			&HitomiDownloader{},
-			&ManhwaWebDownloader{},
-			&ZonaTMODownloader{},
-			&MangaDexDownloader{},
-			&NHentaiDownloader{},
-			&Manga18Downloader{},
-		},
+		paused:       make(map[string]*queuedJob),
+		hookQueue:    make(chan hookRun, hookQueueSize),
+	}
+	m.startHookWorkers()
+	m.builtins = builtinAlgorithms()
+	for _, d := range m.builtins {
+		if c, ok := d.(clientUser); ok {
+			c.setClients(m.clients)
+		}
+	}
+	if err := m.ReloadScrapers(); err != nil {
+		fmt.Printf("[Downloader] Some scraper definitions failed to load: %v\n", err)
+	}
+	if err := m.ReloadPlugins(); err != nil {
+		fmt.Printf("[Downloader] Some plugins failed to load: %v\n", err)
+	}
+	return m
+}
+
+// builtinAlgorithms returns the hand-written site downloaders
+func builtinAlgorithms() []DownloaderInterface {
+	return []DownloaderInterface{
+		&HitomiDownloader{},
+		&ManhwaWebDownloader{},
+		&ZonaTMODownloader{},
+		&MangaDexDownloader{},
+		&NHentaiDownloader{},
+		&Manga18Downloader{},
+	}
+}
+
+// ReloadScrapers (re)loads the declarative site definitions from the scrapers folder.
+// They are registered before the built-in downloaders, so a definition can take over a broken site.
+func (m *Module) ReloadScrapers() error {
+	custom, errs := LoadScraperDefinitions(persistence.GetScrapersDir())
+
+	scrapers := make([]DownloaderInterface, 0, len(custom))
+	for _, d := range custom {
+		fmt.Printf("[Downloader] Loaded scraper definition %s from %s\n", d.GetSiteID(), d.Source())
+		d.setClients(m.clients)
+		scrapers = append(scrapers, d)
+	}
+
+	m.algoLock.Lock()
+	m.scrapers = scrapers
+	m.rebuildAlgorithms()
+	m.algoLock.Unlock()
+
+	return errors.Join(errs...)
+}
+
+// ReloadPlugins (re)loads the JavaScript plugins from the plugins folder.
+// Plugins come first, ahead of scraper definitions and built-in downloaders.
+func (m *Module) ReloadPlugins() error {
+	loaded, errs := LoadPlugins(persistence.GetPluginsDir())
+
+	plugins := make([]DownloaderInterface, 0, len(loaded))
+	for _, d := range loaded {
+		fmt.Printf("[Downloader] Loaded plugin %s from %s\n", d.GetSiteID(), d.Source())
+		d.setClients(m.clients)
+		plugins = append(plugins, d)
+	}
+
+	m.algoLock.Lock()
+	m.plugins = plugins
+	m.rebuildAlgorithms()
+	m.algoLock.Unlock()
+	m.clipboardPlugins.reset()
+
+	return errors.Join(errs...)
+}
+
+// rebuildAlgorithms sets the lookup order: plugins, scraper definitions, built-ins.
+// Caller must hold algoLock.
+func (m *Module) rebuildAlgorithms() {
+	algorithms := make([]DownloaderInterface, 0, len(m.plugins)+len(m.scrapers)+len(m.builtins))
+	algorithms = append(algorithms, m.plugins...)
+	algorithms = append(algorithms, m.scrapers...)
+	algorithms = append(algorithms, m.builtins...)
+	m.algorithms = algorithms
+}
+
+// GetScrapersPath returns the folder scanned for declarative site definitions
+func (m *Module) GetScrapersPath() string {
+	return persistence.GetScrapersDir()
+}
+
+// GetPluginsPath returns the folder scanned for JavaScript plugins
+func (m *Module) GetPluginsPath() string {
+	return persistence.GetPluginsDir()
+}
+
+// findAlgorithm returns the first downloader able to handle url, or nil
+func (m *Module) findAlgorithm(url string) DownloaderInterface {
+	// CanHandle of a plugin may wait for its runtime, a reload mustn't wait on that
+	m.algoLock.RLock()
+	algorithms := append([]DownloaderInterface(nil), m.algorithms...)
+	m.algoLock.RUnlock()
+
+	for _, a := range algorithms {
+		if a.CanHandle(url) {
+			return a
+		}
 	}
+	return nil
 }
 
 func (m *Module) SetContext(ctx context.Context) {
 	m.ctx = ctx
 	m.StartClipboardMonitor()
+	m.StartPluginWatcher()
+	m.StartSubscriptionPoller()
+	m.StartScheduler()
+	go m.RebuildContentIndex()
 }
 
 func (m *Module) GetHistory() []persistence.DownloadJob {
@@ -79,12 +231,7 @@ func (m *Module) ClearDownloadsData() error {
 	m.notifyUpdate()
 
 	// 2. Delete actual files
-	settings := m.sm.Get()
-	basePath := settings.DownloadPath
-	if basePath == "" {
-		homeDir, _ := os.UserHomeDir()
-		basePath = filepath.Join(homeDir, ".manga-visor", "downloads")
-	}
+	basePath := m.downloadBasePath()
 
 	// Safety check: ensure basePath is not empty or root
 	if basePath == "" || basePath == "/" || basePath == "\\" {
@@ -123,13 +270,14 @@ func (m *Module) RemoveJob(id string) {
 		}
 		m.queues[siteID] = newQueue
 	}
+	delete(m.paused, id)
 	m.queueLock.Unlock()
 
 	// Update job status to cancelled if it exists in persistence
 	existingJobs := m.pm.GetJobs()
 	for _, job := range existingJobs {
 		if job.ID == id {
-			if job.Status == persistence.StatusRunning || job.Status == persistence.StatusPending {
+			if job.Status == persistence.StatusRunning || job.Status == persistence.StatusPending || job.Status == persistence.StatusScheduled || job.Status == persistence.StatusPaused {
 				m.pm.UpdateJob(id, map[string]interface{}{"status": persistence.StatusCancelled})
 			}
 			break
@@ -142,30 +290,36 @@ func (m *Module) RemoveJob(id string) {
 }
 
 func (m *Module) FetchMangaInfo(url string) (*SiteInfo, error) {
-	var algo DownloaderInterface
-	for _, a := range m.algorithms {
-		if a.CanHandle(url) {
-			algo = a
-			break
-		}
-	}
-
+	algo := m.findAlgorithm(url)
 	if algo == nil {
 		return nil, fmt.Errorf("no algorithm found for this URL")
 	}
 
-	return algo.GetImages(url)
-}
+	info, err := algo.GetImages(url)
+	if err != nil {
+		return nil, err
+	}
 
-func (m *Module) StartDownload(url string, overrideSeries string, overrideChapter string) (string, error) {
-	var algo DownloaderInterface
-	for _, a := range m.algorithms {
-		if a.CanHandle(url) {
-			algo = a
-			break
+	// Tell the user what's already on disk before they pick chapters or start. Names only,
+	// the page hashes are compared by the job once it runs.
+	if m.duplicateAction() != DuplicateOff {
+		if info.Type == "series" {
+			m.markLocalChapters(info)
+		} else {
+			info.Duplicates = findDuplicates(m.ci.GetEntries(), info, nil)
 		}
 	}
+	return info, nil
+}
 
+func (m *Module) StartDownload(url string, overrideSeries string, overrideChapter string) (string, error) {
+	return m.startDownload(url, overrideSeries, overrideChapter, "")
+}
+
+// startDownload creates or resumes the job of a chapter URL. batchID groups the jobs
+// started together by StartSeriesDownload, empty for single downloads.
+func (m *Module) startDownload(url string, overrideSeries string, overrideChapter string, batchID string) (string, error) {
+	algo := m.findAlgorithm(url)
 	if algo == nil {
 		return "", fmt.Errorf("no algorithm found for this URL")
 	}
@@ -182,6 +336,16 @@ func (m *Module) StartDownload(url string, overrideSeries string, overrideChapte
 	}
 
 	if existingJob != nil {
+		if existingJob.Status == persistence.StatusPaused {
+			// Paused in this session, the resolved images are still in memory
+			m.queueLock.Lock()
+			_, parked := m.paused[existingJob.ID]
+			m.queueLock.Unlock()
+			if parked {
+				return existingJob.ID, m.ResumeJob(existingJob.ID)
+			}
+		}
+
 		if existingJob.Status == persistence.StatusCompleted {
 			fmt.Printf("[Downloader] URL already completed: %s\n", url)
 			// Notify frontend that this download already exists
@@ -189,24 +353,8 @@ func (m *Module) StartDownload(url string, overrideSeries string, overrideChapte
 			return existingJob.ID, nil
 		}
 
-		if existingJob.Status == persistence.StatusRunning || existingJob.Status == persistence.StatusPending {
-			// Check if it's actually running in memory
-			_, isActive := m.activeJobs.Load(existingJob.ID)
-
-			// Also check if it's in the pending queue
-			m.queueLock.Lock()
-			inQueue := false
-			if queue, ok := m.queues[existingJob.Site]; ok {
-				for _, qj := range queue {
-					if qj.job.ID == existingJob.ID {
-						inQueue = true
-						break
-					}
-				}
-			}
-			m.queueLock.Unlock()
-
-			if isActive || inQueue {
+		if existingJob.Status == persistence.StatusRunning || existingJob.Status == persistence.StatusPending || existingJob.Status == persistence.StatusScheduled {
+			if m.isActiveOrQueued(*existingJob) {
 				fmt.Printf("[Downloader] URL actually active/queued: %s\n", url)
 				return existingJob.ID, nil
 			}
@@ -215,13 +363,27 @@ func (m *Module) StartDownload(url string, overrideSeries string, overrideChapte
 		}
 	}
 
-	info, err := algo.GetImages(url)
-	if err != nil {
-		return "", err
+	var info *SiteInfo
+	resolved := false
+	var stored []persistence.DownloadImage
+	if existingJob != nil {
+		stored = m.pm.GetJobImages(existingJob.ID)
 	}
+	if len(stored) > 0 {
+		// Reuse the stored page list, runDownload resolves it again if the URLs have expired
+		fmt.Printf("[Downloader] Resuming job %s with %d stored pages\n", existingJob.ID, len(stored))
+		info = siteInfoFromJob(*existingJob, stored)
+	} else {
+		var err error
+		info, err = algo.GetImages(url)
+		if err != nil {
+			return "", err
+		}
 
-	if info.Type == "series" {
-		return "", fmt.Errorf("this is a series URL, use FetchMangaInfo to select chapters")
+		if info.Type == "series" {
+			return "", fmt.Errorf("this is a series URL, use FetchMangaInfo to select chapters")
+		}
+		resolved = true
 	}
 
 	// Apply overrides if provided
@@ -232,6 +394,22 @@ func (m *Module) StartDownload(url string, overrideSeries string, overrideChapte
 		info.ChapterName = overrideChapter
 	}
 
+	// Same gallery or chapter from another source, only checked when it was scraped
+	// now, so resumes don't pay for it again. Names are compared here, the page hashes
+	// need the site and are compared by runDownload, where cancelling the job stops them.
+	duplicateOf := ""
+	if resolved && m.duplicateAction() != DuplicateOff {
+		duplicateOf = m.jobDuplicate(info, existingJob, nil)
+		info.comparePages = duplicateOf == ""
+		if duplicateOf != "" {
+			if m.duplicateAction() == DuplicateSkip {
+				fmt.Printf("[Downloader] Skipping %s, already downloaded at %s\n", url, duplicateOf)
+				return "", fmt.Errorf("%w: %s", errDuplicate, duplicateOf)
+			}
+			fmt.Printf("[Downloader] %s looks like a duplicate of %s\n", url, duplicateOf)
+		}
+	}
+
 	var jobID string
 	var job persistence.DownloadJob
 
@@ -246,12 +424,25 @@ func (m *Module) StartDownload(url string, overrideSeries string, overrideChapte
 		job.TotalPages = len(info.Images)
 
 		// Update persistence status to Pending so UI shows it waiting
-		m.pm.UpdateJob(jobID, map[string]interface{}{
-			"status": persistence.StatusPending,
-		})
+		updates := fillChapterDetails(&job, info)
+		updates["status"] = persistence.StatusPending
+		if duplicateOf != "" {
+			updates["duplicateOf"] = duplicateOf
+		}
+		if batchID != "" {
+			job.BatchID = batchID
+			updates["batchId"] = batchID
+		}
+		if resolved {
+			updates["images"] = storedImages(info.Images)
+			updates["resolvedAt"] = time.Now().Format(time.RFC3339)
+			updates["downloadDelayMs"] = int(info.DownloadDelay / time.Millisecond)
+			updates["totalPages"] = len(info.Images)
+		}
+		m.pm.UpdateJob(jobID, updates)
 	} else {
 		// Create new job
-		jobID = fmt.Sprintf("%d", time.Now().UnixNano())
+		jobID = newJobID()
 		job = persistence.DownloadJob{
 			ID:          jobID,
 			URL:         url,
@@ -262,31 +453,123 @@ func (m *Module) StartDownload(url string, overrideSeries string, overrideChapte
 			Progress:    0,
 			TotalPages:  len(info.Images),
 			CreatedAt:   time.Now().Format(time.RFC3339),
+			BatchID:     batchID,
+
+			Images:          storedImages(info.Images),
+			ResolvedAt:      time.Now().Format(time.RFC3339),
+			DownloadDelayMs: int(info.DownloadDelay / time.Millisecond),
+			DuplicateOf:     duplicateOf,
+		}
+		fillChapterDetails(&job, info)
+		if err := m.pm.AddJob(job); err != nil {
+			return "", fmt.Errorf("failed to save job: %v", err)
 		}
-		m.pm.AddJob(job)
 	}
 	m.notifyUpdate() // Notify frontend
 
-	// Check concurrency limits
+	m.queueLock.Lock()
+	m.enqueueLocked(job, info)
+	m.queueLock.Unlock()
+
+	return jobID, nil
+}
+
+// lastJobID is the last value handed out by newJobID
+var lastJobID atomic.Int64
+
+// newJobID returns the ID of a new job: the current time in nanoseconds, moved forward
+// when jobs are created faster than the clock ticks
+func newJobID() string {
+	for {
+		last := lastJobID.Load()
+		id := max(time.Now().UnixNano(), last+1)
+		if lastJobID.CompareAndSwap(last, id) {
+			return fmt.Sprintf("%d", id)
+		}
+	}
+}
+
+// isActiveOrQueued reports whether a job is running or waiting in its site queue in this session
+func (m *Module) isActiveOrQueued(job persistence.DownloadJob) bool {
+	if _, isActive := m.activeJobs.Load(job.ID); isActive {
+		return true
+	}
+
 	m.queueLock.Lock()
 	defer m.queueLock.Unlock()
+	for _, qj := range m.queues[job.Site] {
+		if qj.job.ID == job.ID {
+			return true
+		}
+	}
+	return false
+}
+
+// SetSeriesModule sets the module completed chapters are registered in
+func (m *Module) SetSeriesModule(sm interface {
+	AddChapter(seriesPath string, chapterPath string) error
+	MoveChapter(oldPath string, newPath string) error
+}) {
+	m.seriesModule = sm
+}
+
+// addToSeries registers the chapter folder of a completed job in its series, the
+// folder above it. Chapters right in the download folder have no series to join.
+func (m *Module) addToSeries(jobID string) {
+	job, ok := m.findJob(jobID)
+	if !ok || job.Path == "" || m.seriesModule == nil {
+		return
+	}
+	chapterPath := job.Path
+	if strings.EqualFold(filepath.Ext(chapterPath), ".cbz") {
+		// Series chapters are folders, only usable if the images were kept
+		chapterPath = strings.TrimSuffix(chapterPath, filepath.Ext(chapterPath))
+		if info, err := os.Stat(chapterPath); err != nil || !info.IsDir() {
+			return
+		}
+	}
+	seriesPath := filepath.Dir(chapterPath)
+	if filepath.Clean(seriesPath) == filepath.Clean(m.downloadBasePath()) {
+		fmt.Printf("[Downloader] %s is not inside a series folder, not added to Series\n", chapterPath)
+		return
+	}
+	if err := m.seriesModule.AddChapter(seriesPath, chapterPath); err != nil {
+		fmt.Printf("[Downloader] Failed to add %s to Series: %v\n", job.ChapterName, err)
+	}
+}
 
-	siteID := algo.GetSiteID()
-	limit := m.maxConcurrency[siteID]
+// enqueueLocked starts a job right away when its site has a free slot, otherwise queues it.
+// Caller must hold queueLock.
+func (m *Module) enqueueLocked(job persistence.DownloadJob, info *SiteInfo) {
+	siteID := job.Site
+	limit := m.siteLimit(siteID).MaxJobs
 	active := m.activeCounts[siteID]
 
+	// Keeps its place in the queue across pauses and restarts
+	if job.QueueOrder == 0 {
+		job.QueueOrder = time.Now().UnixNano()
+		m.pm.UpdateJob(job.ID, map[string]interface{}{"queueOrder": job.QueueOrder})
+	}
+
+	// Outside the download windows everything waits in the queue
+	if !m.downloadWindowOpen() {
+		job.Status = persistence.StatusScheduled
+		m.insertQueuedLocked(&queuedJob{job: job, info: info})
+		m.pm.UpdateJob(job.ID, map[string]interface{}{"status": persistence.StatusScheduled})
+		fmt.Printf("[Downloader] Scheduled job %s for site %s until the next download window\n", job.ID, siteID)
+		return
+	}
+
 	// If limit is 0 (unlimited) or active count is below limit, start immediately
 	if limit == 0 || active < limit {
 		m.activeCounts[siteID]++
 		go m.runDownload(job, info)
 	} else {
 		// Queue the job
-		m.queues[siteID] = append(m.queues[siteID], &queuedJob{job: job, info: info})
+		m.insertQueuedLocked(&queuedJob{job: job, info: info})
 		// Job remains in Pending status in persistence
-		fmt.Printf("[Downloader] Queued job %s for site %s (Active: %d, Limit: %d)\n", jobID, siteID, active, limit)
+		fmt.Printf("[Downloader] Queued job %s for site %s (Active: %d, Limit: %d)\n", job.ID, siteID, active, limit)
 	}
-
-	return jobID, nil
 }
 
 func (m *Module) runDownload(job persistence.DownloadJob, info *SiteInfo) {
@@ -294,24 +577,33 @@ func (m *Module) runDownload(job persistence.DownloadJob, info *SiteInfo) {
 	defer m.finalizeJob(job.Site)
 
 	ctx, cancel := context.WithCancel(context.Background())
-	m.activeJobs.Store(job.ID, &activeJob{cancel: cancel})
-	defer m.activeJobs.Delete(job.ID)
+	aj := &activeJob{cancel: cancel}
+	m.activeJobs.Store(job.ID, aj)
+	// A resumed job may already have registered itself again under the same ID
+	defer m.activeJobs.CompareAndDelete(job.ID, aj)
 
 	m.pm.UpdateJob(job.ID, map[string]interface{}{"status": persistence.StatusRunning})
 	m.notifyUpdate()
 
-	settings := m.sm.Get()
-	basePath := settings.DownloadPath
-	if basePath == "" {
-		// Use app data dir / downloads
-		homeDir, _ := os.UserHomeDir()
-		basePath = filepath.Join(homeDir, ".manga-visor", "downloads")
+	if info.comparePages && !m.comparePages(ctx, job, info) {
+		return
 	}
 
-	// Folder structure: Site / Series / Chapter
-	safeSeries := sanitizeFilename(info.SeriesName)
-	safeChapter := sanitizeFilename(info.ChapterName)
-	downloadDir := filepath.Join(basePath, info.SiteID, safeSeries, safeChapter)
+	// Pages go where the naming template puts them, by default Site / Series / Chapter
+	tmpl := m.namingTemplate()
+	basePath := m.downloadBasePath()
+	fields := jobNamingFields(job, info)
+	pagePath := func(i int, img ImageDownload) string {
+		f := fields
+		f.Page = i + 1
+		f.Filename, f.Ext = splitFilename(img.Filename, img.URL)
+		f.File = img.Filename
+		return filepath.Join(basePath, renderTemplate(tmpl, f))
+	}
+	downloadDir := basePath
+	if len(info.Images) > 0 {
+		downloadDir = filepath.Dir(pagePath(0, info.Images[0]))
+	}
 
 	if err := os.MkdirAll(downloadDir, 0755); err != nil {
 		m.failJob(job.ID, err.Error())
@@ -320,55 +612,215 @@ func (m *Module) runDownload(job persistence.DownloadJob, info *SiteInfo) {
 
 	m.pm.UpdateJob(job.ID, map[string]interface{}{"path": downloadDir})
 
-	for i, img := range info.Images {
-		select {
-		case <-ctx.Done():
-			m.pm.UpdateJob(job.ID, map[string]interface{}{"status": persistence.StatusCancelled})
-			m.notifyUpdate()
-			return
-		default:
-			// Check if file already exists (Resume capability)
-			destFilename := sanitizeFilename(img.Filename)
-			if destFilename == "" {
-				destFilename = fmt.Sprintf("page_%04d", i+1)
-			}
-			destPath := filepath.Join(downloadDir, destFilename)
-			if fInfo, err := os.Stat(destPath); err == nil && fInfo.Size() > 0 {
-				fmt.Printf("[Downloader] Skipping existing file: %s\n", img.Filename)
-				m.pm.UpdateJob(job.ID, map[string]interface{}{"progress": i + 1})
-				m.notifyUpdate()
-				continue
+	// Don't start what won't fit, a paused job is easier to deal with than half a gallery
+	if err := m.preflightJob(ctx, job, info, pagePath); err != nil {
+		m.holdJob(job, info, err.Error())
+		return
+	}
+	defer m.releasePreflight(job.ID)
+
+	// Pages are fetched by a small worker pool. DownloadDelay still spaces out
+	// request starts for the whole job, so the site sees the same request rate.
+	limit := m.siteLimit(info.SiteID)
+	workers := m.pageWorkers(info.SiteID)
+	if workers > len(info.Images) {
+		workers = len(info.Images)
+	}
+	pace := newPacer(info.DownloadDelay)
+	refresher := newImageRefresher(m, job, len(info.Images))
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		completed int
+		failed    []int
+		pageErrs  = make(map[int]string)
+	)
+
+	// Pages finish out of order, so progress counts finished pages instead of using the index
+	pageDone := func() {
+		mu.Lock()
+		completed++
+		m.pm.UpdateJob(job.ID, map[string]interface{}{"progress": completed})
+		mu.Unlock()
+		m.notifyUpdate()
+	}
+
+	indexes := make(chan int)
+	go func() {
+		defer close(indexes)
+		for i := range info.Images {
+			select {
+			case indexes <- i:
+			case <-ctx.Done():
+				return
 			}
+		}
+	}()
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				img := info.Images[i]
+
+				// Check if file already exists (Resume capability)
+				destPath := pagePath(i, img)
+				if fInfo, err := os.Stat(destPath); err == nil && fInfo.Size() > 0 {
+					// Pages written before .part files existed may be truncated
+					verifyErr := verifyImage(destPath)
+					if verifyErr == nil {
+						fmt.Printf("[Downloader] Skipping existing file: %s\n", img.Filename)
+						pageDone()
+						continue
+					}
+					fmt.Printf("[Downloader] Downloading again damaged file %s: %v\n", img.Filename, verifyErr)
+				}
 
-			// Add a small delay between requests to avoid rate limiting
-			if i > 0 && info.DownloadDelay > 0 {
-				select {
-				case <-ctx.Done():
-					m.pm.UpdateJob(job.ID, map[string]interface{}{"status": persistence.StatusCancelled})
-					m.notifyUpdate()
+				// Wait for our turn to avoid rate limiting
+				if !pace.wait(ctx) {
 					return
-				case <-time.After(info.DownloadDelay):
-					// Delay completed, continue
 				}
-			}
 
-			err := downloadFile(img.URL, destPath, img.Headers)
-			if err != nil {
-				// Retry is handled inside downloadFile, if it still fails, we fail the job
-				m.failJob(job.ID, fmt.Sprintf("Failed to download page %d: %v", i+1, err))
-				return
+				img = refresher.latest(i, img)
+				err := m.clients.downloadFile(ctx, img.URL, destPath, img.Headers, info.SiteID, limit)
+				if isExpiredURL(err) {
+					// Stored URL no longer valid, resolve the chapter again and retry once
+					if fresh, refreshErr := refresher.refresh(i); refreshErr == nil {
+						if !pace.wait(ctx) {
+							return
+						}
+						err = m.clients.downloadFile(ctx, fresh.URL, destPath, fresh.Headers, info.SiteID, limit)
+					} else {
+						err = fmt.Errorf("%v (%v)", err, refreshErr)
+					}
+				}
+				if err != nil && ctx.Err() != nil {
+					// Cancelled or paused, the page is not a failure
+					return
+				}
+				if err != nil {
+					// Retry is handled inside downloadFile. If it still fails, record the page
+					// and keep going, RetryFailedPages can fetch it later
+					fmt.Printf("[Downloader] Failed to download page %d of job %s: %v\n", i+1, job.ID, err)
+					mu.Lock()
+					failed = append(failed, i)
+					pageErrs[i] = err.Error()
+					mu.Unlock()
+					continue
+				}
+				m.pageWritten(job.ID, destPath)
+				pageDone()
 			}
-			m.pm.UpdateJob(job.ID, map[string]interface{}{"progress": i + 1})
-			m.notifyUpdate()
+		}()
+	}
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		if m.parkJob(job, info, aj) {
+			return
 		}
+		m.pm.UpdateJob(job.ID, map[string]interface{}{"status": persistence.StatusCancelled})
+		m.notifyUpdate()
+		return
 	}
 
 	now := time.Now().Format(time.RFC3339)
+	if len(failed) > 0 {
+		sort.Ints(failed)
+		// Keep the stored page list, RetryFailedPages works from it
+		m.pm.UpdateJob(job.ID, map[string]interface{}{
+			"status":      persistence.StatusCompletedWithErrors,
+			"completedAt": now,
+			"failedPages": failed,
+			"error":       fmt.Sprintf("%d of %d pages failed (page %d: %s)", len(failed), len(info.Images), failed[0]+1, pageErrs[failed[0]]),
+		})
+		m.notifyUpdate()
+		m.jobFinished(job.ID, HookCompletedWithErrors)
+		return
+	}
+
+	// Page sizes feed the estimate of the next downloads from the site
+	var bytes int64
+	files := make([]string, len(info.Images))
+	for i, img := range info.Images {
+		files[i] = filepath.Base(pagePath(i, img))
+		if fInfo, err := os.Stat(pagePath(i, img)); err == nil {
+			bytes += fInfo.Size()
+		}
+	}
+
 	m.pm.UpdateJob(job.ID, map[string]interface{}{
 		"status":      persistence.StatusCompleted,
 		"completedAt": now,
+		"bytes":       bytes,
+		"files":       files,
+		"images":      []persistence.DownloadImage(nil),
+		"failedPages": []int(nil),
+		"error":       "",
 	})
+
+	if packageCBZEnabled(m.sm.Get(), info.SiteID) {
+		// The chapter is complete either way, a failed package leaves the folder as it is
+		if err := m.packageCBZ(job.ID); err != nil {
+			fmt.Printf("[Downloader] Failed to package job %s as CBZ: %v\n", job.ID, err)
+		}
+	}
+	if m.sm.Get().AutoAddToSeries {
+		m.addToSeries(job.ID)
+	}
+	m.indexJob(job.ID)
 	m.notifyUpdate()
+	m.jobFinished(job.ID, HookCompleted)
+}
+
+// siteLimit returns the limits configured in settings for a site
+func (m *Module) siteLimit(siteID string) persistence.SiteLimit {
+	return siteLimitFor(m.sm.Get().SiteLimits, siteID)
+}
+
+// pageWorkers returns the configured page concurrency for a site
+func (m *Module) pageWorkers(siteID string) int {
+	workers := m.siteLimit(siteID).PageConcurrency
+	if workers < 1 {
+		workers = 1
+	}
+	return workers
+}
+
+// pacer hands out request start times at least delay apart, shared by all workers of a job
+type pacer struct {
+	delay time.Duration
+	mu    sync.Mutex
+	next  time.Time
+}
+
+func newPacer(delay time.Duration) *pacer {
+	return &pacer{delay: delay}
+}
+
+// wait blocks until the caller may start a request, false if ctx was cancelled first
+func (p *pacer) wait(ctx context.Context) bool {
+	if p.delay <= 0 {
+		return ctx.Err() == nil
+	}
+
+	p.mu.Lock()
+	now := time.Now()
+	start := p.next
+	if start.Before(now) {
+		start = now
+	}
+	p.next = start.Add(p.delay)
+	p.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(time.Until(start)):
+		return true
+	}
 }
 
 func (m *Module) failJob(id string, err string) {
@@ -377,6 +829,7 @@ func (m *Module) failJob(id string, err string) {
 		"error":  err,
 	})
 	m.notifyUpdate()
+	m.jobFinished(id, HookFailed)
 }
 
 func (m *Module) finalizeJob(siteID string) {
@@ -388,18 +841,8 @@ func (m *Module) finalizeJob(siteID string) {
 		m.activeCounts[siteID] = 0 // Should not happen
 	}
 
-	// Check if there are pending jobs
-	queue := m.queues[siteID]
-	if len(queue) > 0 {
-		// Pop first
-		next := queue[0]
-		m.queues[siteID] = queue[1:]
-
-		// Start it
-		m.activeCounts[siteID]++
-		fmt.Printf("[Downloader] Starting queued job %s for site %s\n", next.job.ID, siteID)
-		go m.runDownload(next.job, next.info)
-	}
+	// Start pending jobs while there are free slots
+	m.startQueuedLocked(siteID)
 }
 
 func (m *Module) notifyUpdate() {
@@ -424,6 +867,15 @@ func (m *Module) VerifyDownloadComplete(job persistence.DownloadJob) (bool, erro
 		return false, nil // Carpeta no existe
 	}
 
+	// Packaged as CBZ
+	if strings.EqualFold(filepath.Ext(job.Path), ".cbz") {
+		pages, err := countArchivePages(job.Path)
+		if err != nil {
+			return false, err
+		}
+		return pages >= job.TotalPages, nil
+	}
+
 	// Contar archivos en la carpeta
 	files, err := os.ReadDir(job.Path)
 	if err != nil {
@@ -466,6 +918,11 @@ func (m *Module) ResumeIncompleteDownloads(autoResume bool) error {
 	}
 
 	jobs := m.pm.GetJobs()
+	// Reanudar en el orden de la cola (prioridad y posición), las más antiguas primero
+	for i, j := 0, len(jobs)-1; i < j; i, j = i+1, j-1 {
+		jobs[i], jobs[j] = jobs[j], jobs[i]
+	}
+	sort.SliceStable(jobs, func(i, j int) bool { return queueLess(jobs[i], jobs[j]) })
 	for _, job := range jobs {
 		// NO reanudar descargas marcadas como "completed"
 		// Aunque falten archivos, asumimos que el usuario las eliminó/movió intencionalmente
@@ -476,7 +933,7 @@ func (m *Module) ResumeIncompleteDownloads(autoResume bool) error {
 		// Solo reanudar: failed, cancelled, o pending/running zombies
 		if job.Status == persistence.StatusFailed ||
 			job.Status == persistence.StatusCancelled ||
-			(job.Status == persistence.StatusPending || job.Status == persistence.StatusRunning) {
+			(job.Status == persistence.StatusPending || job.Status == persistence.StatusScheduled || job.Status == persistence.StatusRunning) {
 			// Verificar si está realmente activa
 			_, isActive := m.activeJobs.Load(job.ID)
 
@@ -552,64 +1009,138 @@ func sanitizeFilename(name string) string {
 	return res
 }
 
-func downloadFile(url string, path string, headers map[string]string) error {
-	client := &http.Client{}
+// partSuffix is appended to pages while they are being written
+const partSuffix = ".part"
+
+// statusError is returned by downloadFile for responses that retrying won't fix
+type statusError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("bad status: %s", e.Status)
+}
+
+// isExpiredURL reports whether a download failed because the image URL is no longer valid
+func isExpiredURL(err error) bool {
+	var se *statusError
+	return errors.As(err, &se) && (se.StatusCode == http.StatusForbidden || se.StatusCode == http.StatusNotFound)
+}
+
+// downloadFile fetches one page, retrying with exponential backoff. Cancelling ctx aborts
+// the request in flight and the backoff wait.
+func (f *clientFactory) downloadFile(ctx context.Context, url string, path string, headers map[string]string, siteID string, limit persistence.SiteLimit) error {
+	// No overall timeout, the body may take long under a bandwidth cap. The transport
+	// bounds connecting and the response headers, downloadAttempt the gaps in the body.
+	client := f.newClient()
+	client.Timeout = 0
 	var lastErr error
 
-	// Retry configuration
-	maxRetries := 3
-	baseDelay := 2 * time.Second
+	// Retry configuration, from the site limits
+	maxRetries := limit.MaxRetries
+	if maxRetries < 0 {
+		maxRetries = 0
+	}
+	baseDelay := time.Duration(limit.BackoffMs) * time.Millisecond
 
 	for attempt := 0; attempt <= maxRetries; attempt++ {
 		if attempt > 0 {
-			// Exponential backoff: 2s, 4s, 8s
+			// Exponential backoff: 2s, 4s, 8s with the default 2s base
 			sleepDuration := baseDelay * time.Duration(1<<uint(attempt-1))
-			time.Sleep(sleepDuration)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(sleepDuration):
+			}
 		}
 
-		req, err := http.NewRequest("GET", url, nil)
-		if err != nil {
-			return err // Fatal error building request
+		retry, err := f.downloadAttempt(ctx, client, url, path, headers, siteID)
+		if err == nil {
+			return nil
 		}
-
-		// Add headers
-		if headers != nil {
-			for k, v := range headers {
-				req.Header.Set(k, v)
-			}
-		} else {
-			req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) Chrome/120.0.0.0")
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if !retry {
+			return err
 		}
+		lastErr = err
+	}
 
-		resp, err := client.Do(req)
-		if err != nil {
-			lastErr = err
-			continue // Network error, retry
+	return fmt.Errorf("failed after %d retries: %v", maxRetries, lastErr)
+}
+
+// downloadAttempt makes a single request for a page. retry reports whether trying again may help.
+func (f *clientFactory) downloadAttempt(ctx context.Context, client *http.Client, url string, path string, headers map[string]string, siteID string) (retry bool, err error) {
+	attemptCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	req, err := http.NewRequestWithContext(attemptCtx, "GET", url, nil)
+	if err != nil {
+		return false, err // Fatal error building request
+	}
+
+	// Add headers
+	if headers != nil {
+		for k, v := range headers {
+			req.Header.Set(k, v)
 		}
-		defer resp.Body.Close()
+	} else {
+		req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) Chrome/120.0.0.0")
+	}
 
-		if resp.StatusCode == http.StatusOK {
-			// Success
-			out, err := os.Create(path)
-			if err != nil {
-				return err // File system error
-			}
-			defer out.Close()
+	// MangaDex@Home nodes want to hear about every page they serve, failed or not
+	start := time.Now()
+	var cached bool
+	var written int64
+	defer func() {
+		if ctx.Err() == nil {
+			f.reportAtHome(siteID, url, err == nil, cached, written, time.Since(start))
+		}
+	}()
 
-			_, err = io.Copy(out, resp.Body)
-			return err
+	resp, err := client.Do(req)
+	if err != nil {
+		return true, err // Network error, retry
+	}
+	defer resp.Body.Close()
+	cached = strings.HasPrefix(resp.Header.Get("X-Cache"), "HIT")
+
+	if resp.StatusCode != http.StatusOK {
+		// 403/404 usually mean the URL itself expired, retrying won't help.
+		// The caller can resolve the page again instead.
+		if resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusNotFound {
+			return false, &statusError{StatusCode: resp.StatusCode, Status: resp.Status}
 		}
+		return true, fmt.Errorf("bad status: %s", resp.Status)
+	}
 
-		// Handle non-200 status codes
-		lastErr = fmt.Errorf("bad status: %s", resp.Status)
+	// Write to a .part file first, the final name only ever holds complete pages
+	partPath := path + partSuffix
+	out, err := os.Create(partPath)
+	if err != nil {
+		return false, err // File system error
+	}
 
-		// If it's a 503 or 429, we definitely want to retry.
-		// For others (like 404), maybe not, but simple retry mechanism for now covers transient issues.
-		// If 404, usually retrying won't help, but keeping it simple.
-		if resp.StatusCode != http.StatusServiceUnavailable && resp.StatusCode != http.StatusTooManyRequests {
-			// Optional: break here if we strictly don't want to retry 404s
+	body := &idleTimeoutReader{r: resp.Body, timeout: pageIdleTimeout, cancel: cancel}
+	written, err = io.Copy(out, f.throttle(ctx, body, siteID))
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && resp.ContentLength >= 0 && written != resp.ContentLength {
+		err = fmt.Errorf("incomplete body: got %d of %d bytes", written, resp.ContentLength)
+	}
+	if err == nil {
+		err = verifyImage(partPath)
+	}
+	if err != nil {
+		// Truncated, corrupt or cancelled halfway, retry it
+		os.Remove(partPath)
+		if ctx.Err() == nil {
+			fmt.Printf("[Downloader] Page failed verification (%s): %v\n", filepath.Base(path), err)
 		}
+		return true, err
 	}
 
-	return fmt.Errorf("failed after %d retries: %v", maxRetries, lastErr)
+	return false, os.Rename(partPath, path)
 }
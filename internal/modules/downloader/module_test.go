@@ -0,0 +1,96 @@
+package downloader
+
+import (
+	"errors"
+	"manga-visor/internal/persistence"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestMain points the data directory at a temp folder, so tests that build a Module
+// never touch the real job list and settings
+func TestMain(m *testing.M) {
+	home, err := os.MkdirTemp("", "manga-visor-test")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("HOME", home)
+	os.Setenv("USERPROFILE", home)
+	code := m.Run()
+	os.RemoveAll(home)
+	os.Exit(code)
+}
+
+// fakeDownloader handles the URLs under its prefix and fails every GetImages, recording the calls
+type fakeDownloader struct {
+	prefix string
+	mu     sync.Mutex
+	calls  []string
+}
+
+func (d *fakeDownloader) CanHandle(url string) bool {
+	return strings.HasPrefix(url, d.prefix)
+}
+
+func (d *fakeDownloader) GetImages(url string) (*SiteInfo, error) {
+	d.mu.Lock()
+	d.calls = append(d.calls, url)
+	d.mu.Unlock()
+	return nil, errors.New("offline")
+}
+
+func (d *fakeDownloader) GetSiteID() string {
+	return "fake"
+}
+
+func (d *fakeDownloader) called() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string(nil), d.calls...)
+}
+
+// newTestModule returns a Module with an empty job list whose only downloader is d
+func newTestModule(t *testing.T, d DownloaderInterface) *Module {
+	t.Helper()
+	pm := persistence.NewDownloaderManager()
+	pm.ClearJobs()
+	m := NewModule(pm, persistence.NewSettingsManager(), persistence.NewCookiesManager(), persistence.NewSubscriptionsManager(), persistence.NewContentIndexManager(), persistence.NewMangaDexAuthManager())
+	m.algoLock.Lock()
+	m.builtins = []DownloaderInterface{d}
+	m.rebuildAlgorithms()
+	m.algoLock.Unlock()
+	return m
+}
+
+// waitFor polls cond until it holds, failing the test after two seconds
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestNewJobIDUnique(t *testing.T) {
+	var last int64
+	seen := make(map[string]bool)
+	for i := 0; i < 5000; i++ {
+		id := newJobID()
+		n, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			t.Fatalf("job ID %q is not a number", id)
+		}
+		if seen[id] || n <= last {
+			t.Fatalf("job ID %s after %d is not new", id, last)
+		}
+		seen[id] = true
+		last = n
+	}
+}
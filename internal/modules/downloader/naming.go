@@ -0,0 +1,567 @@
+package downloader
+
+import (
+	"fmt"
+	"manga-visor/internal/persistence"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultNamingTemplate is the original layout: Site / Series / Chapter / native filename.
+// Segments that are a single field render the bare value, so it gives the same paths as
+// before templates existed and old downloads resume in place.
+const defaultNamingTemplate = "{site}/{series}/{title}/{file}"
+
+// namingFields are the values a naming template can use
+type namingFields struct {
+	Site     string
+	Series   string
+	Title    string // chapter name
+	Chapter  string // chapter number, the name when the site has none
+	Volume   string
+	Group    string
+	Lang     string
+	Page     int    // 1-based
+	Filename string // native filename without extension
+	Ext      string // without the dot
+	File     string // native filename as the site gives it
+}
+
+var templateFieldRe = regexp.MustCompile(`\{(\w+)(?::([0#.]+))?\}`)
+var emptyBracketsRe = regexp.MustCompile(`\[\s*\]|\(\s*\)|\{\s*\}`)
+
+// namingTemplate returns the template from settings, or the default one when it is empty or invalid
+func (m *Module) namingTemplate() string {
+	tmpl := strings.TrimSpace(m.sm.Get().DownloadNamingTemplate)
+	if tmpl == "" {
+		return defaultNamingTemplate
+	}
+	if err := validateTemplate(tmpl); err != nil {
+		fmt.Printf("[Downloader] Invalid naming template, using the default one: %v\n", err)
+		return defaultNamingTemplate
+	}
+	return tmpl
+}
+
+// validateTemplate checks the fields of a template and that every page of every chapter
+// gets its own name
+func validateTemplate(tmpl string) error {
+	for _, match := range templateFieldRe.FindAllStringSubmatch(tmpl, -1) {
+		switch match[1] {
+		case "site", "series", "title", "chapter", "volume", "group", "lang", "page", "filename", "ext", "file":
+		default:
+			return fmt.Errorf("unknown field {%s}", match[1])
+		}
+	}
+	segments := strings.Split(tmpl, "/")
+	last := segments[len(segments)-1]
+	if !strings.Contains(last, "{page") && !strings.Contains(last, "{filename") && !strings.Contains(last, "{file}") {
+		return fmt.Errorf("the file name must contain {page}, {filename} or {file}")
+	}
+	// A chapter has to stay in one folder
+	for _, segment := range segments[:len(segments)-1] {
+		if strings.Contains(segment, "{page") || strings.Contains(segment, "{file") || strings.Contains(segment, "{ext") {
+			return fmt.Errorf("{page}, {filename}, {file} and {ext} can only be used in the file name")
+		}
+	}
+	// Otherwise the pages of every chapter get the same paths, and a resume takes the
+	// pages of the previous chapter for its own
+	if !templateNamesChapter(tmpl) {
+		return fmt.Errorf("the template must contain {chapter} or {title}")
+	}
+	return nil
+}
+
+// templateNamesChapter reports whether a template part has a field that differs per chapter
+func templateNamesChapter(part string) bool {
+	return strings.Contains(part, "{title") || strings.Contains(part, "{chapter")
+}
+
+// renderTemplate builds the path of a page relative to the download folder.
+// Every segment goes through sanitizeFilename, so a "/" in a series name can't create folders.
+func renderTemplate(tmpl string, f namingFields) string {
+	var parts []string
+	for _, segment := range strings.Split(tmpl, "/") {
+		if rendered := sanitizeFilename(renderSegment(segment, f)); rendered != "" {
+			parts = append(parts, rendered)
+		}
+	}
+	return filepath.Join(parts...)
+}
+
+func renderSegment(segment string, f namingFields) string {
+	if loc := templateFieldRe.FindStringSubmatchIndex(segment); loc != nil && loc[0] == 0 && loc[1] == len(segment) && loc[4] < 0 {
+		// A bare field is used as it is, only sanitizeFilename applies
+		return fieldValue(segment[loc[2]:loc[3]], f)
+	}
+
+	var out strings.Builder
+	last := 0
+	for _, loc := range templateFieldRe.FindAllStringSubmatchIndex(segment, -1) {
+		literal := segment[last:loc[0]]
+		last = loc[1]
+
+		name := segment[loc[2]:loc[3]]
+		spec := ""
+		if loc[4] >= 0 {
+			spec = segment[loc[4]:loc[5]]
+		}
+
+		value := fieldValue(name, f)
+		if spec != "" {
+			value = formatNumber(value, spec)
+		}
+		if value == "" {
+			// Drop the label glued to an empty field, "Vol.{volume}" disappears as a whole
+			out.WriteString(literal[:strings.LastIndexAny(literal, " [(_-")+1])
+			continue
+		}
+		out.WriteString(literal)
+		out.WriteString(value)
+	}
+	out.WriteString(segment[last:])
+
+	res := emptyBracketsRe.ReplaceAllString(out.String(), "")
+	return strings.Trim(strings.Join(strings.Fields(res), " "), " -_")
+}
+
+func fieldValue(name string, f namingFields) string {
+	switch name {
+	case "site":
+		return f.Site
+	case "series":
+		return f.Series
+	case "title":
+		return f.Title
+	case "chapter":
+		if f.Chapter != "" {
+			return f.Chapter
+		}
+		return f.Title
+	case "volume":
+		return f.Volume
+	case "group":
+		return f.Group
+	case "lang":
+		return f.Lang
+	case "page":
+		return strconv.Itoa(f.Page)
+	case "filename":
+		if f.Filename != "" {
+			return f.Filename
+		}
+		return fmt.Sprintf("page_%04d", f.Page)
+	case "ext":
+		return f.Ext
+	case "file":
+		if f.File != "" {
+			return f.File
+		}
+		return fmt.Sprintf("page_%04d", f.Page)
+	}
+	return ""
+}
+
+// formatNumber pads a number with a spec like "000.#": the zeros before the dot are the
+// minimum integer digits, after it "0" is a required decimal and "#" an optional one.
+// Without a dot in the spec the decimals are kept as they are. Non-numbers are left alone.
+func formatNumber(value string, spec string) string {
+	if _, err := strconv.ParseFloat(value, 64); err != nil || strings.HasPrefix(value, "-") {
+		return value
+	}
+	intPart, fracPart, _ := strings.Cut(value, ".")
+	fracPart = strings.TrimRight(fracPart, "0")
+
+	intSpec, fracSpec, hasFrac := strings.Cut(spec, ".")
+	intPart = strings.TrimLeft(intPart, "0")
+	for len(intPart) < max(strings.Count(intSpec, "0"), 1) {
+		intPart = "0" + intPart
+	}
+
+	if hasFrac {
+		required := strings.Count(fracSpec, "0")
+		if maxDigits := len(fracSpec); len(fracPart) > maxDigits {
+			fracPart = fracPart[:maxDigits]
+		}
+		for len(fracPart) < required {
+			fracPart += "0"
+		}
+	}
+	if fracPart == "" {
+		return intPart
+	}
+	return intPart + "." + fracPart
+}
+
+// splitFilename returns the native name of a page without extension, and the extension
+func splitFilename(filename string, url string) (string, string) {
+	ext := filepath.Ext(filename)
+	if ext == "" {
+		// Extension from the URL path, ignoring the query string
+		urlPath, _, _ := strings.Cut(url, "?")
+		ext = path.Ext(urlPath)
+	}
+	base := strings.TrimSuffix(filename, filepath.Ext(filename))
+	return base, strings.TrimPrefix(strings.ToLower(ext), ".")
+}
+
+// jobNamingFields collects the template fields of a job
+func jobNamingFields(job persistence.DownloadJob, info *SiteInfo) namingFields {
+	f := namingFields{
+		Site:    info.SiteID,
+		Series:  info.SeriesName,
+		Title:   info.ChapterName,
+		Chapter: job.ChapterNumber,
+		Volume:  job.Volume,
+		Group:   job.ScanGroup,
+		Lang:    job.Language,
+	}
+	if f.Chapter == "" {
+		if n, ok := chapterNumber(ChapterInfo{Name: info.ChapterName}); ok {
+			f.Chapter = strconv.FormatFloat(n, 'f', -1, 64)
+		}
+	}
+	return f
+}
+
+// fillChapterDetails copies the chapter details reported by a site into the fields of a job
+// that are still empty. Returns the changes as UpdateJob updates.
+func fillChapterDetails(job *persistence.DownloadJob, info *SiteInfo) map[string]interface{} {
+	updates := map[string]interface{}{}
+	set := func(field *string, key string, value string) {
+		if *field == "" && value != "" {
+			*field = value
+			updates[key] = value
+		}
+	}
+	set(&job.ChapterNumber, "chapterNumber", info.ChapterNumber)
+	set(&job.Volume, "volume", info.Volume)
+	set(&job.ScanGroup, "scanGroup", info.ScanGroup)
+	set(&job.Language, "language", info.Language)
+	return updates
+}
+
+// PreviewNamingTemplate renders a template with sample values, for the settings page
+func (m *Module) PreviewNamingTemplate(tmpl string) (string, error) {
+	if strings.TrimSpace(tmpl) == "" {
+		tmpl = defaultNamingTemplate
+	}
+	if err := validateTemplate(tmpl); err != nil {
+		return "", err
+	}
+	return renderTemplate(tmpl, namingFields{
+		Site:     "mangadex.org",
+		Series:   "One Piece",
+		Title:    "Chapter 1044.5 - Warrior of Liberation [en]",
+		Chapter:  "1044.5",
+		Volume:   "104",
+		Group:    "TCB Scans",
+		Lang:     "en",
+		Page:     7,
+		Filename: "007",
+		Ext:      "jpg",
+		File:     "007.jpg",
+	}), nil
+}
+
+// downloadBasePath returns the download folder from settings, or the default one
+func (m *Module) downloadBasePath() string {
+	basePath := m.sm.Get().DownloadPath
+	if basePath == "" {
+		// Use app data dir / downloads
+		homeDir, _ := os.UserHomeDir()
+		basePath = filepath.Join(homeDir, ".manga-visor", "downloads")
+	}
+	return basePath
+}
+
+// RenameResult reports what RenameDownloadsToTemplate did
+type RenameResult struct {
+	Renamed int      `json:"renamed"`
+	Skipped int      `json:"skipped"`
+	Errors  []string `json:"errors"`
+}
+
+// RenameDownloadsToTemplate moves the pages of completed downloads to the paths of the
+// current naming template. Pages are matched to page numbers in natural filename order,
+// so chapters whose file count doesn't match their page count are left alone.
+func (m *Module) RenameDownloadsToTemplate() (*RenameResult, error) {
+	tmpl := m.namingTemplate()
+	basePath := m.downloadBasePath()
+	result := &RenameResult{Errors: []string{}}
+
+	for _, job := range m.pm.GetJobs() {
+		if job.Status != persistence.StatusCompleted || job.Path == "" {
+			continue
+		}
+		if _, active := m.activeJobs.Load(job.ID); active {
+			continue
+		}
+		renamed, err := m.renameJobFiles(job, tmpl, basePath)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", job.ChapterName, err))
+			continue
+		}
+		if renamed == nil {
+			result.Skipped++
+			continue
+		}
+		// Series and the content index follow the files, or the files go back
+		if err := m.moveJobRecords(job, renamed.path); err != nil {
+			renamed.undo()
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", job.ChapterName, err))
+			continue
+		}
+		m.pm.UpdateJob(job.ID, map[string]interface{}{"path": renamed.path, "files": renamed.files})
+		result.Renamed++
+	}
+
+	fmt.Printf("[Downloader] Renamed %d downloads to template (%d unchanged, %d errors)\n", result.Renamed, result.Skipped, len(result.Errors))
+	m.notifyUpdate()
+	return result, nil
+}
+
+// renamedJob is where renameJobFiles moved a chapter
+type renamedJob struct {
+	path  string
+	files []string
+	// Moves the files back where they were
+	undo func()
+}
+
+// moveJobRecords points the Series chapter and the content index entry of a job at the
+// path it was renamed to. Either both follow or, on error, neither does.
+func (m *Module) moveJobRecords(job persistence.DownloadJob, newPath string) error {
+	var previous *persistence.ContentEntry
+	for _, entry := range m.ci.GetEntries() {
+		if entry.Path == job.Path {
+			previous = &entry
+			break
+		}
+	}
+	if previous != nil {
+		moved := *previous
+		moved.Path = newPath
+		name := filepath.Base(newPath)
+		if strings.EqualFold(filepath.Ext(name), ".cbz") {
+			name = strings.TrimSuffix(name, filepath.Ext(name))
+		}
+		moved.Title = normalizeTitle(name)
+		moved.Series = normalizeTitle(filepath.Base(filepath.Dir(newPath)))
+		moved.Number = nameNumber(name)
+		if info, err := os.Stat(newPath); err == nil {
+			moved.ModTime = info.ModTime().Format(time.RFC3339Nano)
+		}
+		if err := m.ci.ReplaceEntry(job.Path, moved); err != nil {
+			return fmt.Errorf("failed to update the content index: %v", err)
+		}
+	}
+
+	// Chapters packaged as CBZ are registered in Series by the folder they keep, which stays
+	if m.seriesModule != nil && !strings.EqualFold(filepath.Ext(job.Path), ".cbz") {
+		if err := m.seriesModule.MoveChapter(job.Path, newPath); err != nil {
+			if previous != nil {
+				if undoErr := m.ci.ReplaceEntry(newPath, *previous); undoErr != nil {
+					fmt.Printf("[Downloader] Failed to restore the content index entry of %s: %v\n", job.Path, undoErr)
+				}
+			}
+			return fmt.Errorf("failed to update Series: %v", err)
+		}
+	}
+	return nil
+}
+
+// renameJobFiles moves one chapter to the paths of the template. Returns nil when the
+// chapter is already there. Either every file is moved or, on error, none is.
+func (m *Module) renameJobFiles(job persistence.DownloadJob, tmpl string, basePath string) (*renamedJob, error) {
+	if strings.EqualFold(filepath.Ext(job.Path), ".cbz") {
+		return m.renameJobArchive(job, tmpl, basePath)
+	}
+
+	// Without the recorded files, a folder shared with other chapters can't be told apart
+	shared := m.isSharedFolder(job)
+	if shared && len(job.Files) == 0 {
+		return nil, fmt.Errorf("%s is shared with other chapters", job.Path)
+	}
+	pages, err := jobPageFiles(job)
+	if err != nil {
+		return nil, err
+	}
+	if len(job.Files) == 0 && job.TotalPages > 0 && len(pages) != job.TotalPages {
+		return nil, fmt.Errorf("found %d of %d pages", len(pages), job.TotalPages)
+	}
+
+	// Other files (ComicInfo.xml, notes) follow the chapter, unless they may belong to another one
+	var others []string
+	if !shared {
+		entries, err := os.ReadDir(job.Path)
+		if err != nil {
+			return nil, err
+		}
+		isPage := make(map[string]bool, len(pages))
+		for _, name := range pages {
+			isPage[name] = true
+		}
+		for _, e := range entries {
+			if !e.IsDir() && !isPage[e.Name()] {
+				others = append(others, e.Name())
+			}
+		}
+	}
+
+	info := &SiteInfo{SiteID: job.Site, SeriesName: job.SeriesName, ChapterName: job.ChapterName}
+	fields := jobNamingFields(job, info)
+
+	// Plan every move before touching anything
+	type move struct{ from, to string }
+	var moves []move
+	newDir := ""
+	files := make([]string, len(pages))
+	targets := make(map[string]bool)
+	for i, name := range pages {
+		fields.Page = i + 1
+		fields.Filename, fields.Ext = splitFilename(name, "")
+		fields.File = name
+		target := filepath.Join(basePath, renderTemplate(tmpl, fields))
+		dir := filepath.Dir(target)
+		if newDir == "" {
+			newDir = dir
+		} else if dir != newDir {
+			return nil, fmt.Errorf("template splits a chapter over several folders")
+		}
+		if targets[target] {
+			return nil, fmt.Errorf("template gives two pages the same name")
+		}
+		targets[target] = true
+		files[i] = filepath.Base(target)
+		moves = append(moves, move{filepath.Join(job.Path, name), target})
+	}
+	for _, name := range others {
+		target := filepath.Join(newDir, name)
+		if targets[target] {
+			return nil, fmt.Errorf("template gives a page the name of %s", name)
+		}
+		moves = append(moves, move{filepath.Join(job.Path, name), target})
+	}
+
+	unchanged := true
+	for _, mv := range moves {
+		if mv.from != mv.to {
+			unchanged = false
+			break
+		}
+	}
+	if unchanged {
+		return nil, nil
+	}
+
+	sameDir := filepath.Clean(newDir) == filepath.Clean(job.Path)
+	if !sameDir {
+		for _, mv := range moves {
+			if _, err := os.Stat(mv.to); err == nil {
+				return nil, fmt.Errorf("%s already exists", mv.to)
+			}
+		}
+	}
+	if err := os.MkdirAll(newDir, 0755); err != nil {
+		return nil, err
+	}
+
+	// Every rename done so far, undone in reverse order if a later one fails
+	var done []move
+	undo := func() {
+		if err := os.MkdirAll(job.Path, 0755); err != nil {
+			fmt.Printf("[Downloader] Failed to recreate %s: %v\n", job.Path, err)
+		}
+		for i := len(done) - 1; i >= 0; i-- {
+			if undoErr := os.Rename(done[i].to, done[i].from); undoErr != nil {
+				fmt.Printf("[Downloader] Failed to move %s back: %v\n", done[i].to, undoErr)
+			}
+		}
+		if !sameDir {
+			removeEmptyDirs(newDir, basePath)
+		}
+	}
+	rollback := func(err error) (*renamedJob, error) {
+		undo()
+		return nil, err
+	}
+
+	if sameDir {
+		// Renames inside one folder may swap names, go through temporary names first
+		for i := range moves {
+			tmp := fmt.Sprintf("%s.renaming-%d", moves[i].from, i)
+			if err := os.Rename(moves[i].from, tmp); err != nil {
+				return rollback(err)
+			}
+			done = append(done, move{moves[i].from, tmp})
+			moves[i].from = tmp
+		}
+	}
+	for _, mv := range moves {
+		if err := os.Rename(mv.from, mv.to); err != nil {
+			return rollback(err)
+		}
+		done = append(done, mv)
+	}
+
+	if !sameDir {
+		removeEmptyDirs(job.Path, basePath)
+	}
+	return &renamedJob{path: newDir, files: files, undo: undo}, nil
+}
+
+// renameJobArchive moves a chapter packaged as CBZ to "<template folder>.cbz"
+func (m *Module) renameJobArchive(job persistence.DownloadJob, tmpl string, basePath string) (*renamedJob, error) {
+	if _, err := os.Stat(job.Path); err != nil {
+		return nil, err
+	}
+	// The archive is named after the chapter folder, a folder shared by chapters would give
+	// them all the same name
+	if dir := tmpl[:max(strings.LastIndex(tmpl, "/"), 0)]; !templateNamesChapter(dir) {
+		return nil, nil
+	}
+	fields := jobNamingFields(job, &SiteInfo{SiteID: job.Site, SeriesName: job.SeriesName, ChapterName: job.ChapterName})
+	fields.Page = 1
+	target := filepath.Dir(filepath.Join(basePath, renderTemplate(tmpl, fields))) + filepath.Ext(job.Path)
+	if target == filepath.Clean(job.Path) {
+		return nil, nil
+	}
+	if _, err := os.Stat(target); err == nil {
+		return nil, fmt.Errorf("%s already exists", target)
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(job.Path, target); err != nil {
+		return nil, err
+	}
+	removeEmptyDirs(filepath.Dir(job.Path), basePath)
+	undo := func() {
+		err := os.MkdirAll(filepath.Dir(job.Path), 0755)
+		if err == nil {
+			err = os.Rename(target, job.Path)
+		}
+		if err != nil {
+			fmt.Printf("[Downloader] Failed to move %s back: %v\n", target, err)
+			return
+		}
+		removeEmptyDirs(filepath.Dir(target), basePath)
+	}
+	return &renamedJob{path: target, files: job.Files, undo: undo}, nil
+}
+
+// removeEmptyDirs deletes dir and its empty parents, stopping at root
+func removeEmptyDirs(dir string, root string) {
+	root = filepath.Clean(root)
+	for dir = filepath.Clean(dir); dir != root && strings.HasPrefix(dir, root+string(filepath.Separator)); dir = filepath.Dir(dir) {
+		if err := os.Remove(dir); err != nil {
+			return // Not empty
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package downloader
+
+import (
+	"errors"
+	"manga-visor/internal/persistence"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateTemplate(t *testing.T) {
+	tests := []struct {
+		tmpl    string
+		wantErr string // substring of the error, empty when valid
+	}{
+		{tmpl: defaultNamingTemplate},
+		{tmpl: "{series}/Vol.{volume} Ch.{chapter:000}/{page:000}.{ext}"},
+		{tmpl: "{series}/{title}/{filename}.{ext}"},
+		{tmpl: "{series}/{chapter:000.#} [{group}]/{file}"},
+		{tmpl: "{series} - {title} - {page:000}.{ext}"},
+		{tmpl: "{series}/{title}/{author}/{page}", wantErr: "unknown field {author}"},
+		{tmpl: "{series}/{title}", wantErr: "file name must contain"},
+		{tmpl: "{series}/{title}/{ext}", wantErr: "file name must contain"},
+		{tmpl: "{series}/{page}/{title}.{ext}", wantErr: "file name must contain"},
+		{tmpl: "{series}/{title} {page}/{file}", wantErr: "only be used in the file name"},
+		{tmpl: "{series}/{ext}/{title}/{file}", wantErr: "only be used in the file name"},
+		{tmpl: "{site}/{series}/{file}", wantErr: "{chapter} or {title}"},
+		{tmpl: "{series}/{volume}/{page:000}.{ext}", wantErr: "{chapter} or {title}"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.tmpl, func(t *testing.T) {
+			err := validateTemplate(tc.tmpl)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("validateTemplate(%q): %v", tc.tmpl, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("validateTemplate(%q) = %v, want an error containing %q", tc.tmpl, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestRenderTemplate(t *testing.T) {
+	fields := namingFields{
+		Site:     "mangadex",
+		Series:   "One Piece",
+		Title:    "Chapter 12.5: Romance Dawn",
+		Chapter:  "12.5",
+		Volume:   "2",
+		Group:    "TCB",
+		Lang:     "en",
+		Page:     7,
+		Filename: "x7f3a",
+		Ext:      "webp",
+		File:     "x7f3a.webp",
+	}
+	tests := []struct {
+		name   string
+		tmpl   string
+		fields func(f *namingFields)
+		want   string
+	}{
+		{name: "default template", tmpl: defaultNamingTemplate, want: "mangadex/One Piece/Chapter 12.5_ Romance Dawn/x7f3a.webp"},
+		{name: "padded numbers", tmpl: "{series}/Ch.{chapter:000}/{page:000}.{ext}", want: "One Piece/Ch.012.5/007.webp"},
+		{name: "optional decimal", tmpl: "{series}/{chapter:000.#}/{page:00}.{ext}", want: "One Piece/012.5/07.webp"},
+		{name: "required decimal", tmpl: "{series}/{chapter:0.00}/{page}.{ext}", want: "One Piece/12.50/7.webp"},
+		{name: "brackets and label", tmpl: "{series}/Vol.{volume} Ch.{chapter} [{group}] ({lang})/{page}.{ext}", want: "One Piece/Vol.2 Ch.12.5 [TCB] (en)/7.webp"},
+		{
+			name:   "empty fields drop their label and brackets",
+			tmpl:   "{series}/Vol.{volume} Ch.{chapter} [{group}]/{page}.{ext}",
+			fields: func(f *namingFields) { f.Volume, f.Group = "", "" },
+			want:   "One Piece/Ch.12.5/7.webp",
+		},
+		{
+			name:   "chapter falls back to the title",
+			tmpl:   "{series}/{chapter}/{page}.{ext}",
+			fields: func(f *namingFields) { f.Chapter = "" },
+			want:   "One Piece/Chapter 12.5_ Romance Dawn/7.webp",
+		},
+		{
+			name:   "file without a native name",
+			tmpl:   "{series}/{title}/{file}",
+			fields: func(f *namingFields) { f.File = "" },
+			want:   "One Piece/Chapter 12.5_ Romance Dawn/page_0007",
+		},
+		{
+			name:   "slash in a value stays in one folder",
+			tmpl:   "{series}/{title}/{page}.{ext}",
+			fields: func(f *namingFields) { f.Series = "Fate/Zero" },
+			want:   "Fate_Zero/Chapter 12.5_ Romance Dawn/7.webp",
+		},
+		{
+			name:   "bare field keeps its edges",
+			tmpl:   "{series}/{title}/{page}.{ext}",
+			fields: func(f *namingFields) { f.Series = "-Hitomi_" },
+			want:   "-Hitomi_/Chapter 12.5_ Romance Dawn/7.webp",
+		},
+		{
+			name:   "empty segment is skipped",
+			tmpl:   "{series}/{volume}/{title}/{page}.{ext}",
+			fields: func(f *namingFields) { f.Volume = "" },
+			want:   "One Piece/Chapter 12.5_ Romance Dawn/7.webp",
+		},
+		{name: "non-number is not padded", tmpl: "{series}/{title:000}/{page}.{ext}", want: "One Piece/Chapter 12.5_ Romance Dawn/7.webp"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			f := fields
+			if tc.fields != nil {
+				tc.fields(&f)
+			}
+			if got, want := renderTemplate(tc.tmpl, f), filepath.FromSlash(tc.want); got != want {
+				t.Errorf("renderTemplate(%q) = %q, want %q", tc.tmpl, got, want)
+			}
+		})
+	}
+}
+
+// fakeSeries records the chapters moved in Series, failing every move when err is set
+type fakeSeries struct {
+	err   error
+	moves [][2]string
+}
+
+func (s *fakeSeries) AddChapter(seriesPath string, chapterPath string) error { return nil }
+
+func (s *fakeSeries) MoveChapter(oldPath string, newPath string) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.moves = append(s.moves, [2]string{oldPath, newPath})
+	return nil
+}
+
+func TestRenameDownloadsToTemplate(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		seriesErr error
+	}{
+		{name: "records follow the files"},
+		{name: "failed Series update moves the files back", seriesErr: errors.New("disk full")},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			m := newTestModule(t, &fakeDownloader{prefix: "https://example.test/"})
+			series := &fakeSeries{err: tc.seriesErr}
+			m.SetSeriesModule(series)
+
+			base := t.TempDir()
+			oldPath := filepath.Join(base, "example.test", "Series", "Chapter 1")
+			if err := os.MkdirAll(oldPath, 0755); err != nil {
+				t.Fatal(err)
+			}
+			for _, name := range []string{"001.jpg", "002.jpg"} {
+				if err := os.WriteFile(filepath.Join(oldPath, name), []byte("page"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			job := persistence.DownloadJob{
+				ID: newJobID(), URL: "https://example.test/1", Site: "example.test", SeriesName: "Series",
+				ChapterName: "Chapter 1", Status: persistence.StatusCompleted, Path: oldPath, TotalPages: 2,
+			}
+			if err := m.pm.AddJob(job); err != nil {
+				t.Fatal(err)
+			}
+			m.ci.PutEntry(persistence.ContentEntry{Path: oldPath, Title: "chapter 1", Series: "series", Pages: 2})
+			if err := m.sm.Update(map[string]interface{}{"downloadPath": base, "downloadNamingTemplate": "{series}/{title}/{file}"}); err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := m.RenameDownloadsToTemplate()
+			if err != nil {
+				t.Fatal(err)
+			}
+			newPath := filepath.Join(base, "Series", "Chapter 1")
+			wantPath, gonePath := newPath, oldPath
+			if tc.seriesErr != nil {
+				if result.Renamed != 0 || len(result.Errors) != 1 {
+					t.Fatalf("result = %+v, want one error", result)
+				}
+				wantPath, gonePath = oldPath, newPath
+			} else if result.Renamed != 1 || len(result.Errors) != 0 {
+				t.Fatalf("result = %+v, want one renamed", result)
+			}
+
+			for _, name := range []string{"001.jpg", "002.jpg"} {
+				if _, err := os.Stat(filepath.Join(wantPath, name)); err != nil {
+					t.Errorf("page %s not in %s: %v", name, wantPath, err)
+				}
+			}
+			if _, err := os.Stat(gonePath); !os.IsNotExist(err) {
+				t.Errorf("%s still exists", gonePath)
+			}
+			if current, _ := m.findJob(job.ID); current.Path != wantPath {
+				t.Errorf("job path = %q, want %q", current.Path, wantPath)
+			}
+			indexed := map[string]bool{}
+			for _, entry := range m.ci.GetEntries() {
+				indexed[entry.Path] = true
+			}
+			if !indexed[wantPath] || indexed[gonePath] {
+				t.Errorf("content index has %v, want %s and not %s", indexed, wantPath, gonePath)
+			}
+			if tc.seriesErr == nil && (len(series.moves) != 1 || series.moves[0] != [2]string{oldPath, newPath}) {
+				t.Errorf("Series moves = %v, want %s to %s", series.moves, oldPath, newPath)
+			}
+		})
+	}
+}
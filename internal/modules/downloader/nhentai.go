@@ -9,7 +9,9 @@ import (
 	"strings"
 )
 
-type NHentaiDownloader struct{}
+type NHentaiDownloader struct {
+	withClients
+}
 
 type nhentaiData struct {
 	ID      json.Number `json:"id"`
@@ -37,7 +39,7 @@ func (d *NHentaiDownloader) GetImages(url string) (*SiteInfo, error) {
 	// Extract ID from URL if possible, though we mainly need to fetch the page
 	// URL example: https://nhentai.net/g/12345/
 
-	client := &http.Client{}
+	client := d.newHTTPClient()
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return nil, err
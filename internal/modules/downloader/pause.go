@@ -0,0 +1,162 @@
+package downloader
+
+import (
+	"errors"
+	"fmt"
+	"manga-visor/internal/persistence"
+)
+
+// PauseJob stops a running or queued job. The resolved image list is kept in memory,
+// so ResumeJob continues from the first missing page without scraping the site again.
+func (m *Module) PauseJob(id string) error {
+	m.queueLock.Lock()
+
+	// Running: ask runDownload to park the job once the pages in flight are done
+	if data, ok := m.activeJobs.Load(id); ok {
+		aj := data.(*activeJob)
+		aj.pausing = true
+		aj.resumeAfterPause = false
+		aj.pauseReason = ""
+		m.queueLock.Unlock()
+
+		aj.cancel()
+		fmt.Printf("[Downloader] Pausing active download: %s\n", id)
+		return nil
+	}
+
+	// Queued: move it out of the queue as is
+	for siteID, queue := range m.queues {
+		for i, qj := range queue {
+			if qj.job.ID != id {
+				continue
+			}
+			m.queues[siteID] = append(queue[:i:i], queue[i+1:]...)
+			m.paused[id] = qj
+			m.queueLock.Unlock()
+
+			fmt.Printf("[Downloader] Paused queued job: %s (site: %s)\n", id, siteID)
+			m.pm.UpdateJob(id, map[string]interface{}{"status": persistence.StatusPaused})
+			m.notifyUpdate()
+			return nil
+		}
+	}
+	m.queueLock.Unlock()
+
+	// Pending/running leftovers from a previous session have nothing to stop, just flag them
+	for _, job := range m.pm.GetJobs() {
+		if job.ID != id {
+			continue
+		}
+		if job.Status != persistence.StatusPending && job.Status != persistence.StatusScheduled && job.Status != persistence.StatusRunning {
+			return fmt.Errorf("job is not running (status: %s)", job.Status)
+		}
+		m.pm.UpdateJob(id, map[string]interface{}{"status": persistence.StatusPaused})
+		m.notifyUpdate()
+		return nil
+	}
+	return fmt.Errorf("job not found: %s", id)
+}
+
+// ResumeJob puts a paused job back in its site queue
+func (m *Module) ResumeJob(id string) error {
+	m.queueLock.Lock()
+
+	if qj, ok := m.paused[id]; ok {
+		delete(m.paused, id)
+		// Drops the reason of a pause by the disk checks, they run again on start
+		m.pm.UpdateJob(id, map[string]interface{}{"status": persistence.StatusPending, "error": ""})
+		m.enqueueLocked(qj.job, qj.info)
+		m.queueLock.Unlock()
+
+		fmt.Printf("[Downloader] Resumed paused job: %s\n", id)
+		m.notifyUpdate()
+		return nil
+	}
+
+	// Still winding down from PauseJob, runDownload will requeue it instead of parking it
+	if data, ok := m.activeJobs.Load(id); ok {
+		aj := data.(*activeJob)
+		if aj.pausing {
+			aj.resumeAfterPause = true
+		}
+		m.queueLock.Unlock()
+		return nil
+	}
+	m.queueLock.Unlock()
+
+	// Paused in a previous session, the image list has to be resolved again
+	for _, job := range m.pm.GetJobs() {
+		if job.ID != id {
+			continue
+		}
+		if job.Status != persistence.StatusPaused {
+			return fmt.Errorf("job is not paused (status: %s)", job.Status)
+		}
+		_, err := m.StartDownload(job.URL, job.SeriesName, job.ChapterName)
+		return err
+	}
+	return fmt.Errorf("job not found: %s", id)
+}
+
+// PauseAllJobs pauses every running and queued job
+func (m *Module) PauseAllJobs() error {
+	var errs []error
+	for _, job := range m.pm.GetJobs() {
+		if job.Status == persistence.StatusRunning || job.Status == persistence.StatusPending || job.Status == persistence.StatusScheduled {
+			if err := m.PauseJob(job.ID); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %v", job.ID, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ResumeAllJobs resumes every paused job, oldest first so they keep their queue order
+func (m *Module) ResumeAllJobs() error {
+	jobs := m.pm.GetJobs()
+	var errs []error
+	for i := len(jobs) - 1; i >= 0; i-- {
+		if jobs[i].Status == persistence.StatusPaused {
+			if err := m.ResumeJob(jobs[i].ID); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %v", jobs[i].ID, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// parkJob is called by runDownload when its context was cancelled. It returns false for a
+// plain cancel; for a pause it keeps the job (or requeues it if ResumeJob came in meanwhile).
+// A job removed while winding down is left alone and reported as handled.
+func (m *Module) parkJob(job persistence.DownloadJob, info *SiteInfo, aj *activeJob) bool {
+	m.queueLock.Lock()
+	defer m.queueLock.Unlock()
+
+	// RemoveJob drops the active entry before it clears the queues under this lock,
+	// so a job found here is still there for RemoveJob to clean up
+	if current, ok := m.activeJobs.Load(job.ID); !ok || current != aj {
+		return true
+	}
+	if _, ok := m.findJob(job.ID); !ok {
+		return true
+	}
+
+	if !aj.pausing {
+		return false
+	}
+
+	if aj.resumeAfterPause {
+		m.pm.UpdateJob(job.ID, map[string]interface{}{"status": persistence.StatusPending})
+		m.enqueueLocked(job, info)
+	} else {
+		m.paused[job.ID] = &queuedJob{job: job, info: info}
+		updates := map[string]interface{}{"status": persistence.StatusPaused}
+		if aj.pauseReason != "" {
+			updates["error"] = aj.pauseReason
+		}
+		m.pm.UpdateJob(job.ID, updates)
+		fmt.Printf("[Downloader] Paused job %s\n", job.ID)
+	}
+	m.notifyUpdate()
+	return true
+}
@@ -0,0 +1,72 @@
+package downloader
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"manga-visor/internal/persistence"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// StartPluginWatcher reloads the plugins whenever a script in the plugins folder changes,
+// so plugins can be edited without restarting the app
+func (m *Module) StartPluginWatcher() {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		fmt.Printf("[Downloader] Warning: Could not create plugin watcher: %v\n", err)
+		return
+	}
+	if err := watcher.Add(persistence.GetPluginsDir()); err != nil {
+		fmt.Printf("[Downloader] Warning: Could not watch plugins folder: %v\n", err)
+		watcher.Close()
+		return
+	}
+
+	go func() {
+		defer watcher.Close()
+
+		// Editors write a file in several steps, wait for them to settle before reloading
+		var reloadTimer *time.Timer
+		reloadDebounceDuration := 500 * time.Millisecond
+
+		for {
+			select {
+			case <-m.ctx.Done():
+				if reloadTimer != nil {
+					reloadTimer.Stop()
+				}
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if !strings.EqualFold(filepath.Ext(event.Name), ".js") {
+					continue
+				}
+				if event.Op&(fsnotify.Create|fsnotify.Write|fsnotify.Remove|fsnotify.Rename) == 0 {
+					continue
+				}
+
+				if reloadTimer != nil {
+					reloadTimer.Stop()
+				}
+				reloadTimer = time.AfterFunc(reloadDebounceDuration, func() {
+					fmt.Printf("[Downloader] Plugin change detected, reloading plugins\n")
+					if err := m.ReloadPlugins(); err != nil {
+						fmt.Printf("[Downloader] Some plugins failed to load: %v\n", err)
+					}
+					runtime.EventsEmit(m.ctx, "plugins_reloaded")
+				})
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				fmt.Printf("[Downloader] Plugin watcher error: %v\n", err)
+			}
+		}
+	}()
+}
@@ -0,0 +1,516 @@
+package downloader
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/andybalholm/cascadia"
+	"github.com/dop251/goja"
+	"golang.org/x/net/html"
+)
+
+const (
+	// Maximum time a plugin may spend in getImages (fetches included)
+	pluginCallTimeout = 2 * time.Minute
+	// canHandle runs for every pasted/clipboard URL, it has to be quick
+	pluginMatchTimeout = 1 * time.Second
+	// Limits for the fetch() API exposed to plugins
+	pluginFetchTimeout = 30 * time.Second
+	pluginFetchMaxBody = 20 << 20
+)
+
+// ScriptDownloader implements DownloaderInterface with a JavaScript plugin.
+// Plugins run in their own goja runtime without filesystem or process access;
+// the only way out is fetch(), limited to the hosts the plugin declares. See docs/PLUGINS.md.
+type ScriptDownloader struct {
+	withClients
+	id          string
+	name        string
+	source      string
+	hosts       []string
+	headers     map[string]string
+	delay       time.Duration
+	urlPatterns []*regexp.Regexp
+
+	vm        *goja.Runtime
+	canHandle goja.Callable
+	getImages goja.Callable
+	// Held while the runtime runs, goja runtimes are not goroutine safe.
+	// A channel so canHandle can give up waiting.
+	busy chan struct{}
+}
+
+// scriptSiteInfo is the object returned by a plugin's getImages
+type scriptSiteInfo struct {
+	SeriesName      string `json:"seriesName"`
+	ChapterName     string `json:"chapterName"`
+	Type            string `json:"type"`
+	DownloadDelayMs int    `json:"downloadDelayMs"`
+	Images          []struct {
+		URL      string            `json:"url"`
+		Filename string            `json:"filename"`
+		Headers  map[string]string `json:"headers"`
+	} `json:"images"`
+	Chapters []struct {
+		ID        string `json:"id"`
+		Name      string `json:"name"`
+		URL       string `json:"url"`
+		Date      string `json:"date"`
+		ScanGroup string `json:"scanGroup"`
+		Language  string `json:"language"`
+		Number    string `json:"number"`
+	} `json:"chapters"`
+}
+
+// LoadPlugins loads every *.js plugin in dir.
+// Like scraper definitions, a broken plugin is reported and skipped.
+func LoadPlugins(dir string) ([]*ScriptDownloader, []error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.js"))
+	if err != nil {
+		return nil, []error{err}
+	}
+
+	var loaded []*ScriptDownloader
+	var errs []error
+	for _, file := range files {
+		code, err := os.ReadFile(file)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", filepath.Base(file), err))
+			continue
+		}
+
+		d, err := NewScriptDownloader(file, string(code))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", filepath.Base(file), err))
+			continue
+		}
+		loaded = append(loaded, d)
+	}
+	return loaded, errs
+}
+
+// NewScriptDownloader runs a plugin script and reads the `plugin` object it defines
+func NewScriptDownloader(source string, code string) (*ScriptDownloader, error) {
+	d := &ScriptDownloader{
+		source: source,
+		vm:     goja.New(),
+		busy:   make(chan struct{}, 1),
+	}
+	d.installAPI()
+
+	if _, err := d.run(pluginCallTimeout, func() (goja.Value, error) {
+		return d.vm.RunScript(filepath.Base(source), code)
+	}); err != nil {
+		return nil, err
+	}
+
+	obj := d.vm.Get("plugin")
+	if obj == nil || goja.IsUndefined(obj) || goja.IsNull(obj) {
+		return nil, fmt.Errorf("script does not define a plugin object")
+	}
+	plugin := obj.ToObject(d.vm)
+
+	var meta struct {
+		ID              string            `json:"id"`
+		Name            string            `json:"name"`
+		Hosts           []string          `json:"hosts"`
+		URLPatterns     []string          `json:"urlPatterns"`
+		Headers         map[string]string `json:"headers"`
+		DownloadDelayMs int               `json:"downloadDelayMs"`
+	}
+	if err := d.exportJSON(plugin, &meta); err != nil {
+		return nil, fmt.Errorf("invalid plugin object: %v", err)
+	}
+	if meta.ID == "" {
+		return nil, fmt.Errorf("missing id")
+	}
+
+	d.id = meta.ID
+	d.name = meta.Name
+	d.headers = meta.Headers
+	d.delay = time.Duration(meta.DownloadDelayMs) * time.Millisecond
+	for _, h := range meta.Hosts {
+		d.hosts = append(d.hosts, strings.ToLower(strings.TrimPrefix(h, ".")))
+	}
+	for _, p := range meta.URLPatterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid urlPattern %q: %v", p, err)
+		}
+		d.urlPatterns = append(d.urlPatterns, re)
+	}
+
+	var ok bool
+	if d.getImages, ok = goja.AssertFunction(plugin.Get("getImages")); !ok {
+		return nil, fmt.Errorf("missing getImages function")
+	}
+	d.canHandle, _ = goja.AssertFunction(plugin.Get("canHandle"))
+	if d.canHandle == nil && len(d.urlPatterns) == 0 {
+		return nil, fmt.Errorf("plugin needs urlPatterns or a canHandle function")
+	}
+
+	return d, nil
+}
+
+func (d *ScriptDownloader) GetSiteID() string {
+	return d.id
+}
+
+// Source returns the file the plugin was loaded from
+func (d *ScriptDownloader) Source() string {
+	return d.source
+}
+
+func (d *ScriptDownloader) CanHandle(url string) bool {
+	// urlPatterns are checked in Go, canHandle is only consulted when there are none
+	if len(d.urlPatterns) > 0 {
+		for _, re := range d.urlPatterns {
+			if re.MatchString(url) {
+				return true
+			}
+		}
+		return false
+	}
+
+	// The whole call, waiting for a getImages in progress included, fits in pluginMatchTimeout.
+	// Past it the answer is no, and the runtime finishes on its own.
+	timeout := time.NewTimer(pluginMatchTimeout)
+	defer timeout.Stop()
+	select {
+	case d.busy <- struct{}{}:
+	case <-timeout.C:
+		fmt.Printf("[Plugin %s] canHandle skipped, the plugin is busy\n", d.id)
+		return false
+	}
+
+	result := make(chan bool, 1)
+	go func() {
+		defer func() { <-d.busy }()
+		v, err := d.run(pluginMatchTimeout, func() (goja.Value, error) {
+			return d.canHandle(goja.Undefined(), d.vm.ToValue(url))
+		})
+		if err != nil {
+			fmt.Printf("[Plugin %s] canHandle failed: %v\n", d.id, err)
+			result <- false
+			return
+		}
+		result <- v.ToBoolean()
+	}()
+
+	select {
+	case ok := <-result:
+		return ok
+	case <-timeout.C:
+		fmt.Printf("[Plugin %s] canHandle timed out\n", d.id)
+		return false
+	}
+}
+
+func (d *ScriptDownloader) GetImages(url string) (*SiteInfo, error) {
+	d.busy <- struct{}{}
+	defer func() { <-d.busy }()
+
+	v, err := d.run(pluginCallTimeout, func() (goja.Value, error) {
+		return d.getImages(goja.Undefined(), d.vm.ToValue(url))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var result scriptSiteInfo
+	if err := d.exportJSON(v, &result); err != nil {
+		return nil, fmt.Errorf("invalid getImages result: %v", err)
+	}
+
+	info := &SiteInfo{
+		SeriesName:    result.SeriesName,
+		ChapterName:   result.ChapterName,
+		SiteID:        d.id,
+		Type:          result.Type,
+		DownloadDelay: d.delay,
+	}
+	if result.DownloadDelayMs > 0 {
+		info.DownloadDelay = time.Duration(result.DownloadDelayMs) * time.Millisecond
+	}
+	if info.SeriesName == "" {
+		info.SeriesName = "Unknown"
+	}
+
+	for _, c := range result.Chapters {
+		if c.URL == "" {
+			continue
+		}
+		id := c.ID
+		if id == "" {
+			id = c.URL
+		}
+		info.Chapters = append(info.Chapters, ChapterInfo{
+			ID:        id,
+			Name:      c.Name,
+			URL:       c.URL,
+			Date:      c.Date,
+			ScanGroup: c.ScanGroup,
+			Language:  c.Language,
+			Number:    c.Number,
+		})
+	}
+	if info.Type == "" && len(info.Chapters) > 0 {
+		info.Type = "series"
+	}
+
+	for _, img := range result.Images {
+		if img.URL == "" {
+			continue
+		}
+		filename := img.Filename
+		if filename == "" {
+			ext := strings.TrimPrefix(strings.ToLower(path.Ext(urlPath(img.URL))), ".")
+			if ext == "" || len(ext) > 4 {
+				ext = "jpg"
+			}
+			filename = fmt.Sprintf("%03d.%s", len(info.Images)+1, ext)
+		}
+
+		headers := make(map[string]string)
+		for k, v := range d.defaultHeaders() {
+			headers[k] = v
+		}
+		for k, v := range img.Headers {
+			headers[k] = v
+		}
+
+		info.Images = append(info.Images, ImageDownload{
+			URL:      img.URL,
+			Filename: filename,
+			Index:    len(info.Images),
+			Headers:  headers,
+		})
+	}
+
+	if info.Type != "series" && len(info.Images) == 0 {
+		return nil, fmt.Errorf("no images found")
+	}
+
+	return info, nil
+}
+
+// run executes fn, interrupting the script if it takes longer than timeout
+func (d *ScriptDownloader) run(timeout time.Duration, fn func() (goja.Value, error)) (goja.Value, error) {
+	timer := time.AfterFunc(timeout, func() {
+		d.vm.Interrupt(fmt.Sprintf("plugin timed out after %v", timeout))
+	})
+	defer func() {
+		timer.Stop()
+		d.vm.ClearInterrupt()
+	}()
+
+	v, err := fn()
+	if err != nil {
+		if jsErr, ok := err.(*goja.Exception); ok {
+			return nil, fmt.Errorf("plugin error: %s", jsErr.Error())
+		}
+		return nil, err
+	}
+	return v, nil
+}
+
+func (d *ScriptDownloader) defaultHeaders() map[string]string {
+	headers := map[string]string{
+		"User-Agent": "Mozilla/5.0 (Windows NT 10.0; Win64; x64) Chrome/120.0.0.0",
+	}
+	for k, v := range d.headers {
+		headers[k] = v
+	}
+	return headers
+}
+
+// allowedHost reports whether the plugin declared host (or a parent domain of it)
+func (d *ScriptDownloader) allowedHost(u *neturl.URL) bool {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, h := range d.hosts {
+		if host == h || strings.HasSuffix(host, "."+h) {
+			return true
+		}
+	}
+	return false
+}
+
+// installAPI exposes the globals available to plugins: fetch, html, resolveUrl and log
+func (d *ScriptDownloader) installAPI() {
+	vm := d.vm
+	vm.Set("fetch", d.jsFetch)
+	vm.Set("resolveUrl", resolveURL)
+	vm.Set("log", func(call goja.FunctionCall) goja.Value {
+		parts := make([]string, len(call.Arguments))
+		for i, arg := range call.Arguments {
+			parts[i] = arg.String()
+		}
+		fmt.Printf("[Plugin %s] %s\n", d.id, strings.Join(parts, " "))
+		return goja.Undefined()
+	})
+
+	htmlAPI := vm.NewObject()
+	htmlAPI.Set("parse", func(text string) (goja.Value, error) {
+		doc, err := html.Parse(strings.NewReader(text))
+		if err != nil {
+			return nil, err
+		}
+		return d.wrapNode(doc), nil
+	})
+	vm.Set("html", htmlAPI)
+}
+
+// jsFetch implements fetch(url, {method, headers, body}) for plugins.
+// It is synchronous and returns {status, ok, url, text, headers}.
+func (d *ScriptDownloader) jsFetch(call goja.FunctionCall) goja.Value {
+	vm := d.vm
+	rawURL := call.Argument(0).String()
+
+	var opts struct {
+		Method  string            `json:"method"`
+		Headers map[string]string `json:"headers"`
+		Body    string            `json:"body"`
+	}
+	if arg := call.Argument(1); !goja.IsUndefined(arg) && !goja.IsNull(arg) {
+		if err := d.exportJSON(arg, &opts); err != nil {
+			panic(vm.NewTypeError("fetch: invalid options: %v", err))
+		}
+	}
+	if opts.Method == "" {
+		opts.Method = "GET"
+	}
+
+	u, err := neturl.Parse(rawURL)
+	if err != nil || !d.allowedHost(u) {
+		panic(vm.NewGoError(fmt.Errorf("fetch: %s is not in the plugin hosts", rawURL)))
+	}
+
+	var body io.Reader
+	if opts.Body != "" {
+		body = bytes.NewBufferString(opts.Body)
+	}
+	req, err := http.NewRequest(strings.ToUpper(opts.Method), u.String(), body)
+	if err != nil {
+		panic(vm.NewGoError(err))
+	}
+	for k, v := range d.defaultHeaders() {
+		req.Header.Set(k, v)
+	}
+	for k, v := range opts.Headers {
+		req.Header.Set(k, v)
+	}
+
+	client := d.newHTTPClient()
+	client.Timeout = pluginFetchTimeout
+	client.CheckRedirect = func(r *http.Request, via []*http.Request) error {
+		if len(via) >= 10 {
+			return fmt.Errorf("too many redirects")
+		}
+		if !d.allowedHost(r.URL) {
+			return fmt.Errorf("redirect to %s is not in the plugin hosts", r.URL.Host)
+		}
+		return nil
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		panic(vm.NewGoError(fmt.Errorf("fetch: %v", err)))
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, pluginFetchMaxBody+1))
+	if err != nil {
+		panic(vm.NewGoError(fmt.Errorf("fetch: %v", err)))
+	}
+	if len(data) > pluginFetchMaxBody {
+		panic(vm.NewGoError(fmt.Errorf("fetch: response larger than %d bytes", pluginFetchMaxBody)))
+	}
+
+	headers := make(map[string]interface{})
+	for k := range resp.Header {
+		headers[strings.ToLower(k)] = resp.Header.Get(k)
+	}
+
+	result := vm.NewObject()
+	result.Set("status", resp.StatusCode)
+	result.Set("ok", resp.StatusCode >= 200 && resp.StatusCode < 300)
+	result.Set("url", resp.Request.URL.String())
+	result.Set("text", string(data))
+	result.Set("headers", headers)
+	return result
+}
+
+// wrapNode exposes an html node to scripts as {select, first, text, attr, html}
+func (d *ScriptDownloader) wrapNode(n *html.Node) goja.Value {
+	vm := d.vm
+	obj := vm.NewObject()
+
+	compile := func(css string) cascadia.Sel {
+		sel, err := cascadia.Parse(css)
+		if err != nil {
+			panic(vm.NewTypeError("invalid selector %q: %v", css, err))
+		}
+		return sel
+	}
+
+	obj.Set("select", func(css string) []goja.Value {
+		nodes := cascadia.QueryAll(n, compile(css))
+		wrapped := make([]goja.Value, len(nodes))
+		for i, child := range nodes {
+			wrapped[i] = d.wrapNode(child)
+		}
+		return wrapped
+	})
+	obj.Set("first", func(css string) goja.Value {
+		if child := cascadia.Query(n, compile(css)); child != nil {
+			return d.wrapNode(child)
+		}
+		return goja.Null()
+	})
+	obj.Set("text", func() string {
+		return strings.Join(strings.Fields(nodeText(n)), " ")
+	})
+	obj.Set("attr", func(key string) goja.Value {
+		for _, attr := range n.Attr {
+			if attr.Key == key {
+				return vm.ToValue(attr.Val)
+			}
+		}
+		return goja.Null()
+	})
+	obj.Set("html", func() string {
+		var buf bytes.Buffer
+		html.Render(&buf, n)
+		return buf.String()
+	})
+	return obj
+}
+
+// exportJSON converts a script value into a Go struct through JSON.stringify,
+// which drops functions and other values that have no JSON form
+func (d *ScriptDownloader) exportJSON(v goja.Value, target interface{}) error {
+	stringify, ok := goja.AssertFunction(d.vm.Get("JSON").ToObject(d.vm).Get("stringify"))
+	if !ok {
+		return fmt.Errorf("JSON.stringify is not available")
+	}
+	text, err := stringify(goja.Undefined(), v)
+	if err != nil {
+		return err
+	}
+	if goja.IsUndefined(text) {
+		return fmt.Errorf("value has no JSON form")
+	}
+	return json.Unmarshal([]byte(text.String()), target)
+}
@@ -0,0 +1,159 @@
+package downloader
+
+import (
+	"fmt"
+	"manga-visor/internal/persistence"
+	"sort"
+)
+
+// queueLess orders queued jobs: higher priority first, then by queue order
+func queueLess(a, b persistence.DownloadJob) bool {
+	if a.Priority != b.Priority {
+		return a.Priority > b.Priority
+	}
+	return a.QueueOrder < b.QueueOrder
+}
+
+// insertQueuedLocked adds a job to its site queue, keeping it sorted so the next job
+// to start is always at the front. Caller must hold queueLock.
+func (m *Module) insertQueuedLocked(qj *queuedJob) {
+	queue := m.queues[qj.job.Site]
+	i := sort.Search(len(queue), func(i int) bool { return queueLess(qj.job, queue[i].job) })
+	queue = append(queue, nil)
+	copy(queue[i+1:], queue[i:])
+	queue[i] = qj
+	m.queues[qj.job.Site] = queue
+}
+
+// findQueuedLocked returns the site and index of a queued job. Caller must hold queueLock.
+func (m *Module) findQueuedLocked(id string) (string, int, bool) {
+	for siteID, queue := range m.queues {
+		for i, qj := range queue {
+			if qj.job.ID == id {
+				return siteID, i, true
+			}
+		}
+	}
+	return "", 0, false
+}
+
+// MoveJob moves a queued job to a position (0 = next to start) in its site queue.
+// The job takes the priority of the jobs around its new place, so it stays there.
+func (m *Module) MoveJob(id string, position int) error {
+	m.queueLock.Lock()
+	siteID, index, ok := m.findQueuedLocked(id)
+	if !ok {
+		m.queueLock.Unlock()
+		return fmt.Errorf("job is not queued: %s", id)
+	}
+
+	queue := m.queues[siteID]
+	qj := queue[index]
+	queue = append(queue[:index:index], queue[index+1:]...)
+	if position < 0 {
+		position = 0
+	}
+	if position > len(queue) {
+		position = len(queue)
+	}
+	queue = append(queue[:position], append([]*queuedJob{qj}, queue[position:]...)...)
+
+	// Ahead of a job: same priority as it, at the end: same as the one before
+	if position+1 < len(queue) {
+		qj.job.Priority = queue[position+1].job.Priority
+	} else if position > 0 {
+		qj.job.Priority = queue[position-1].job.Priority
+	}
+	m.queues[siteID] = queue
+	m.renumberQueueLocked(siteID)
+	m.pm.UpdateJob(id, map[string]interface{}{"priority": qj.job.Priority})
+	m.queueLock.Unlock()
+
+	fmt.Printf("[Downloader] Moved job %s to position %d (site: %s)\n", id, position, siteID)
+	m.notifyUpdate()
+	return nil
+}
+
+// PrioritizeJob puts a job ahead of everything else queued for its site. A paused job
+// keeps the priority for when it's resumed.
+func (m *Module) PrioritizeJob(id string) error {
+	m.queueLock.Lock()
+	siteID, _, queued := m.findQueuedLocked(id)
+	if !queued {
+		qj, paused := m.paused[id]
+		if !paused {
+			m.queueLock.Unlock()
+			return fmt.Errorf("job is not queued: %s", id)
+		}
+		siteID = qj.job.Site
+	}
+
+	top := 0
+	for _, other := range m.queues[siteID] {
+		if other.job.ID != id && other.job.Priority > top {
+			top = other.job.Priority
+		}
+	}
+	m.queueLock.Unlock()
+
+	return m.SetJobPriority(id, top+1)
+}
+
+// SetJobPriority changes the priority of a job, reordering the queue if it's waiting in it
+func (m *Module) SetJobPriority(id string, priority int) error {
+	if _, ok := m.findJob(id); !ok {
+		return fmt.Errorf("job not found: %s", id)
+	}
+
+	m.queueLock.Lock()
+	if siteID, index, ok := m.findQueuedLocked(id); ok {
+		queue := m.queues[siteID]
+		qj := queue[index]
+		m.queues[siteID] = append(queue[:index:index], queue[index+1:]...)
+		qj.job.Priority = priority
+		m.insertQueuedLocked(qj)
+	} else if qj, ok := m.paused[id]; ok {
+		qj.job.Priority = priority
+	}
+	m.pm.UpdateJob(id, map[string]interface{}{"priority": priority})
+	m.queueLock.Unlock()
+
+	m.notifyUpdate()
+	return nil
+}
+
+// renumberQueueLocked stores the current order of a site queue, starting from its lowest
+// queue order so jobs queued later still go after. Caller must hold queueLock.
+func (m *Module) renumberQueueLocked(siteID string) {
+	queue := m.queues[siteID]
+	if len(queue) == 0 {
+		return
+	}
+	base := queue[0].job.QueueOrder
+	for _, qj := range queue {
+		if qj.job.QueueOrder < base {
+			base = qj.job.QueueOrder
+		}
+	}
+	for i, qj := range queue {
+		order := base + int64(i)
+		if qj.job.QueueOrder != order {
+			qj.job.QueueOrder = order
+			m.pm.UpdateJob(qj.job.ID, map[string]interface{}{"queueOrder": order})
+		}
+	}
+}
+
+// GetQueuePositions returns the position of every queued job in its site queue (0 = next)
+func (m *Module) GetQueuePositions() map[string]int {
+	m.queueLock.Lock()
+	defer m.queueLock.Unlock()
+
+	positions := make(map[string]int)
+	for _, queue := range m.queues {
+		for i, qj := range queue {
+			positions[qj.job.ID] = i
+		}
+	}
+	return positions
+}
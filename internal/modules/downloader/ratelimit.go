@@ -0,0 +1,161 @@
+package downloader
+
+import (
+	"context"
+	"manga-visor/internal/persistence"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultSiteLimit applies when the settings have no entry for a site, not even "default"
+var defaultSiteLimit = persistence.SiteLimit{MaxJobs: 3, PageConcurrency: 2, MaxRetries: 3, BackoffMs: 2000}
+
+// siteLimitFor returns the limits configured for a site ID
+func siteLimitFor(limits map[string]persistence.SiteLimit, siteID string) persistence.SiteLimit {
+	if l, ok := limits[siteID]; ok {
+		return l
+	}
+	if l, ok := limits["default"]; ok {
+		return l
+	}
+	return defaultSiteLimit
+}
+
+// siteOptionsFor returns the options configured for a site ID, falling back to "default"
+func siteOptionsFor(options map[string]persistence.SiteOptions, siteID string) persistence.SiteOptions {
+	if o, ok := options[siteID]; ok {
+		return o
+	}
+	return options["default"]
+}
+
+// packageCBZEnabled tells whether completed chapters of a site are packaged: the site's
+// own choice when it has one, the global setting otherwise
+func packageCBZEnabled(settings *persistence.Settings, siteID string) bool {
+	if o, ok := settings.SiteOptions[siteID]; ok && o.PackageCBZ != nil {
+		return *o.PackageCBZ
+	}
+	return settings.PackageCBZ
+}
+
+// siteLimitForHost returns the limits of the site a host belongs to.
+// The longest matching suffix wins, so "api.mangadex.org" can have its own entry.
+func siteLimitForHost(limits map[string]persistence.SiteLimit, host string) persistence.SiteLimit {
+	host = strings.ToLower(host)
+	best, bestLen := "", 0
+	for siteID, l := range limits {
+		for _, suffix := range append([]string{siteID}, l.Hosts...) {
+			suffix = strings.ToLower(strings.TrimPrefix(suffix, "."))
+			if (host == suffix || strings.HasSuffix(host, "."+suffix)) && len(suffix) > bestLen {
+				best, bestLen = siteID, len(suffix)
+			}
+		}
+	}
+	return siteLimitFor(limits, best)
+}
+
+// tokenBucket paces the requests to one host
+type tokenBucket struct {
+	mu           sync.Mutex
+	tokens       float64
+	last         time.Time
+	blockedUntil time.Time // set when the host answers 429
+}
+
+// wait blocks until a request may be sent at rps requests per second (0 = unlimited)
+func (b *tokenBucket) wait(ctx context.Context, rps float64) error {
+	for {
+		b.mu.Lock()
+		now := time.Now()
+		var delay time.Duration
+		switch {
+		case now.Before(b.blockedUntil):
+			delay = b.blockedUntil.Sub(now)
+		case rps <= 0:
+			b.mu.Unlock()
+			return nil
+		default:
+			// Bursts of up to one second worth of requests
+			burst := math.Max(1, rps)
+			b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*rps)
+			b.last = now
+			if b.tokens >= 1 {
+				b.tokens--
+				b.mu.Unlock()
+				return nil
+			}
+			delay = time.Duration((1 - b.tokens) / rps * float64(time.Second))
+		}
+		b.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// block holds back every request to the host for d
+func (b *tokenBucket) block(d time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if until := time.Now().Add(d); until.After(b.blockedUntil) {
+		b.blockedUntil = until
+	}
+}
+
+// hostLimiter keeps one token bucket per host, shared by every request of a clientFactory
+type hostLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+func (l *hostLimiter) bucket(host string) *tokenBucket {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[host]
+	if !ok {
+		b = &tokenBucket{}
+		l.buckets[host] = b
+	}
+	return b
+}
+
+// limitedTransport waits for the host's token bucket before every request
+type limitedTransport struct {
+	base    http.RoundTripper
+	clients *clientFactory
+}
+
+func (t *limitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := strings.ToLower(req.URL.Hostname())
+	var limits map[string]persistence.SiteLimit
+	if settings := t.clients.currentSettings(); settings != nil {
+		limits = settings.SiteLimits
+	}
+	b, limit := t.clients.limiter.bucket(host), siteLimitForHost(limits, host)
+	if err := b.wait(req.Context(), limit.RequestsPerSecond); err != nil {
+		return nil, err
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err == nil && resp.StatusCode == http.StatusTooManyRequests {
+		// Back off the whole host, not just this request
+		b.block(retryAfter(resp, time.Duration(limit.BackoffMs)*time.Millisecond))
+	}
+	return resp, err
+}
+
+// retryAfter reads the Retry-After header (seconds), fallback when missing
+func retryAfter(resp *http.Response, fallback time.Duration) time.Duration {
+	if secs, err := strconv.Atoi(strings.TrimSpace(resp.Header.Get("Retry-After"))); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	return fallback
+}
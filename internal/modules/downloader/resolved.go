@@ -0,0 +1,108 @@
+package downloader
+
+import (
+	"fmt"
+	"manga-visor/internal/persistence"
+	"sync"
+	"time"
+)
+
+// storedImages converts a resolved page list for persistence
+func storedImages(images []ImageDownload) []persistence.DownloadImage {
+	stored := make([]persistence.DownloadImage, len(images))
+	for i, img := range images {
+		stored[i] = persistence.DownloadImage{
+			URL:      img.URL,
+			Filename: img.Filename,
+			Index:    img.Index,
+			Headers:  img.Headers,
+		}
+	}
+	return stored
+}
+
+// siteInfoFromJob rebuilds the SiteInfo of a job from its stored page list
+func siteInfoFromJob(job persistence.DownloadJob, stored []persistence.DownloadImage) *SiteInfo {
+	images := make([]ImageDownload, len(stored))
+	for i, img := range stored {
+		images[i] = ImageDownload{
+			URL:      img.URL,
+			Filename: img.Filename,
+			Index:    img.Index,
+			Headers:  img.Headers,
+		}
+	}
+	return &SiteInfo{
+		SeriesName:    job.SeriesName,
+		ChapterName:   job.ChapterName,
+		Images:        images,
+		SiteID:        job.Site,
+		DownloadDelay: time.Duration(job.DownloadDelayMs) * time.Millisecond,
+		Type:          "single",
+	}
+}
+
+// imageRefresher resolves a job's pages again when stored URLs have expired
+// (rotated hitomi subdomains, ZonaTMO tokens...). The site is scraped at most once per run.
+type imageRefresher struct {
+	m      *Module
+	job    persistence.DownloadJob
+	total  int
+	mu     sync.Mutex
+	done   bool
+	images []ImageDownload
+	err    error
+}
+
+func newImageRefresher(m *Module, job persistence.DownloadJob, total int) *imageRefresher {
+	return &imageRefresher{m: m, job: job, total: total}
+}
+
+// latest returns the refreshed page at index if the list was resolved again, img otherwise
+func (r *imageRefresher) latest(index int, img ImageDownload) ImageDownload {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.done && r.err == nil {
+		return r.images[index]
+	}
+	return img
+}
+
+// refresh resolves the page list again (once) and returns the new page at index
+func (r *imageRefresher) refresh(index int) (ImageDownload, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.done {
+		r.done = true
+		r.images, r.err = r.resolve()
+	}
+	if r.err != nil {
+		return ImageDownload{}, r.err
+	}
+	return r.images[index], nil
+}
+
+func (r *imageRefresher) resolve() ([]ImageDownload, error) {
+	fmt.Printf("[Downloader] Stored page URLs expired for job %s, resolving again\n", r.job.ID)
+
+	algo := r.m.findAlgorithm(r.job.URL)
+	if algo == nil {
+		return nil, fmt.Errorf("no algorithm found for this URL")
+	}
+	info, err := algo.GetImages(r.job.URL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve pages again: %v", err)
+	}
+	// Pages are matched by position, a different count means the chapter changed
+	if len(info.Images) != r.total {
+		return nil, fmt.Errorf("page count changed from %d to %d", r.total, len(info.Images))
+	}
+
+	r.m.pm.UpdateJob(r.job.ID, map[string]interface{}{
+		"images":     storedImages(info.Images),
+		"resolvedAt": time.Now().Format(time.RFC3339),
+	})
+	return info.Images, nil
+}
@@ -0,0 +1,65 @@
+package downloader
+
+import (
+	"fmt"
+	"manga-visor/internal/persistence"
+	"time"
+)
+
+// RetryFailedPages resolves the chapter again and downloads only the pages that are
+// still missing. Pages already on disk are verified and skipped by runDownload.
+func (m *Module) RetryFailedPages(id string) error {
+	var job *persistence.DownloadJob
+	for _, j := range m.pm.GetJobs() {
+		if j.ID == id {
+			temp := j
+			job = &temp
+			break
+		}
+	}
+	if job == nil {
+		return fmt.Errorf("job not found: %s", id)
+	}
+	if job.Status != persistence.StatusCompletedWithErrors && job.Status != persistence.StatusFailed {
+		return fmt.Errorf("job has no failed pages (status: %s)", job.Status)
+	}
+	if _, isActive := m.activeJobs.Load(id); isActive {
+		return fmt.Errorf("job is already running")
+	}
+
+	algo := m.findAlgorithm(job.URL)
+	if algo == nil {
+		return fmt.Errorf("no algorithm found for this URL")
+	}
+
+	// Failed pages are often expired URLs, so the stored list is not reused
+	info, err := algo.GetImages(job.URL)
+	if err != nil {
+		return err
+	}
+	if info.Type == "series" {
+		return fmt.Errorf("this is a series URL, use FetchMangaInfo to select chapters")
+	}
+
+	// Keep writing into the same folder
+	info.SiteID = job.Site
+	info.SeriesName = job.SeriesName
+	info.ChapterName = job.ChapterName
+
+	fmt.Printf("[Downloader] Retrying %d failed pages of job %s\n", len(job.FailedPages), id)
+	job.Status = persistence.StatusPending
+	m.pm.UpdateJob(id, map[string]interface{}{
+		"status":          persistence.StatusPending,
+		"error":           "",
+		"images":          storedImages(info.Images),
+		"resolvedAt":      time.Now().Format(time.RFC3339),
+		"downloadDelayMs": int(info.DownloadDelay / time.Millisecond),
+		"totalPages":      len(info.Images),
+	})
+	m.notifyUpdate()
+
+	m.queueLock.Lock()
+	m.enqueueLocked(*job, info)
+	m.queueLock.Unlock()
+	return nil
+}
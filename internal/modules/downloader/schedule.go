@@ -0,0 +1,214 @@
+package downloader
+
+import (
+	"fmt"
+	"manga-visor/internal/persistence"
+	"sort"
+	"time"
+)
+
+// scheduleCheckInterval is how often the scheduler looks whether a download window opened or closed
+const scheduleCheckInterval = 30 * time.Second
+
+// ScheduleStatus tells the UI whether downloads may start now and when that changes
+type ScheduleStatus struct {
+	// False when no valid window is configured
+	Enabled bool `json:"enabled"`
+	Open    bool `json:"open"`
+	// RFC3339, empty when it never changes
+	NextChange string `json:"nextChange,omitempty"`
+}
+
+// parseClock parses "HH:MM" into minutes since midnight
+func parseClock(value string) (int, bool) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, false
+	}
+	return t.Hour()*60 + t.Minute(), true
+}
+
+func windowHasDay(w persistence.DownloadWindow, day time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+	for _, d := range w.Days {
+		if d == int(day) {
+			return true
+		}
+	}
+	return false
+}
+
+// validWindows drops the windows with unparseable times
+func validWindows(windows []persistence.DownloadWindow) []persistence.DownloadWindow {
+	var valid []persistence.DownloadWindow
+	for _, w := range windows {
+		_, okStart := parseClock(w.Start)
+		_, okEnd := parseClock(w.End)
+		if okStart && okEnd {
+			valid = append(valid, w)
+		}
+	}
+	return valid
+}
+
+// windowOpen reports whether downloads may run at the given time. Without valid windows
+// they always may. A window with the same start and end covers its whole day.
+func windowOpen(windows []persistence.DownloadWindow, now time.Time) bool {
+	windows = validWindows(windows)
+	if len(windows) == 0 {
+		return true
+	}
+	minute := now.Hour()*60 + now.Minute()
+	yesterday := now.AddDate(0, 0, -1).Weekday()
+
+	for _, w := range windows {
+		start, _ := parseClock(w.Start)
+		end, _ := parseClock(w.End)
+		switch {
+		case start == end:
+			if windowHasDay(w, now.Weekday()) {
+				return true
+			}
+		case start < end:
+			if windowHasDay(w, now.Weekday()) && minute >= start && minute < end {
+				return true
+			}
+		default:
+			// Past midnight: the evening part belongs to today, the morning part to yesterday
+			if windowHasDay(w, now.Weekday()) && minute >= start {
+				return true
+			}
+			if windowHasDay(w, yesterday) && minute < end {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// nextWindowChange returns when windowOpen next flips, looking up to a week ahead. It can
+// only flip where a window starts or ends or at midnight (day filters), so only those are checked.
+func nextWindowChange(windows []persistence.DownloadWindow, now time.Time) (time.Time, bool) {
+	windows = validWindows(windows)
+	current := windowOpen(windows, now)
+
+	var boundaries []time.Time
+	for day := 0; day <= 8; day++ {
+		// Wall clock times, so days with a DST change still get the right ones
+		at := func(minute int) time.Time {
+			return time.Date(now.Year(), now.Month(), now.Day()+day, minute/60, minute%60, 0, 0, now.Location())
+		}
+		boundaries = append(boundaries, at(0))
+		for _, w := range windows {
+			start, _ := parseClock(w.Start)
+			end, _ := parseClock(w.End)
+			boundaries = append(boundaries, at(start), at(end))
+		}
+	}
+	sort.Slice(boundaries, func(i, j int) bool { return boundaries[i].Before(boundaries[j]) })
+
+	for _, t := range boundaries {
+		if t.After(now) && windowOpen(windows, t) != current {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// downloadWindowOpen reports whether queued jobs may start right now
+func (m *Module) downloadWindowOpen() bool {
+	return windowOpen(m.sm.Get().DownloadSchedule, time.Now())
+}
+
+// GetScheduleStatus returns the state of the download schedule
+func (m *Module) GetScheduleStatus() ScheduleStatus {
+	windows := validWindows(m.sm.Get().DownloadSchedule)
+	now := time.Now()
+	status := ScheduleStatus{Enabled: len(windows) > 0, Open: windowOpen(windows, now)}
+	if next, ok := nextWindowChange(windows, now); ok {
+		status.NextChange = next.Format(time.RFC3339)
+	}
+	return status
+}
+
+// startQueuedLocked starts queued jobs of a site while it has free slots and the
+// download window is open. Caller must hold queueLock.
+func (m *Module) startQueuedLocked(siteID string) {
+	if !m.downloadWindowOpen() {
+		return
+	}
+	// The limit may have been raised meanwhile
+	limit := m.siteLimit(siteID).MaxJobs
+	for len(m.queues[siteID]) > 0 && (limit == 0 || m.activeCounts[siteID] < limit) {
+		// Pop first
+		queue := m.queues[siteID]
+		next := queue[0]
+		m.queues[siteID] = queue[1:]
+
+		// Start it
+		m.activeCounts[siteID]++
+		fmt.Printf("[Downloader] Starting queued job %s for site %s\n", next.job.ID, siteID)
+		go m.runDownload(next.job, next.info)
+	}
+}
+
+// StartScheduler moves queued jobs between pending and scheduled as the download
+// windows open and close, starting them when a window opens. Jobs already running when a
+// window closes finish their chapter: cutting them off would leave a partial folder behind
+// until the next window, and the pages still to come are no more than one chapter per slot.
+func (m *Module) StartScheduler() {
+	go func() {
+		ticker := time.NewTicker(scheduleCheckInterval)
+		defer ticker.Stop()
+
+		wasOpen := m.downloadWindowOpen()
+		for {
+			select {
+			case <-m.ctx.Done():
+				return
+			case <-ticker.C:
+				open := m.downloadWindowOpen()
+				m.applySchedule(open)
+				if open != wasOpen {
+					if open {
+						fmt.Printf("[Downloader] Download window opened\n")
+					} else {
+						fmt.Printf("[Downloader] Download window closed, running jobs finish and queued jobs wait for the next one\n")
+					}
+					wasOpen = open
+				}
+			}
+		}
+	}()
+}
+
+// applySchedule updates the status of the queued jobs and starts them when the window is open.
+// Running on every tick also picks up schedule changes made in settings.
+func (m *Module) applySchedule(open bool) {
+	status := persistence.StatusScheduled
+	if open {
+		status = persistence.StatusPending
+	}
+
+	m.queueLock.Lock()
+	changed := false
+	for siteID, queue := range m.queues {
+		for _, qj := range queue {
+			if qj.job.Status != status {
+				qj.job.Status = status
+				m.pm.UpdateJob(qj.job.ID, map[string]interface{}{"status": status})
+				changed = true
+			}
+		}
+		if open {
+			m.startQueuedLocked(siteID)
+		}
+	}
+	m.queueLock.Unlock()
+
+	if changed {
+		m.notifyUpdate()
+	}
+}
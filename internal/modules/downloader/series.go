@@ -0,0 +1,364 @@
+package downloader
+
+import (
+	"errors"
+	"fmt"
+	"manga-visor/internal/persistence"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SeriesFilter selects the chapters of a series to download. Empty fields don't filter.
+type SeriesFilter struct {
+	// Explicit selection by chapter ID, the other filters still apply
+	ChapterIDs []string `json:"chapterIds,omitempty"`
+	// Chapter number ranges: "1-10, 15, 20-" (open ended), "-5"
+	Ranges string `json:"ranges,omitempty"`
+	// Language codes, e.g. ["en", "es-la"]
+	Languages []string `json:"languages,omitempty"`
+	// Only releases from this scanlation group
+	Group string `json:"group,omitempty"`
+	// Only the N highest chapter numbers
+	Latest int `json:"latest,omitempty"`
+	// Keep one release per chapter number
+	Dedupe bool `json:"dedupe,omitempty"`
+	// Group whose release Dedupe keeps, otherwise the first language in Languages wins
+	PreferGroup string `json:"preferGroup,omitempty"`
+}
+
+// SeriesBatch is the result of StartSeriesDownload
+type SeriesBatch struct {
+	BatchID    string   `json:"batchId"`
+	SeriesName string   `json:"seriesName"`
+	JobIDs     []string `json:"jobIds"`
+	// Matching chapters that were already downloaded
+	Skipped int `json:"skipped"`
+}
+
+// BatchProgress sums up the jobs of a batch
+type BatchProgress struct {
+	BatchID   string `json:"batchId"`
+	Total     int    `json:"total"`
+	Completed int    `json:"completed"`
+	Failed    int    `json:"failed"`
+	Active    int    `json:"active"` // pending, running or paused
+	Pages     int    `json:"pages"`
+	PagesDone int    `json:"pagesDone"`
+}
+
+// Chapter numbers in names: "Chapter 12", "Ch. 12.5", "Capítulo 7", "#3"
+var chapterNumberRe = regexp.MustCompile(`(?i)(?:chapter|ch\.?|cap[ií]tulo|cap\.?|episode|ep\.?|#)\s*(\d+(?:\.\d+)?)`)
+var lastNumberRe = regexp.MustCompile(`\d+(?:\.\d+)?`)
+
+// chapterNumber returns the chapter number from the site, or parsed from the name
+func chapterNumber(ch ChapterInfo) (float64, bool) {
+	text := ch.Number
+	if text == "" {
+		if m := chapterNumberRe.FindStringSubmatch(ch.Name); m != nil {
+			text = m[1]
+		} else if all := lastNumberRe.FindAllString(ch.Name, -1); len(all) > 0 {
+			text = all[len(all)-1]
+		}
+	}
+	n, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+	return n, err == nil
+}
+
+type chapterRange struct{ from, to float64 }
+
+// parseRanges reads "1-10, 15, 20-"
+func parseRanges(text string) ([]chapterRange, error) {
+	var ranges []chapterRange
+	for _, part := range strings.Split(text, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		r := chapterRange{from: math.Inf(-1), to: math.Inf(1)}
+		from, to, isRange := strings.Cut(part, "-")
+		var err error
+		if from = strings.TrimSpace(from); from != "" {
+			if r.from, err = strconv.ParseFloat(from, 64); err != nil {
+				return nil, fmt.Errorf("invalid chapter range %q", part)
+			}
+		}
+		if !isRange {
+			r.to = r.from
+		} else if to = strings.TrimSpace(to); to != "" {
+			if r.to, err = strconv.ParseFloat(to, 64); err != nil {
+				return nil, fmt.Errorf("invalid chapter range %q", part)
+			}
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges, nil
+}
+
+// filterChapters applies a SeriesFilter and returns the chapters sorted by number, lowest first
+func filterChapters(chapters []ChapterInfo, filter SeriesFilter) ([]ChapterInfo, error) {
+	ranges, err := parseRanges(filter.Ranges)
+	if err != nil {
+		return nil, err
+	}
+	selected := make(map[string]bool, len(filter.ChapterIDs))
+	for _, id := range filter.ChapterIDs {
+		selected[id] = true
+	}
+
+	type candidate struct {
+		ch     ChapterInfo
+		num    float64
+		hasNum bool
+	}
+	var list []candidate
+	for _, ch := range chapters {
+		if len(selected) > 0 && !selected[ch.ID] {
+			continue
+		}
+		if len(filter.Languages) > 0 && !containsFold(filter.Languages, ch.Language) {
+			continue
+		}
+		if filter.Group != "" && !strings.EqualFold(strings.TrimSpace(ch.ScanGroup), strings.TrimSpace(filter.Group)) {
+			continue
+		}
+		num, hasNum := chapterNumber(ch)
+		if len(ranges) > 0 {
+			if !hasNum {
+				continue
+			}
+			inRange := false
+			for _, r := range ranges {
+				if num >= r.from && num <= r.to {
+					inRange = true
+					break
+				}
+			}
+			if !inRange {
+				continue
+			}
+		}
+		list = append(list, candidate{ch, num, hasNum})
+	}
+
+	// Numbered chapters in ascending order, the rest after them in site order
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].hasNum != list[j].hasNum {
+			return list[i].hasNum
+		}
+		return list[i].hasNum && list[i].num < list[j].num
+	})
+
+	if filter.Dedupe {
+		// rank orders the releases of one chapter number, lower is better
+		rank := func(ch ChapterInfo) int {
+			r := len(filter.Languages)
+			for i, lang := range filter.Languages {
+				if strings.EqualFold(lang, ch.Language) {
+					r = i
+					break
+				}
+			}
+			if filter.PreferGroup != "" && !strings.EqualFold(strings.TrimSpace(ch.ScanGroup), strings.TrimSpace(filter.PreferGroup)) {
+				r += len(filter.Languages) + 1
+			}
+			return r
+		}
+		deduped := list[:0]
+		best := make(map[float64]int) // number -> index in deduped
+		for _, c := range list {
+			if !c.hasNum {
+				deduped = append(deduped, c)
+				continue
+			}
+			if i, ok := best[c.num]; ok {
+				if rank(c.ch) < rank(deduped[i].ch) {
+					deduped[i] = c
+				}
+				continue
+			}
+			best[c.num] = len(deduped)
+			deduped = append(deduped, c)
+		}
+		list = deduped
+	}
+
+	if filter.Latest > 0 {
+		// Keep every release of the N highest numbers
+		var numbers []float64
+		seen := make(map[float64]bool)
+		for _, c := range list {
+			if c.hasNum && !seen[c.num] {
+				seen[c.num] = true
+				numbers = append(numbers, c.num)
+			}
+		}
+		if len(numbers) > filter.Latest {
+			cutoff := numbers[len(numbers)-filter.Latest]
+			latest := list[:0]
+			for _, c := range list {
+				if c.hasNum && c.num >= cutoff {
+					latest = append(latest, c)
+				}
+			}
+			list = latest
+		}
+	}
+
+	result := make([]ChapterInfo, len(list))
+	for i, c := range list {
+		result[i] = c.ch
+	}
+	return result, nil
+}
+
+// StartSeriesDownload queues the chapters of a series that match the filter as one batch.
+// Jobs are created right away as pending; their pages are resolved in the background,
+// one chapter after the other, so the call returns without waiting for the site.
+func (m *Module) StartSeriesDownload(url string, filter SeriesFilter) (*SeriesBatch, error) {
+	info, err := m.fetchSeries(url)
+	if err != nil {
+		return nil, err
+	}
+	chapters, err := filterChapters(info.Chapters, filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(chapters) == 0 {
+		return nil, fmt.Errorf("no chapters match the filter")
+	}
+
+	batch := &SeriesBatch{
+		BatchID:    fmt.Sprintf("batch-%d", time.Now().UnixNano()),
+		SeriesName: info.SeriesName,
+	}
+	batch.JobIDs, batch.Skipped, err = m.queueChapters(info, chapters, batch.BatchID)
+	if err != nil && len(batch.JobIDs) == 0 {
+		return nil, err
+	}
+
+	fmt.Printf("[Downloader] Series batch %s: %d chapters queued, %d already downloaded\n", batch.BatchID, len(batch.JobIDs), batch.Skipped)
+	return batch, nil
+}
+
+// queueChapters creates the jobs of chapters from a series as pending and starts them in the
+// background, one after the other. Completed chapters are skipped and counted.
+// batchID groups the jobs, empty for none. If a job can't be saved the chapters from it on
+// are left out, the jobs created before it still start, and the error is returned.
+func (m *Module) queueChapters(info *SiteInfo, chapters []ChapterInfo, batchID string) ([]string, int, error) {
+	existing := make(map[string]persistence.DownloadJob)
+	for _, job := range m.pm.GetJobs() {
+		existing[job.URL] = job
+	}
+
+	jobIDs := []string{}
+	skipped := 0
+	var toStart []persistence.DownloadJob
+	var queueErr error
+	for _, ch := range chapters {
+		details := &SiteInfo{ChapterNumber: ch.Number, Volume: ch.Volume, ScanGroup: ch.ScanGroup, Language: ch.Language}
+
+		if job, ok := existing[ch.URL]; ok {
+			if job.Status == persistence.StatusCompleted {
+				skipped++
+				continue
+			}
+			// Pulled into this batch. Failed and cancelled jobs wait as pending for their turn,
+			// running, queued and paused ones are left as they are.
+			updates := fillChapterDetails(&job, details)
+			if batchID != "" {
+				updates["batchId"] = batchID
+			}
+			leave := job.Status == persistence.StatusPaused || m.isActiveOrQueued(job)
+			if job.Status == persistence.StatusFailed || job.Status == persistence.StatusCancelled {
+				job.Status = persistence.StatusPending
+				updates["status"] = persistence.StatusPending
+				updates["error"] = ""
+			}
+			m.pm.UpdateJob(job.ID, updates)
+			jobIDs = append(jobIDs, job.ID)
+			if !leave {
+				toStart = append(toStart, job)
+			}
+			continue
+		}
+
+		siteID := info.SiteID
+		if algo := m.findAlgorithm(ch.URL); algo != nil {
+			siteID = algo.GetSiteID()
+		}
+		job := persistence.DownloadJob{
+			ID:          newJobID(),
+			URL:         ch.URL,
+			Site:        siteID,
+			SeriesName:  info.SeriesName,
+			ChapterName: ch.Name,
+			Status:      persistence.StatusPending,
+			CreatedAt:   time.Now().Format(time.RFC3339),
+			BatchID:     batchID,
+		}
+		fillChapterDetails(&job, details)
+		if err := m.pm.AddJob(job); err != nil {
+			fmt.Printf("[Downloader] Failed to queue %s: %v\n", ch.Name, err)
+			queueErr = fmt.Errorf("failed to queue %s: %v", ch.Name, err)
+			break
+		}
+		jobIDs = append(jobIDs, job.ID)
+		toStart = append(toStart, job)
+	}
+	m.notifyUpdate()
+
+	go func() {
+		for _, job := range toStart {
+			// Removed or paused while waiting for its turn
+			current, ok := m.findJob(job.ID)
+			if !ok || current.Status == persistence.StatusPaused {
+				continue
+			}
+			if _, err := m.startDownload(job.URL, info.SeriesName, job.ChapterName, batchID); errors.Is(err, errDuplicate) {
+				// Skipped on purpose, not a failure
+				m.pm.UpdateJob(job.ID, map[string]interface{}{"status": persistence.StatusCancelled, "error": err.Error()})
+				m.notifyUpdate()
+			} else if err != nil {
+				fmt.Printf("[Downloader] Failed to start %s: %v\n", job.ChapterName, err)
+				m.failJob(job.ID, err.Error())
+			}
+		}
+	}()
+
+	return jobIDs, skipped, queueErr
+}
+
+func (m *Module) findJob(id string) (persistence.DownloadJob, bool) {
+	for _, job := range m.pm.GetJobs() {
+		if job.ID == id {
+			return job, true
+		}
+	}
+	return persistence.DownloadJob{}, false
+}
+
+// GetBatchProgress sums up the jobs of a series batch
+func (m *Module) GetBatchProgress(batchID string) BatchProgress {
+	progress := BatchProgress{BatchID: batchID}
+	for _, job := range m.pm.GetJobs() {
+		if job.BatchID != batchID {
+			continue
+		}
+		progress.Total++
+		progress.Pages += job.TotalPages
+		progress.PagesDone += job.Progress
+		switch job.Status {
+		case persistence.StatusCompleted, persistence.StatusCompletedWithErrors:
+			progress.Completed++
+		case persistence.StatusFailed, persistence.StatusCancelled:
+			progress.Failed++
+		default:
+			progress.Active++
+		}
+	}
+	return progress
+}
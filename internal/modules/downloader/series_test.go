@@ -0,0 +1,175 @@
+package downloader
+
+import (
+	"fmt"
+	"manga-visor/internal/persistence"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestParseRanges(t *testing.T) {
+	inf := math.Inf(1)
+	tests := []struct {
+		text    string
+		want    []chapterRange
+		wantErr bool
+	}{
+		{text: "", want: nil},
+		{text: " , ", want: nil},
+		{text: "15", want: []chapterRange{{15, 15}}},
+		{text: "1-10, 15, 20-", want: []chapterRange{{1, 10}, {15, 15}, {20, inf}}},
+		{text: "-5", want: []chapterRange{{-inf, 5}}},
+		{text: " 2.5 - 3.5 ", want: []chapterRange{{2.5, 3.5}}},
+		{text: "-", want: []chapterRange{{-inf, inf}}},
+		{text: "a-3", wantErr: true},
+		{text: "1-b", wantErr: true},
+		{text: "1, two", wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.text, func(t *testing.T) {
+			got, err := parseRanges(tc.text)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("parseRanges(%q) = %v, want an error", tc.text, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseRanges(%q): %v", tc.text, err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("parseRanges(%q) = %v, want %v", tc.text, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFilterChapters(t *testing.T) {
+	chapters := []ChapterInfo{
+		{ID: "3en", Name: "Chapter 3", Language: "en", ScanGroup: "TCB"},
+		{ID: "1en", Name: "Ch. 1", Language: "en", ScanGroup: "TCB"},
+		{ID: "1es", Name: "Capítulo 1", Language: "es", ScanGroup: "Lector"},
+		{ID: "2en", Name: "Chapter 2", Number: "2", Language: "en", ScanGroup: "Other"},
+		{ID: "2en-tcb", Name: "Chapter 2", Number: "2", Language: "en", ScanGroup: "TCB"},
+		{ID: "2es", Name: "Capítulo 2", Language: "es", ScanGroup: "Lector"},
+		{ID: "2.5en", Name: "Extra 2.5", Language: "en", ScanGroup: "TCB"},
+		{ID: "oneshot", Name: "Oneshot", Language: "en"},
+	}
+	tests := []struct {
+		name    string
+		filter  SeriesFilter
+		want    string // chapter IDs in order
+		wantErr bool
+	}{
+		{name: "no filter sorts by number", want: "1en 1es 2en 2en-tcb 2es 2.5en 3en oneshot"},
+		{name: "selected IDs", filter: SeriesFilter{ChapterIDs: []string{"3en", "oneshot", "1es"}}, want: "1es 3en oneshot"},
+		{name: "ranges skip unnumbered", filter: SeriesFilter{Ranges: "2-2.5"}, want: "2en 2en-tcb 2es 2.5en"},
+		{name: "open range", filter: SeriesFilter{Ranges: "3-"}, want: "3en"},
+		{name: "several ranges", filter: SeriesFilter{Ranges: "1, 3"}, want: "1en 1es 3en"},
+		{name: "languages", filter: SeriesFilter{Languages: []string{"ES"}}, want: "1es 2es"},
+		{name: "group", filter: SeriesFilter{Group: " tcb "}, want: "1en 2en-tcb 2.5en 3en"},
+		{name: "latest keeps every release", filter: SeriesFilter{Latest: 2}, want: "2.5en 3en"},
+		{name: "latest past the end", filter: SeriesFilter{Latest: 10, Languages: []string{"es"}}, want: "1es 2es"},
+		{name: "dedupe by language", filter: SeriesFilter{Dedupe: true, Languages: []string{"es", "en"}}, want: "1es 2es 2.5en 3en oneshot"},
+		{name: "dedupe by group", filter: SeriesFilter{Dedupe: true, Languages: []string{"en", "es"}, PreferGroup: "TCB"}, want: "1en 2en-tcb 2.5en 3en oneshot"},
+		{name: "dedupe keeps the first release", filter: SeriesFilter{Dedupe: true}, want: "1en 2en 2.5en 3en oneshot"},
+		{name: "dedupe then latest", filter: SeriesFilter{Dedupe: true, Languages: []string{"es", "en"}, Latest: 2}, want: "2.5en 3en"},
+		{name: "invalid range", filter: SeriesFilter{Ranges: "x"}, wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := filterChapters(chapters, tc.filter)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("filterChapters(%+v) returned no error", tc.filter)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("filterChapters(%+v): %v", tc.filter, err)
+			}
+			ids := make([]string, len(got))
+			for i, ch := range got {
+				ids[i] = ch.ID
+			}
+			if strings.Join(ids, " ") != tc.want {
+				t.Errorf("filterChapters(%+v) = %s, want %s", tc.filter, strings.Join(ids, " "), tc.want)
+			}
+		})
+	}
+}
+
+func TestQueueChapters(t *testing.T) {
+	d := &fakeDownloader{prefix: "https://fake.test/"}
+	m := newTestModule(t, d)
+	for _, job := range []persistence.DownloadJob{
+		{ID: "done", URL: "https://fake.test/1", Status: persistence.StatusCompleted},
+		{ID: "failed", URL: "https://fake.test/2", Status: persistence.StatusFailed, Error: "timeout"},
+		{ID: "paused", URL: "https://fake.test/3", Status: persistence.StatusPaused},
+	} {
+		if err := m.pm.AddJob(job); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	info := &SiteInfo{SiteID: "fake", SeriesName: "Series"}
+	var chapters []ChapterInfo
+	for i := 1; i <= 5; i++ {
+		chapters = append(chapters, ChapterInfo{Name: fmt.Sprintf("Chapter %d", i), URL: fmt.Sprintf("https://fake.test/%d", i), Number: strconv.Itoa(i), Language: "en"})
+	}
+	jobIDs, skipped, err := m.queueChapters(info, chapters, "batch-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if skipped != 1 || len(jobIDs) != 4 {
+		t.Fatalf("queueChapters = %d jobs, %d skipped, want 4 jobs and 1 skipped", len(jobIDs), skipped)
+	}
+	if jobIDs[0] != "failed" || jobIDs[1] != "paused" || jobIDs[2] == jobIDs[3] {
+		t.Fatalf("job IDs = %v", jobIDs)
+	}
+
+	// Every job but the paused one is started, in chapter order
+	waitFor(t, "the jobs to start", func() bool { return len(d.called()) == 3 })
+	waitFor(t, "the jobs to fail", func() bool {
+		for _, id := range []string{"failed", jobIDs[2], jobIDs[3]} {
+			if job, _ := m.findJob(id); job.Status != persistence.StatusFailed {
+				return false
+			}
+		}
+		return true
+	})
+	want := []string{"https://fake.test/2", "https://fake.test/4", "https://fake.test/5"}
+	if got := d.called(); !reflect.DeepEqual(got, want) {
+		t.Errorf("started %v, want %v", got, want)
+	}
+
+	for _, id := range jobIDs {
+		job, ok := m.findJob(id)
+		if !ok {
+			t.Fatalf("job %s missing", id)
+		}
+		if job.BatchID != "batch-1" {
+			t.Errorf("job %s has batch %q, want batch-1", id, job.BatchID)
+		}
+		if job.Language != "en" || job.ChapterNumber == "" {
+			t.Errorf("job %s lost its chapter details: %+v", id, job)
+		}
+	}
+	if job, _ := m.findJob("paused"); job.Status != persistence.StatusPaused {
+		t.Errorf("paused job is %s, want it left paused", job.Status)
+	}
+	if job, _ := m.findJob("done"); job.BatchID != "" {
+		t.Errorf("completed job was pulled into the batch")
+	}
+	if job, _ := m.findJob(jobIDs[2]); job.Site != "fake" || job.SeriesName != "Series" || job.ChapterName != "Chapter 4" {
+		t.Errorf("new job = %+v", job)
+	}
+
+	progress := m.GetBatchProgress("batch-1")
+	if progress.Total != 4 || progress.Failed != 3 || progress.Active != 1 {
+		t.Errorf("batch progress = %+v, want 4 jobs, 3 failed, 1 active", progress)
+	}
+}
@@ -0,0 +1,305 @@
+package downloader
+
+import (
+	"fmt"
+	"manga-visor/internal/persistence"
+	"strings"
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// NewChaptersEvent is emitted as "new_chapters" when a subscription finds new chapters
+type NewChaptersEvent struct {
+	SubscriptionID string        `json:"subscriptionId"`
+	SeriesName     string        `json:"seriesName"`
+	Chapters       []ChapterInfo `json:"chapters"`
+	// How many of them were queued (AutoDownload)
+	Queued int `json:"queued"`
+}
+
+// chapterKey identifies a chapter across checks, some sites have no chapter IDs
+func chapterKey(ch ChapterInfo) string {
+	if ch.ID != "" {
+		return ch.ID
+	}
+	return ch.URL
+}
+
+// chapterMatches applies the subscription filters. Chapters without language or group
+// information pass, not every site reports them.
+func chapterMatches(sub persistence.Subscription, ch ChapterInfo) bool {
+	if len(sub.Languages) > 0 && ch.Language != "" && !containsFold(sub.Languages, ch.Language) {
+		return false
+	}
+	if len(sub.Groups) > 0 && ch.ScanGroup != "" && !containsFold(sub.Groups, ch.ScanGroup) {
+		return false
+	}
+	return true
+}
+
+func containsFold(list []string, value string) bool {
+	value = strings.TrimSpace(value)
+	for _, item := range list {
+		if strings.EqualFold(strings.TrimSpace(item), value) {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchSeries resolves a series URL into its chapter list
+func (m *Module) fetchSeries(url string) (*SiteInfo, error) {
+	algo := m.findAlgorithm(url)
+	if algo == nil {
+		return nil, fmt.Errorf("no algorithm found for this URL")
+	}
+	info, err := algo.GetImages(url)
+	if err != nil {
+		return nil, err
+	}
+	if info.Type != "series" {
+		return nil, fmt.Errorf("not a series URL")
+	}
+	return info, nil
+}
+
+func (m *Module) GetSubscriptions() []persistence.Subscription {
+	return m.subs.GetSubscriptions()
+}
+
+// AddSubscription follows a series. The chapters already out are recorded as known,
+// only chapters released afterwards are reported.
+func (m *Module) AddSubscription(url string, languages []string, groups []string, autoDownload bool) (*persistence.Subscription, error) {
+	url = strings.TrimSpace(url)
+	for _, sub := range m.subs.GetSubscriptions() {
+		if sub.URL == url {
+			return nil, fmt.Errorf("already subscribed to this series")
+		}
+	}
+
+	info, err := m.fetchSeries(url)
+	if err != nil {
+		return nil, err
+	}
+
+	known := make([]string, 0, len(info.Chapters))
+	for _, ch := range info.Chapters {
+		known = append(known, chapterKey(ch))
+	}
+
+	now := time.Now().Format(time.RFC3339)
+	sub := persistence.Subscription{
+		ID:            fmt.Sprintf("%d", time.Now().UnixNano()),
+		URL:           url,
+		Site:          info.SiteID,
+		SeriesName:    info.SeriesName,
+		Languages:     languages,
+		Groups:        groups,
+		AutoDownload:  autoDownload,
+		KnownChapters: known,
+		CreatedAt:     now,
+		LastCheckedAt: now,
+	}
+	m.subs.AddSubscription(sub)
+	fmt.Printf("[Downloader] Subscribed to %s (%d chapters known)\n", sub.SeriesName, len(known))
+	m.notifySubscriptions()
+	return &sub, nil
+}
+
+// UpdateSubscription changes the filters of a subscription. They apply from the next check on.
+func (m *Module) UpdateSubscription(id string, languages []string, groups []string, autoDownload bool) error {
+	if _, ok := m.findSubscription(id); !ok {
+		return fmt.Errorf("subscription not found: %s", id)
+	}
+	m.subs.UpdateSubscription(id, map[string]interface{}{
+		"languages":    languages,
+		"groups":       groups,
+		"autoDownload": autoDownload,
+	})
+	m.notifySubscriptions()
+	return nil
+}
+
+func (m *Module) RemoveSubscription(id string) {
+	m.subs.RemoveSubscription(id)
+	m.notifySubscriptions()
+}
+
+func (m *Module) findSubscription(id string) (persistence.Subscription, bool) {
+	for _, sub := range m.subs.GetSubscriptions() {
+		if sub.ID == id {
+			return sub, true
+		}
+	}
+	return persistence.Subscription{}, false
+}
+
+// CheckSubscription fetches the series again and returns the chapters that are new,
+// match the filters and were never downloaded. They are queued if AutoDownload is on.
+func (m *Module) CheckSubscription(id string) ([]ChapterInfo, error) {
+	// One check at a time, so the poller and a manual check don't race on KnownChapters
+	m.subLock.Lock()
+	defer m.subLock.Unlock()
+
+	sub, ok := m.findSubscription(id)
+	if !ok {
+		return nil, fmt.Errorf("subscription not found: %s", id)
+	}
+	now := time.Now().Format(time.RFC3339)
+
+	info, err := m.fetchSeries(sub.URL)
+	if err != nil {
+		fmt.Printf("[Downloader] Failed to check subscription %s: %v\n", sub.SeriesName, err)
+		m.subs.UpdateSubscription(id, map[string]interface{}{
+			"lastCheckedAt": now,
+			"lastError":     err.Error(),
+		})
+		m.notifySubscriptions()
+		return nil, err
+	}
+
+	known := make(map[string]bool, len(sub.KnownChapters))
+	for _, k := range sub.KnownChapters {
+		known[k] = true
+	}
+	downloaded := make(map[string]bool)
+	for _, job := range m.pm.GetJobs() {
+		downloaded[job.URL] = true
+	}
+
+	var fresh []ChapterInfo
+	knownList := append([]string{}, sub.KnownChapters...)
+	for _, ch := range info.Chapters {
+		key := chapterKey(ch)
+		if known[key] {
+			continue
+		}
+		known[key] = true
+		if chapterMatches(sub, ch) && !downloaded[ch.URL] {
+			// Known once reported, or once queued when auto-downloading
+			fresh = append(fresh, ch)
+			continue
+		}
+		knownList = append(knownList, key)
+	}
+
+	seriesName := sub.SeriesName
+	if info.SeriesName != "" {
+		seriesName = info.SeriesName
+	}
+
+	queued := 0
+	lastError := ""
+	hasJob := make(map[string]bool)
+	if sub.AutoDownload && len(fresh) > 0 {
+		info.SeriesName = seriesName
+		jobIDs, _, err := m.queueChapters(info, fresh, "")
+		queued = len(jobIDs)
+		if err != nil {
+			fmt.Printf("[Downloader] Failed to queue new chapters of %s: %v\n", seriesName, err)
+			lastError = err.Error()
+		}
+		for _, job := range m.pm.GetJobs() {
+			hasJob[job.URL] = true
+		}
+	}
+	for _, ch := range fresh {
+		// A chapter that couldn't be queued stays new, the next check tries it again
+		if !sub.AutoDownload || hasJob[ch.URL] {
+			knownList = append(knownList, chapterKey(ch))
+		}
+	}
+
+	m.subs.UpdateSubscription(id, map[string]interface{}{
+		"seriesName":    seriesName,
+		"knownChapters": knownList,
+		"lastCheckedAt": now,
+		"lastError":     lastError,
+	})
+
+	if len(fresh) > 0 {
+		fmt.Printf("[Downloader] %d new chapters for %s (%d queued)\n", len(fresh), seriesName, queued)
+		names := make([]string, len(fresh))
+		for i, ch := range fresh {
+			names[i] = ch.Name
+		}
+		m.fireHooks(HookEvent{
+			Event:    HookNewChapters,
+			Site:     info.SiteID,
+			Series:   seriesName,
+			URL:      sub.URL,
+			Chapters: names,
+		})
+		if m.ctx != nil {
+			runtime.EventsEmit(m.ctx, "new_chapters", NewChaptersEvent{
+				SubscriptionID: id,
+				SeriesName:     seriesName,
+				Chapters:       fresh,
+				Queued:         queued,
+			})
+		}
+	}
+	m.notifySubscriptions()
+	return fresh, nil
+}
+
+// CheckAllSubscriptions checks every subscription now, one after the other.
+// Returns the number of new chapters found.
+func (m *Module) CheckAllSubscriptions() int {
+	total := 0
+	for _, sub := range m.subs.GetSubscriptions() {
+		if fresh, err := m.CheckSubscription(sub.ID); err == nil {
+			total += len(fresh)
+		}
+	}
+	return total
+}
+
+// StartSubscriptionPoller checks the subscriptions that are due every minute,
+// using the interval from settings
+func (m *Module) StartSubscriptionPoller() {
+	go func() {
+		ticker := time.NewTicker(1 * time.Minute)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-m.ctx.Done():
+				return
+			case <-ticker.C:
+				interval := m.sm.Get().SubscriptionCheckMinutes
+				if interval <= 0 {
+					continue
+				}
+				// Logged in to MangaDex, one read of the follows feed covers all its subscriptions
+				viaFeed := m.mangaDexLoggedIn()
+				if viaFeed {
+					last, err := time.Parse(time.RFC3339, m.mda.Get().FeedCheckedAt)
+					if err != nil || time.Since(last) >= time.Duration(interval)*time.Minute {
+						if _, err := m.CheckMangaDexFeed(); err != nil {
+							fmt.Printf("[Downloader] Failed to read the MangaDex feed: %v\n", err)
+							viaFeed = false
+						}
+					}
+				}
+				for _, sub := range m.subs.GetSubscriptions() {
+					if viaFeed && sub.Site == "mangadex.org" {
+						continue
+					}
+					last, err := time.Parse(time.RFC3339, sub.LastCheckedAt)
+					if err == nil && time.Since(last) < time.Duration(interval)*time.Minute {
+						continue
+					}
+					m.CheckSubscription(sub.ID)
+				}
+			}
+		}
+	}()
+}
+
+func (m *Module) notifySubscriptions() {
+	if m.ctx != nil {
+		runtime.EventsEmit(m.ctx, "subscriptions_updated")
+	}
+}
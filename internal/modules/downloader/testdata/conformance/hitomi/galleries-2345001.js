@@ -0,0 +1 @@
+var galleryinfo = {"id": "2345001", "title": "Hanabi", "japanese_title": null, "language": "spanish", "language_localname": "español", "type": "doujinshi", "date": "2025-06-14 09:21:00-05", "files": [{"hash": "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa0c1e", "haswebp": 1, "hasavif": 1, "name": "01.jpg", "width": 1280, "height": 1810}]}
\ No newline at end of file
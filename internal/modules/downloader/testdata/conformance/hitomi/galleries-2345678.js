@@ -0,0 +1 @@
+var galleryinfo = {"id": "2345678", "title": "Natsu no Owari ni", "japanese_title": null, "language": "spanish", "language_localname": "español", "type": "doujinshi", "date": "2025-06-14 09:21:00-05", "files": [{"hash": "8c3a5d9e0f1b2c4d6e7f8a9b0c1d2e3f4a5b6c7d8e9f0a1b2c3d4e5f6a7b42b0", "haswebp": 1, "hasavif": 1, "name": "01.jpg", "width": 1280, "height": 1810}, {"hash": "1f2e3d4c5b6a79881726354453627180a9b8c7d6e5f4a3b2c1d0e9f8a7b6c5d4", "haswebp": 1, "hasavif": 1, "name": "02.jpg", "width": 1280, "height": 1810}, {"hash": "0a1b2c3d4e5f60718293a4b5c6d7e8f90a1b2c3d4e5f60718293a4b5c6d7e57a", "haswebp": 1, "hasavif": 1, "name": "03.jpg", "width": 1280, "height": 1810}]}
\ No newline at end of file
@@ -0,0 +1,16 @@
+'use strict';
+
+gg = { m: function(g) {
+var o = 0;
+switch (g) {
+case 43:
+case 1402:
+case 2733:
+case 3612:
+o = 1; break;
+}
+return o;
+},
+s: function(h) { var m = /(..)(.)$/.exec(h); return parseInt(m[2]+m[1], 16).toString(10); },
+b: '1739275202/'
+};
@@ -16,6 +16,11 @@ type ChapterInfo struct {
 	Date      string
 	ScanGroup string
 	Language  string
+	// Chapter number as given by the site ("12", "12.5"), empty if it only has a name
+	Number string
+	Volume string
+	// Chapter already on disk, found by name in the content index
+	LocalPath string
 }
 
 type SiteInfo struct {
@@ -27,6 +32,16 @@ type SiteInfo struct {
 	// New fields for series support
 	Type     string // "single" or "series"
 	Chapters []ChapterInfo
+	// Optional chapter details for the naming template
+	ChapterNumber string
+	Volume        string
+	ScanGroup     string
+	Language      string
+	// Local copies found by FetchMangaInfo
+	Duplicates []DuplicateMatch
+	// Set by startDownload for a chapter scraped for the job, whose first pages runDownload
+	// still compares with the index
+	comparePages bool
 }
 
 type DownloaderInterface interface {
@@ -0,0 +1,83 @@
+package downloader
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// verifyImage checks that a downloaded page is a complete image.
+// JPEG, PNG and GIF are fully decoded, which catches truncated files; for the other
+// formats the magic bytes are checked and, where the container has one, the declared size.
+// Unknown binary formats pass, text (error pages, JSON) doesn't.
+func verifyImage(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() == 0 {
+		return fmt.Errorf("empty file")
+	}
+
+	header := make([]byte, 32)
+	n, err := io.ReadFull(f, header)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return err
+	}
+	header = header[:n]
+	err = nil // A file shorter than the header is left to the checks below
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	sniffed := http.DetectContentType(header)
+	switch {
+	case bytes.HasPrefix(header, []byte{0xFF, 0xD8, 0xFF}):
+		_, err = jpeg.Decode(f)
+	case bytes.HasPrefix(header, []byte("\x89PNG\r\n\x1a\n")):
+		_, err = png.Decode(f)
+	case bytes.HasPrefix(header, []byte("GIF87a")) || bytes.HasPrefix(header, []byte("GIF89a")):
+		_, err = gif.DecodeAll(f)
+	case len(header) >= 12 && bytes.Equal(header[0:4], []byte("RIFF")) && bytes.Equal(header[8:12], []byte("WEBP")):
+		// RIFF size covers everything after the first 8 bytes
+		if riffSize := int64(binary.LittleEndian.Uint32(header[4:8])) + 8; riffSize > info.Size() {
+			err = fmt.Errorf("truncated webp: %d of %d bytes", info.Size(), riffSize)
+		}
+	case len(header) >= 12 && bytes.Equal(header[4:8], []byte("ftyp")):
+		// AVIF/HEIF, the box structure is left to the decoder
+	case len(header) >= 6 && bytes.HasPrefix(header, []byte("BM")):
+		if bmpSize := int64(binary.LittleEndian.Uint32(header[2:6])); bmpSize > info.Size() {
+			err = fmt.Errorf("truncated bmp: %d of %d bytes", info.Size(), bmpSize)
+		}
+	case bytes.HasPrefix(header, []byte("II*\x00")) || bytes.HasPrefix(header, []byte("MM\x00*")):
+		// TIFF
+	case strings.EqualFold(filepath.Ext(strings.TrimSuffix(path, partSuffix)), ".svg") &&
+		bytes.HasPrefix(bytes.TrimSpace(bytes.TrimPrefix(header, []byte("\xEF\xBB\xBF"))), []byte("<")):
+		// SVG, text based
+	case strings.HasPrefix(sniffed, "text/"):
+		// Most often an HTML error/captcha page served with status 200
+		return fmt.Errorf("not an image (%s)", strings.SplitN(sniffed, ";", 2)[0])
+	default:
+		// A binary format not checked here (JPEG XL, ICO...), kept as it is
+	}
+
+	if err != nil {
+		return fmt.Errorf("corrupt image: %v", err)
+	}
+	return nil
+}
@@ -8,7 +8,9 @@ import (
 	"strings"
 )
 
-type ZonaTMODownloader struct{}
+type ZonaTMODownloader struct {
+	withClients
+}
 
 func normalizeZonaTMOSeriesName(raw string) string {
 	raw = strings.TrimSpace(raw)
@@ -47,7 +49,7 @@ func (d *ZonaTMODownloader) GetImages(viewerURL string) (*SiteInfo, error) {
 		viewerURL = strings.Replace(viewerURL, "/paginated", "/cascade", 1)
 	}
 
-	client := &http.Client{}
+	client := d.newHTTPClient()
 	req, _ := http.NewRequest("GET", viewerURL, nil)
 	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) Chrome/120.0.0.0")
 	req.Header.Set("Referer", "https://zonatmo.com/")
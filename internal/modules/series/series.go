@@ -73,30 +73,11 @@ func (m *Module) getBaseURL() string {
 // AddSeries adds a series with its chapters
 // Optimized: Uses data from subfolders parameter instead of re-scanning
 func (m *Module) AddSeries(path string, subfolders []persistence.FolderInfo, isTemp bool) (*persistence.AddFolderResult, error) {
-	// Use shallow scan for root directory cover detection (fast)
-	rootImagePath, hasRootImages := m.fileLoader.FindFirstImageShallow(path)
-	var coverImage string
-
-	if hasRootImages && rootImagePath != "" {
-		coverImage = rootImagePath
-		// Try to find a better cover (cover.jpg, folder.jpg, etc.) using shallow scan
-		entries, _ := os.ReadDir(path)
-		for _, entry := range entries {
-			if entry.IsDir() {
-				continue
-			}
-			lowerName := strings.ToLower(entry.Name())
-			if m.fileLoader.IsSupportedImage(entry.Name()) {
-				fullPath := filepath.Join(path, entry.Name())
-				if strings.Contains(lowerName, "cover") || strings.Contains(lowerName, "folder") || strings.Contains(lowerName, "thumb") {
-					coverImage = fullPath
-					break
-				}
-			}
-		}
-	} else if len(subfolders) > 0 && subfolders[0].CoverImage != "" {
-		coverImage = subfolders[0].CoverImage
+	firstChapterCover := ""
+	if len(subfolders) > 0 {
+		firstChapterCover = subfolders[0].CoverImage
 	}
+	coverImage := m.seriesCover(path, firstChapterCover)
 
 	chapters := make([]persistence.ChapterInfo, len(subfolders))
 	for i, sub := range subfolders {
@@ -140,6 +121,88 @@ func (m *Module) AddSeries(path string, subfolders []persistence.FolderInfo, isT
 	return &persistence.AddFolderResult{Path: path, IsSeries: true}, nil
 }
 
+// seriesCover returns the cover of a series: an image in the series folder itself
+// (cover.jpg, folder.jpg... preferred), otherwise fallback
+func (m *Module) seriesCover(path string, fallback string) string {
+	// Use shallow scan for root directory cover detection (fast)
+	rootImagePath, hasRootImages := m.fileLoader.FindFirstImageShallow(path)
+	if !hasRootImages || rootImagePath == "" {
+		return fallback
+	}
+
+	// Try to find a better cover (cover.jpg, folder.jpg, etc.) using shallow scan
+	entries, _ := os.ReadDir(path)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		lowerName := strings.ToLower(entry.Name())
+		if m.fileLoader.IsSupportedImage(entry.Name()) {
+			if strings.Contains(lowerName, "cover") || strings.Contains(lowerName, "folder") || strings.Contains(lowerName, "thumb") {
+				return filepath.Join(path, entry.Name())
+			}
+		}
+	}
+	return rootImagePath
+}
+
+// AddChapter adds or refreshes one chapter of a series, creating the series if it isn't
+// registered yet. Chapters are kept in natural order; a series without a cover gets one.
+// Used by the downloader when a chapter completes.
+func (m *Module) AddChapter(seriesPath string, chapterPath string) error {
+	imageCount := m.fileLoader.GetShallowImageCount(chapterPath)
+	if imageCount == 0 {
+		return fmt.Errorf("no images found in %s", chapterPath)
+	}
+	chapter := persistence.ChapterInfo{
+		Path:       chapterPath,
+		Name:       filepath.Base(chapterPath),
+		ImageCount: imageCount,
+	}
+	if firstImagePath, hasImage := m.fileLoader.FindFirstImageShallow(chapterPath); hasImage {
+		chapter.CoverImage = filepath.Base(firstImagePath)
+	}
+
+	// Chapters completing together (one per download slot) each see the other's update
+	count, err := m.series.UpsertChapter(seriesPath, chapter, chapterLess, m.seriesCover(seriesPath, ""))
+	if err != nil {
+		return err
+	}
+
+	if m.ctx != nil {
+		runtime.EventsEmit(m.ctx, "series_updated")
+	}
+	fmt.Printf("Series chapter added: %s (%d chapters)\n", chapterPath, count)
+	return nil
+}
+
+// MoveChapter follows a chapter whose folder was moved or whose pages were renamed, into
+// the series of its new parent folder. Chapters that aren't in Series are left alone.
+func (m *Module) MoveChapter(oldPath string, newPath string) error {
+	chapter := persistence.ChapterInfo{
+		Path:       newPath,
+		Name:       filepath.Base(newPath),
+		ImageCount: m.fileLoader.GetShallowImageCount(newPath),
+	}
+	if firstImagePath, hasImage := m.fileLoader.FindFirstImageShallow(newPath); hasImage {
+		chapter.CoverImage = filepath.Base(firstImagePath)
+	}
+
+	moved, err := m.series.MoveChapter(oldPath, filepath.Dir(newPath), chapter, chapterLess)
+	if err != nil {
+		return err
+	}
+	if moved && m.ctx != nil {
+		runtime.EventsEmit(m.ctx, "series_updated")
+	}
+	return nil
+}
+
+// chapterLess keeps the chapters of a series in natural name order
+func chapterLess(a, b persistence.ChapterInfo) bool {
+	return fileloader.NaturalLess(strings.ToLower(a.Name), strings.ToLower(b.Name))
+}
+
 // GetSeries returns all series entries with direct links
 func (m *Module) GetSeries() []SeriesEntryWithURLs {
 	entries := m.series.GetAll()
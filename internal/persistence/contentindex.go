@@ -0,0 +1,124 @@
+package persistence
+
+import "sync"
+
+const contentIndexFile = "content_index.json"
+
+// ContentEntry is a chapter or gallery on disk, as seen by the duplicate check
+type ContentEntry struct {
+	// Folder of images or .cbz archive
+	Path string `json:"path"`
+	// Normalized names of the entry and of the folder holding it
+	Title  string `json:"title"`
+	Series string `json:"series"`
+	// Chapter number in the name, if any
+	Number string `json:"number,omitempty"`
+	// Release of a download, empty when unknown (library folders)
+	Language string `json:"language,omitempty"`
+	Group    string `json:"group,omitempty"`
+	Pages    int    `json:"pages"`
+	// Difference hashes of the first pages, in hex
+	Hashes []string `json:"hashes,omitempty"`
+	// Modification time of the path when it was indexed (RFC3339Nano)
+	ModTime string `json:"modTime"`
+	// "downloads" or "library"
+	Source string `json:"source"`
+}
+
+type ContentIndexData struct {
+	Entries []ContentEntry `json:"entries"`
+}
+
+type ContentIndexManager struct {
+	data *ContentIndexData
+	mu   sync.RWMutex
+}
+
+func NewContentIndexManager() *ContentIndexManager {
+	cm := &ContentIndexManager{
+		data: &ContentIndexData{Entries: []ContentEntry{}},
+	}
+	cm.Load()
+	return cm
+}
+
+func (cm *ContentIndexManager) Load() error {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	if !fileExists(contentIndexFile) {
+		return saveJSON(contentIndexFile, cm.data)
+	}
+
+	return loadJSON(contentIndexFile, cm.data)
+}
+
+func (cm *ContentIndexManager) GetEntries() []ContentEntry {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	// Return a copy
+	entries := make([]ContentEntry, len(cm.data.Entries))
+	copy(entries, cm.data.Entries)
+	return entries
+}
+
+// SetEntries replaces the whole index, after a rescan
+func (cm *ContentIndexManager) SetEntries(entries []ContentEntry) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	cm.data.Entries = entries
+	saveJSON(contentIndexFile, cm.data)
+}
+
+// PutEntry adds an entry or replaces the one with the same path
+func (cm *ContentIndexManager) PutEntry(entry ContentEntry) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	for i := range cm.data.Entries {
+		if cm.data.Entries[i].Path == entry.Path {
+			cm.data.Entries[i] = entry
+			saveJSON(contentIndexFile, cm.data)
+			return
+		}
+	}
+	cm.data.Entries = append(cm.data.Entries, entry)
+	saveJSON(contentIndexFile, cm.data)
+}
+
+// ReplaceEntry swaps the entry of oldPath for entry, after the chapter was moved or renamed.
+// Does nothing when oldPath isn't indexed. On error the index is left as it was.
+func (cm *ContentIndexManager) ReplaceEntry(oldPath string, entry ContentEntry) error {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	for i := range cm.data.Entries {
+		if cm.data.Entries[i].Path == oldPath {
+			previous := cm.data.Entries[i]
+			cm.data.Entries[i] = entry
+			if err := saveJSON(contentIndexFile, cm.data); err != nil {
+				cm.data.Entries[i] = previous
+				return err
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+// RemoveEntry drops the entry of a path
+func (cm *ContentIndexManager) RemoveEntry(path string) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	entries := []ContentEntry{}
+	for _, entry := range cm.data.Entries {
+		if entry.Path != path {
+			entries = append(entries, entry)
+		}
+	}
+	cm.data.Entries = entries
+	saveJSON(contentIndexFile, cm.data)
+}
@@ -0,0 +1,171 @@
+package persistence
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const cookiesFile = "cookies.json"
+
+// StoredCookie is a cookie kept in the downloader's cookie jar
+type StoredCookie struct {
+	Domain string `json:"domain"` // without the leading dot
+	// Only sent to Domain itself, not to its subdomains
+	HostOnly bool   `json:"hostOnly"`
+	Path     string `json:"path"`
+	Name     string `json:"name"`
+	Value    string `json:"value"`
+	// Unix seconds, 0 for session cookies
+	Expires  int64 `json:"expires,omitempty"`
+	Secure   bool  `json:"secure"`
+	HttpOnly bool  `json:"httpOnly"`
+}
+
+// Expired reports whether the cookie should no longer be sent
+func (c StoredCookie) Expired(now time.Time) bool {
+	return c.Expires != 0 && c.Expires <= now.Unix()
+}
+
+type CookiesData struct {
+	Cookies []StoredCookie `json:"cookies"`
+	// User-Agent per domain. Clearance cookies (Cloudflare) are only valid with the
+	// User-Agent of the browser they were issued to.
+	UserAgents map[string]string `json:"userAgents"`
+}
+
+type CookiesManager struct {
+	data *CookiesData
+	mu   sync.RWMutex
+}
+
+func NewCookiesManager() *CookiesManager {
+	cm := &CookiesManager{
+		data: &CookiesData{Cookies: []StoredCookie{}, UserAgents: map[string]string{}},
+	}
+	cm.Load()
+	return cm
+}
+
+func (cm *CookiesManager) Load() error {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	if !fileExists(cookiesFile) {
+		return savePrivateJSON(cookiesFile, cm.data)
+	}
+
+	if err := loadJSON(cookiesFile, cm.data); err != nil {
+		return err
+	}
+	if cm.data.UserAgents == nil {
+		cm.data.UserAgents = map[string]string{}
+	}
+	// Written world-readable by older versions
+	return os.Chmod(filepath.Join(getDataDir(), cookiesFile), 0600)
+}
+
+// GetCookies returns a copy of the stored cookies, expired ones included
+func (cm *CookiesManager) GetCookies() []StoredCookie {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	cookies := make([]StoredCookie, len(cm.data.Cookies))
+	copy(cookies, cm.data.Cookies)
+	return cookies
+}
+
+// SetCookies adds or replaces cookies (same domain, path and name) and drops expired ones.
+// The file is only written when something changed.
+func (cm *CookiesManager) SetCookies(cookies []StoredCookie) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	now := time.Now()
+	changed := false
+	for _, c := range cookies {
+		c.Domain = normalizeDomain(c.Domain)
+		if c.Path == "" {
+			c.Path = "/"
+		}
+
+		found := false
+		for i, existing := range cm.data.Cookies {
+			if existing.Domain == c.Domain && existing.Path == c.Path && existing.Name == c.Name {
+				found = true
+				if existing != c {
+					cm.data.Cookies[i] = c
+					changed = true
+				}
+				break
+			}
+		}
+		if !found && !c.Expired(now) {
+			cm.data.Cookies = append(cm.data.Cookies, c)
+			changed = true
+		}
+	}
+
+	kept := cm.data.Cookies[:0]
+	for _, c := range cm.data.Cookies {
+		if c.Expired(now) {
+			changed = true
+			continue
+		}
+		kept = append(kept, c)
+	}
+	cm.data.Cookies = kept
+
+	if changed {
+		savePrivateJSON(cookiesFile, cm.data)
+	}
+}
+
+// GetUserAgents returns a copy of the User-Agent overrides
+func (cm *CookiesManager) GetUserAgents() map[string]string {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	agents := make(map[string]string, len(cm.data.UserAgents))
+	for k, v := range cm.data.UserAgents {
+		agents[k] = v
+	}
+	return agents
+}
+
+// SetUserAgent sets the User-Agent override of a domain, an empty value removes it
+func (cm *CookiesManager) SetUserAgent(domain string, userAgent string) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	domain = normalizeDomain(domain)
+	if userAgent = strings.TrimSpace(userAgent); userAgent == "" {
+		delete(cm.data.UserAgents, domain)
+	} else {
+		cm.data.UserAgents[domain] = userAgent
+	}
+	savePrivateJSON(cookiesFile, cm.data)
+}
+
+// ClearDomain removes the cookies of a domain and its subdomains, and its User-Agent override
+func (cm *CookiesManager) ClearDomain(domain string) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	domain = normalizeDomain(domain)
+	kept := []StoredCookie{}
+	for _, c := range cm.data.Cookies {
+		if c.Domain != domain && !strings.HasSuffix(c.Domain, "."+domain) {
+			kept = append(kept, c)
+		}
+	}
+	cm.data.Cookies = kept
+	delete(cm.data.UserAgents, domain)
+	savePrivateJSON(cookiesFile, cm.data)
+}
+
+func normalizeDomain(domain string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(domain), "."))
+}
@@ -1,12 +1,19 @@
 package persistence
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
 	"sync"
 	"time"
 )
 
 const downloaderFile = "downloader.json"
 
+// downloadPagesDir holds the resolved page list of each unfinished job, one file per job,
+// so saving the job list on every finished page stays cheap
+const downloadPagesDir = "download_pages"
+
 type DownloadStatus string
 
 const (
@@ -15,6 +22,11 @@ const (
 	StatusCompleted DownloadStatus = "completed"
 	StatusFailed    DownloadStatus = "failed"
 	StatusCancelled DownloadStatus = "cancelled"
+	StatusPaused    DownloadStatus = "paused"
+	// Queued, waiting for the next download window
+	StatusScheduled DownloadStatus = "scheduled"
+	// Finished, but some pages could not be downloaded (see FailedPages)
+	StatusCompletedWithErrors DownloadStatus = "completed_with_errors"
 )
 
 type DownloadJob struct {
@@ -30,6 +42,41 @@ type DownloadJob struct {
 	CreatedAt   string         `json:"createdAt"`        // ISO 8601 format (RFC3339)
 	CompletedAt *string         `json:"completedAt,omitempty"` // ISO 8601 format (RFC3339)
 	Path        string         `json:"path"`
+	// Resolved page list, so a resume doesn't have to scrape the site again. Stored in
+	// its own file (see GetJobImages), only set here by job lists of older versions.
+	// Dropped once the job completes.
+	Images []DownloadImage `json:"images,omitempty"`
+	// When Images was resolved (RFC3339)
+	ResolvedAt string `json:"resolvedAt,omitempty"`
+	// Delay between page requests for the site, in milliseconds
+	DownloadDelayMs int `json:"downloadDelayMs,omitempty"`
+	// File names of the downloaded pages in page order, inside Path. Set on completion.
+	Files []string `json:"files,omitempty"`
+	// Indices (0-based) of the pages that failed in the last run
+	FailedPages []int `json:"failedPages,omitempty"`
+	// Set on the jobs queued together by a series download
+	BatchID string `json:"batchId,omitempty"`
+	// Chapter details used by the naming template, when the site provides them
+	ChapterNumber string `json:"chapterNumber,omitempty"`
+	Volume        string `json:"volume,omitempty"`
+	ScanGroup     string `json:"scanGroup,omitempty"`
+	Language      string `json:"language,omitempty"`
+	// Queued jobs with a higher priority start first
+	Priority int `json:"priority,omitempty"`
+	// Position in the site queue among jobs of the same priority, lower starts first
+	QueueOrder int64 `json:"queueOrder,omitempty"`
+	// Size of the downloaded pages in bytes, set on completion
+	Bytes int64 `json:"bytes,omitempty"`
+	// Local copy the duplicate check found when the job was queued
+	DuplicateOf string `json:"duplicateOf,omitempty"`
+}
+
+// DownloadImage is a resolved page of a DownloadJob
+type DownloadImage struct {
+	URL      string            `json:"url"`
+	Filename string            `json:"filename"`
+	Index    int               `json:"index"`
+	Headers  map[string]string `json:"headers,omitempty"`
 }
 
 type DownloaderData struct {
@@ -57,7 +104,57 @@ func (dm *DownloaderManager) Load() error {
 		return saveJSON(downloaderFile, dm.data)
 	}
 
-	return loadJSON(downloaderFile, dm.data)
+	if err := loadJSON(downloaderFile, dm.data); err != nil {
+		return err
+	}
+
+	// Move the page lists older versions kept inline to their own files
+	moved := false
+	for i := range dm.data.Jobs {
+		if len(dm.data.Jobs[i].Images) > 0 {
+			if err := saveJobImages(dm.data.Jobs[i].ID, dm.data.Jobs[i].Images); err != nil {
+				fmt.Printf("[DownloaderManager] Failed to move page list of job %s: %v\n", dm.data.Jobs[i].ID, err)
+				continue
+			}
+			dm.data.Jobs[i].Images = nil
+			moved = true
+		}
+	}
+	if moved {
+		return saveJSON(downloaderFile, dm.data)
+	}
+	return nil
+}
+
+// GetJobImages returns the stored page list of a job, nil when there is none
+func (dm *DownloaderManager) GetJobImages(id string) []DownloadImage {
+	dm.mu.RLock()
+	defer dm.mu.RUnlock()
+
+	var images []DownloadImage
+	if err := loadJSON(jobImagesFile(id), &images); err != nil {
+		return nil
+	}
+	return images
+}
+
+func jobImagesFile(id string) string {
+	return filepath.Join(downloadPagesDir, filepath.Base(id)+".json")
+}
+
+// saveJobImages writes the page list of a job, an empty list deletes it
+func saveJobImages(id string, images []DownloadImage) error {
+	if len(images) == 0 {
+		err := os.Remove(filepath.Join(getDataDir(), jobImagesFile(id)))
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if err := os.MkdirAll(filepath.Join(getDataDir(), downloadPagesDir), 0755); err != nil {
+		return err
+	}
+	return saveJSON(jobImagesFile(id), images)
 }
 
 func (dm *DownloaderManager) Save() error {
@@ -76,12 +173,25 @@ func (dm *DownloaderManager) GetJobs() []DownloadJob {
 	return jobs
 }
 
-func (dm *DownloaderManager) AddJob(job DownloadJob) {
+// AddJob stores a new job. On error the job is not kept.
+func (dm *DownloaderManager) AddJob(job DownloadJob) error {
 	dm.mu.Lock()
 	defer dm.mu.Unlock()
 
-	dm.data.Jobs = append([]DownloadJob{job}, dm.data.Jobs...) // Add to top
-	saveJSON(downloaderFile, dm.data)
+	if len(job.Images) > 0 {
+		if err := saveJobImages(job.ID, job.Images); err != nil {
+			fmt.Printf("[DownloaderManager] Failed to save page list of job %s: %v\n", job.ID, err)
+		}
+		job.Images = nil
+	}
+	jobs := dm.data.Jobs
+	dm.data.Jobs = append([]DownloadJob{job}, jobs...) // Add to top
+	if err := saveJSON(downloaderFile, dm.data); err != nil {
+		dm.data.Jobs = jobs
+		saveJobImages(job.ID, nil)
+		return err
+	}
+	return nil
 }
 
 func (dm *DownloaderManager) UpdateJob(id string, updates map[string]interface{}) {
@@ -119,6 +229,64 @@ func (dm *DownloaderManager) UpdateJob(id string, updates map[string]interface{}
 					if p, ok := v.(string); ok {
 						dm.data.Jobs[i].Path = p
 					}
+				case "images":
+					if imgs, ok := v.([]DownloadImage); ok {
+						if err := saveJobImages(id, imgs); err != nil {
+							fmt.Printf("[DownloaderManager] Failed to save page list of job %s: %v\n", id, err)
+						}
+					}
+				case "resolvedAt":
+					if t, ok := v.(string); ok {
+						dm.data.Jobs[i].ResolvedAt = t
+					}
+				case "downloadDelayMs":
+					if d, ok := v.(int); ok {
+						dm.data.Jobs[i].DownloadDelayMs = d
+					}
+				case "files":
+					if f, ok := v.([]string); ok {
+						dm.data.Jobs[i].Files = f
+					}
+				case "failedPages":
+					if f, ok := v.([]int); ok {
+						dm.data.Jobs[i].FailedPages = f
+					}
+				case "batchId":
+					if b, ok := v.(string); ok {
+						dm.data.Jobs[i].BatchID = b
+					}
+				case "chapterNumber":
+					if n, ok := v.(string); ok {
+						dm.data.Jobs[i].ChapterNumber = n
+					}
+				case "volume":
+					if vol, ok := v.(string); ok {
+						dm.data.Jobs[i].Volume = vol
+					}
+				case "scanGroup":
+					if g, ok := v.(string); ok {
+						dm.data.Jobs[i].ScanGroup = g
+					}
+				case "language":
+					if l, ok := v.(string); ok {
+						dm.data.Jobs[i].Language = l
+					}
+				case "priority":
+					if p, ok := v.(int); ok {
+						dm.data.Jobs[i].Priority = p
+					}
+				case "queueOrder":
+					if o, ok := v.(int64); ok {
+						dm.data.Jobs[i].QueueOrder = o
+					}
+				case "duplicateOf":
+					if d, ok := v.(string); ok {
+						dm.data.Jobs[i].DuplicateOf = d
+					}
+				case "bytes":
+					if b, ok := v.(int64); ok {
+						dm.data.Jobs[i].Bytes = b
+					}
 				}
 			}
 			break
@@ -137,6 +305,7 @@ func (dm *DownloaderManager) RemoveJob(id string) {
 			newJobs = append(newJobs, job)
 		}
 	}
+	saveJobImages(id, nil)
 	dm.data.Jobs = newJobs
 	saveJSON(downloaderFile, dm.data)
 }
@@ -145,6 +314,9 @@ func (dm *DownloaderManager) ClearJobs() {
 	dm.mu.Lock()
 	defer dm.mu.Unlock()
 
+	for _, job := range dm.data.Jobs {
+		saveJobImages(job.ID, nil)
+	}
 	dm.data.Jobs = []DownloadJob{}
 	saveJSON(downloaderFile, dm.data)
 }
@@ -0,0 +1,77 @@
+package persistence
+
+import "sync"
+
+const mangaDexAuthFile = "mangadex_auth.json"
+
+// MangaDexAuth is the MangaDex personal API client and its session. The password is
+// never stored, only the tokens it was exchanged for. The file is readable by the
+// current user only.
+type MangaDexAuth struct {
+	ClientID     string `json:"clientId"`
+	ClientSecret string `json:"clientSecret"`
+	Username     string `json:"username"`
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken"`
+	// When the access token stops working (RFC3339)
+	ExpiresAt string `json:"expiresAt,omitempty"`
+	// Last time the follows feed was read (RFC3339), new chapters are looked for after it
+	FeedCheckedAt string `json:"feedCheckedAt,omitempty"`
+}
+
+type MangaDexAuthManager struct {
+	data *MangaDexAuth
+	mu   sync.RWMutex
+}
+
+func NewMangaDexAuthManager() *MangaDexAuthManager {
+	am := &MangaDexAuthManager{data: &MangaDexAuth{}}
+	am.Load()
+	return am
+}
+
+func (am *MangaDexAuthManager) Load() error {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	if !fileExists(mangaDexAuthFile) {
+		return nil
+	}
+
+	return loadJSON(mangaDexAuthFile, am.data)
+}
+
+// Get returns a copy of the stored session
+func (am *MangaDexAuthManager) Get() MangaDexAuth {
+	am.mu.RLock()
+	defer am.mu.RUnlock()
+
+	return *am.data
+}
+
+// Set replaces the stored session
+func (am *MangaDexAuthManager) Set(auth MangaDexAuth) error {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	*am.data = auth
+	return savePrivateJSON(mangaDexAuthFile, am.data)
+}
+
+// SetFeedCheckedAt records the last read of the follows feed
+func (am *MangaDexAuthManager) SetFeedCheckedAt(at string) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	am.data.FeedCheckedAt = at
+	savePrivateJSON(mangaDexAuthFile, am.data)
+}
+
+// Clear forgets the session, the client ID is kept for the next login
+func (am *MangaDexAuthManager) Clear() error {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	am.data = &MangaDexAuth{ClientID: am.data.ClientID}
+	return savePrivateJSON(mangaDexAuthFile, am.data)
+}
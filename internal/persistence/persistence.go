@@ -45,6 +45,23 @@ func saveJSON(filename string, data interface{}) error {
 	return os.WriteFile(filePath, jsonData, 0644)
 }
 
+// savePrivateJSON saves data like saveJSON, readable by the current user only.
+// Used for credentials and tokens.
+func savePrivateJSON(filename string, data interface{}) error {
+	filePath := filepath.Join(getDataDir(), filename)
+
+	jsonData, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(filePath, jsonData, 0600); err != nil {
+		return err
+	}
+	// WriteFile keeps the mode of a file that already existed
+	return os.Chmod(filePath, 0600)
+}
+
 // loadJSON loads JSON data from the specified file
 func loadJSON(filename string, target interface{}) error {
 	filePath := filepath.Join(getDataDir(), filename)
@@ -70,3 +87,24 @@ func GetTempDir() string {
 	os.MkdirAll(tempDir, 0755)
 	return tempDir
 }
+
+// GetScrapersDir returns the directory holding user-defined scraper definitions
+func GetScrapersDir() string {
+	scrapersDir := filepath.Join(getDataDir(), "scrapers")
+	os.MkdirAll(scrapersDir, 0755)
+	return scrapersDir
+}
+
+// GetPluginsDir returns the directory holding user JavaScript downloader plugins
+func GetPluginsDir() string {
+	pluginsDir := filepath.Join(getDataDir(), "plugins")
+	os.MkdirAll(pluginsDir, 0755)
+	return pluginsDir
+}
+
+// GetHooksDir returns the directory holding the output logs of download hooks
+func GetHooksDir() string {
+	hooksDir := filepath.Join(getDataDir(), "hooks")
+	os.MkdirAll(hooksDir, 0755)
+	return hooksDir
+}
@@ -1,6 +1,7 @@
 package persistence
 
 import (
+	"path/filepath"
 	"sort"
 	"sync"
 	"time"
@@ -108,6 +109,158 @@ func (sm *SeriesManager) Add(entry SeriesEntry) error {
 	return saveJSON(seriesFile, sm.series)
 }
 
+// UpsertChapter adds or replaces one chapter of a series in a single step, registering the
+// series when it doesn't exist yet. less orders the chapters. A series without a cover gets
+// cover, or the cover of the first chapter when cover is empty; an existing cover is kept.
+// Returns the number of chapters.
+func (sm *SeriesManager) UpsertChapter(seriesPath string, chapter ChapterInfo, less func(a, b ChapterInfo) bool, cover string) (int, error) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	id := generateID(seriesPath)
+	index := -1
+	for i, e := range sm.series.Entries {
+		if e.ID == id {
+			index = i
+			break
+		}
+	}
+	var entry SeriesEntry
+	if index >= 0 {
+		entry = sm.series.Entries[index]
+	} else {
+		entry = SeriesEntry{
+			ID:      id,
+			Path:    seriesPath,
+			Name:    filepath.Base(seriesPath),
+			AddedAt: time.Now().Format(time.RFC3339),
+		}
+	}
+
+	// A new slice, copies handed out by Get and GetAll keep sharing the old one
+	chapters := make([]ChapterInfo, 0, len(entry.Chapters)+1)
+	for _, ch := range entry.Chapters {
+		if ch.Path != chapter.Path {
+			chapters = append(chapters, ch)
+		}
+	}
+	chapters = append(chapters, chapter)
+	sort.SliceStable(chapters, func(i, j int) bool { return less(chapters[i], chapters[j]) })
+	entry.Chapters = chapters
+
+	if entry.CoverImage == "" {
+		entry.CoverImage = cover
+	}
+	if entry.CoverImage == "" {
+		entry.CoverImage = filepath.Join(chapters[0].Path, chapters[0].CoverImage)
+	}
+
+	if index >= 0 {
+		sm.series.Entries[index] = entry
+	} else {
+		sm.series.Entries = append(sm.series.Entries, entry)
+		sort.Slice(sm.series.Entries, func(i, j int) bool {
+			return sm.series.Entries[i].Name < sm.series.Entries[j].Name
+		})
+	}
+	return len(chapters), saveJSON(seriesFile, sm.series)
+}
+
+// MoveChapter re-registers the chapter at oldPath as chapter, in the series at seriesPath,
+// after its folder was moved or its pages renamed. A series cover inside the old folder
+// becomes the cover of the moved chapter, a series left without chapters is dropped.
+// Reports whether oldPath was registered. On error nothing changes.
+func (sm *SeriesManager) MoveChapter(oldPath string, seriesPath string, chapter ChapterInfo, less func(a, b ChapterInfo) bool) (bool, error) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	from := -1
+	for i, e := range sm.series.Entries {
+		for _, ch := range e.Chapters {
+			if ch.Path == oldPath {
+				from = i
+				break
+			}
+		}
+		if from >= 0 {
+			break
+		}
+	}
+	if from < 0 {
+		return false, nil
+	}
+
+	// Built as a new slice, so a failed save can put the old one back
+	previous := sm.series.Entries
+	id := generateID(seriesPath)
+	coverMoved := false
+	entries := make([]SeriesEntry, 0, len(previous)+1)
+	to := -1
+	for i, e := range previous {
+		if i == from {
+			chapters := make([]ChapterInfo, 0, len(e.Chapters))
+			for _, ch := range e.Chapters {
+				if ch.Path != oldPath {
+					chapters = append(chapters, ch)
+				}
+			}
+			e.Chapters = chapters
+			if e.CoverImage != "" && filepath.Dir(e.CoverImage) == filepath.Clean(oldPath) {
+				coverMoved = true
+				e.CoverImage = ""
+				if e.ID != id && len(chapters) > 0 {
+					e.CoverImage = filepath.Join(chapters[0].Path, chapters[0].CoverImage)
+				}
+			}
+			if len(chapters) == 0 && e.ID != id {
+				continue
+			}
+		}
+		if e.ID == id {
+			to = len(entries)
+		}
+		entries = append(entries, e)
+	}
+
+	var entry SeriesEntry
+	if to >= 0 {
+		entry = entries[to]
+	} else {
+		entry = SeriesEntry{
+			ID:      id,
+			Path:    seriesPath,
+			Name:    filepath.Base(seriesPath),
+			AddedAt: time.Now().Format(time.RFC3339),
+		}
+	}
+	chapters := make([]ChapterInfo, 0, len(entry.Chapters)+1)
+	for _, ch := range entry.Chapters {
+		if ch.Path != chapter.Path {
+			chapters = append(chapters, ch)
+		}
+	}
+	chapters = append(chapters, chapter)
+	sort.SliceStable(chapters, func(i, j int) bool { return less(chapters[i], chapters[j]) })
+	entry.Chapters = chapters
+	if coverMoved || entry.CoverImage == "" {
+		entry.CoverImage = filepath.Join(chapter.Path, chapter.CoverImage)
+	}
+
+	if to >= 0 {
+		entries[to] = entry
+	} else {
+		entries = append(entries, entry)
+		sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	}
+
+	sm.series.Entries = entries
+	if err := saveJSON(seriesFile, sm.series); err != nil {
+		sm.series.Entries = previous
+		return false, err
+	}
+	return true, nil
+}
+
 // Remove removes a series entry
 func (sm *SeriesManager) Remove(path string) error {
 	sm.mu.Lock()
@@ -1,6 +1,7 @@
 package persistence
 
 import (
+	"encoding/json"
 	"fmt"
 	"sync"
 	"time"
@@ -52,6 +53,8 @@ type Settings struct {
 	EnabledMenuItems map[string]bool `json:"enabledMenuItems"`
 	// Download path
 	DownloadPath string `json:"downloadPath"`
+	// Naming template for downloaded pages, relative to DownloadPath (empty = Site/Series/Chapter/original name)
+	DownloadNamingTemplate string `json:"downloadNamingTemplate"`
 	// Clipboard auto monitor
 	ClipboardAutoMonitor bool `json:"clipboardAutoMonitor"`
 	// Auto resume incomplete downloads
@@ -62,6 +65,87 @@ type Settings struct {
 	RestoreTabs bool `json:"restoreTabs"`
 	// Saved tabs state (JSON string)
 	SavedTabs string `json:"savedTabs"`
+	// Play short animated previews for GIF/WebP/AVIF pages in thumbnail grids
+	AnimatedThumbnails bool `json:"animatedThumbnails"`
+	// Download limits per site ID ("default" applies to sites without an entry)
+	SiteLimits map[string]SiteLimit `json:"siteLimits"`
+	// Output and bandwidth options per site ID
+	SiteOptions map[string]SiteOptions `json:"siteOptions"`
+	// Proxy for downloads (http://host:port or socks5://host:port), empty uses the system environment
+	DownloadProxy string `json:"downloadProxy"`
+	// Add completed chapters to the Series page right away
+	AutoAddToSeries bool `json:"autoAddToSeries"`
+	// Package completed chapters as CBZ with a ComicInfo.xml, sites can override it in SiteOptions
+	PackageCBZ bool `json:"packageCbz"`
+	// Delete the loose images once a chapter is packaged as CBZ
+	CBZDeleteImages bool `json:"cbzDeleteImages"`
+	// Download speed cap for all downloads together in KB/s (0 = unlimited)
+	BandwidthLimitKBps int `json:"bandwidthLimitKbps"`
+	// Windows in which queued downloads may start (empty = any time)
+	DownloadSchedule []DownloadWindow `json:"downloadSchedule"`
+	// Space in MB left free on the download disk, jobs that would use it are paused
+	MinFreeSpaceMB int `json:"minFreeSpaceMb"`
+	// Maximum size of the download folder in MB (0 = no quota)
+	DownloadQuotaMB int `json:"downloadQuotaMb"`
+	// What to do when a download is already on disk from another source: "warn", "skip" or "off"
+	DuplicateAction string `json:"duplicateAction"`
+	// Chapter languages fetched from MangaDex, as language codes (empty = all languages)
+	MangaDexLanguages []string `json:"mangadexLanguages"`
+	// Download MangaDex chapters in data-saver quality (smaller, recompressed pages)
+	MangaDexDataSaver bool `json:"mangadexDataSaver"`
+	// Commands and webhooks run on download events
+	Hooks []DownloadHook `json:"hooks"`
+	// How often subscriptions are checked for new chapters, in minutes (0 = only manually)
+	SubscriptionCheckMinutes int `json:"subscriptionCheckMinutes"`
+}
+
+// SiteLimit controls how hard the downloader hits a site
+type SiteLimit struct {
+	// Jobs downloaded at the same time (0 = unlimited)
+	MaxJobs int `json:"maxJobs"`
+	// Pages downloaded in parallel within one job
+	PageConcurrency int `json:"pageConcurrency"`
+	// Requests per second to each host of the site, metadata included (0 = unlimited)
+	RequestsPerSecond float64 `json:"requestsPerSecond"`
+	// Retries for a failed page
+	MaxRetries int `json:"maxRetries"`
+	// Delay before the first retry in milliseconds, doubled on every attempt
+	BackoffMs int `json:"backoffMs"`
+	// Extra host suffixes (CDNs, APIs) that belong to the site, the site ID itself always counts
+	Hosts []string `json:"hosts,omitempty"`
+}
+
+// SiteOptions holds what a site's downloads do besides requests: packaging and bandwidth
+type SiteOptions struct {
+	// Package completed chapters as CBZ, overriding Settings.PackageCBZ (nil = follow it)
+	PackageCBZ *bool `json:"packageCbz,omitempty"`
+	// Download speed cap for the site in KB/s (0 = unlimited)
+	BandwidthKBps int `json:"bandwidthKbps,omitempty"`
+}
+
+// DownloadWindow is a time range in which downloads may run. A window whose end is
+// before its start runs past midnight and belongs to the day it starts.
+type DownloadWindow struct {
+	// Days of the week, 0 = Sunday (empty = every day)
+	Days []int `json:"days,omitempty"`
+	// "HH:MM", local time
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// DownloadHook runs a command and/or posts a webhook when a download event happens
+type DownloadHook struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+	// Events that trigger the hook: "completed", "completed_with_errors", "failed", "batch_completed", "new_chapters"
+	Events []string `json:"events"`
+	// Command line run without a shell. Placeholders: {event} {path} {series} {chapter} {site} {url} {error}
+	Command string `json:"command,omitempty"`
+	// URL the event is POSTed to as JSON
+	WebhookURL string `json:"webhookUrl,omitempty"`
+	// Seconds before the hook is stopped (0 = 30)
+	TimeoutSeconds int `json:"timeoutSeconds,omitempty"`
 }
 
 // DefaultSettings returns the default settings
@@ -98,12 +182,23 @@ func DefaultSettings() *Settings {
 			"settings": true,
 			"download": true,
 		},
-		DownloadPath:         "", // empty means default
-		ClipboardAutoMonitor: false,
-		AutoResumeDownloads:  false,
-		TabMemorySaving:      true,
-		RestoreTabs:          false,
-		SavedTabs:            "",
+		DownloadPath:             "", // empty means default
+		DownloadNamingTemplate:   "", // empty means default
+		ClipboardAutoMonitor:     false,
+		AutoResumeDownloads:      false,
+		TabMemorySaving:          true,
+		RestoreTabs:              false,
+		SavedTabs:                "",
+		AnimatedThumbnails:       false,
+		SubscriptionCheckMinutes: 360,
+		MinFreeSpaceMB:           500,
+		SiteLimits: map[string]SiteLimit{
+			"default":      {MaxJobs: 3, PageConcurrency: 2, MaxRetries: 3, BackoffMs: 2000},
+			"hitomi.la":    {MaxJobs: 2, PageConcurrency: 2, MaxRetries: 3, BackoffMs: 2000, Hosts: []string{"gold-usergeneratedcontent.net"}},
+			"mangadex.org": {MaxJobs: 2, PageConcurrency: 6, RequestsPerSecond: 4, MaxRetries: 3, BackoffMs: 2000, Hosts: []string{"mangadex.network"}},
+			"zonatmo":      {MaxJobs: 3, PageConcurrency: 2, MaxRetries: 3, BackoffMs: 2000, Hosts: []string{"zonatmo.com"}},
+			"manhwaweb":    {MaxJobs: 3, PageConcurrency: 2, MaxRetries: 3, BackoffMs: 2000, Hosts: []string{"manhwaweb.com", "manhwawebbackend-production.up.railway.app"}},
+		},
 	}
 }
 
@@ -160,11 +255,56 @@ func (sm *SettingsManager) Load() error {
 	if err := loadJSON(settingsFile, settings); err != nil {
 		return err
 	}
+	migrateSiteOptions(settings)
+	migrateSiteHosts(settings)
 
 	sm.settings = settings
 	return nil
 }
 
+// migrateSiteOptions moves the packaging and bandwidth options that older versions kept
+// in siteLimits into siteOptions. Packaging for "default" becomes the global setting.
+func migrateSiteOptions(settings *Settings) {
+	// Not in the defaults, so nil means the file has no siteOptions yet
+	if settings.SiteOptions != nil {
+		return
+	}
+	var legacy struct {
+		SiteLimits map[string]struct {
+			PackageCBZ    bool `json:"packageCbz"`
+			BandwidthKBps int  `json:"bandwidthKbps"`
+		} `json:"siteLimits"`
+	}
+	if err := loadJSON(settingsFile, &legacy); err != nil {
+		return
+	}
+	settings.SiteOptions = make(map[string]SiteOptions)
+	settings.PackageCBZ = legacy.SiteLimits["default"].PackageCBZ
+	for siteID, old := range legacy.SiteLimits {
+		options := SiteOptions{BandwidthKBps: old.BandwidthKBps}
+		if siteID != "default" && old.PackageCBZ != settings.PackageCBZ {
+			// Sites with their own entry never inherited "default"
+			packageCBZ := old.PackageCBZ
+			options.PackageCBZ = &packageCBZ
+		}
+		if options.PackageCBZ != nil || options.BandwidthKBps != 0 {
+			settings.SiteOptions[siteID] = options
+		}
+	}
+}
+
+// migrateSiteHosts gives the built-in sites stored by older versions the hosts added to
+// their defaults since (MangaDex@Home nodes under mangadex.network). Hosts can't be edited
+// in settings, so an entry without any only means the file predates them.
+func migrateSiteHosts(settings *Settings) {
+	for siteID, def := range DefaultSettings().SiteLimits {
+		if l, ok := settings.SiteLimits[siteID]; ok && len(l.Hosts) == 0 && len(def.Hosts) > 0 {
+			l.Hosts = def.Hosts
+			settings.SiteLimits[siteID] = l
+		}
+	}
+}
+
 // Flush immediately saves any pending changes to disk
 func (sm *SettingsManager) Flush() error {
 	sm.tmMu.Lock()
@@ -331,6 +471,10 @@ func (sm *SettingsManager) Update(updates map[string]interface{}) error {
 			if v, ok := value.(string); ok {
 				sm.settings.DownloadPath = v
 			}
+		case "downloadNamingTemplate":
+			if v, ok := value.(string); ok {
+				sm.settings.DownloadNamingTemplate = v
+			}
 		case "clipboardAutoMonitor":
 			if v, ok := value.(bool); ok {
 				sm.settings.ClipboardAutoMonitor = v
@@ -351,6 +495,113 @@ func (sm *SettingsManager) Update(updates map[string]interface{}) error {
 			if v, ok := value.(string); ok {
 				sm.settings.SavedTabs = v
 			}
+		case "animatedThumbnails":
+			if v, ok := value.(bool); ok {
+				sm.settings.AnimatedThumbnails = v
+			}
+		case "subscriptionCheckMinutes":
+			if v, ok := value.(float64); ok {
+				sm.settings.SubscriptionCheckMinutes = int(v)
+			} else if v, ok := value.(int); ok {
+				sm.settings.SubscriptionCheckMinutes = v
+			}
+		case "autoAddToSeries":
+			if v, ok := value.(bool); ok {
+				sm.settings.AutoAddToSeries = v
+			}
+		case "packageCbz":
+			if v, ok := value.(bool); ok {
+				sm.settings.PackageCBZ = v
+			}
+		case "cbzDeleteImages":
+			if v, ok := value.(bool); ok {
+				sm.settings.CBZDeleteImages = v
+			}
+		case "downloadProxy":
+			if v, ok := value.(string); ok {
+				sm.settings.DownloadProxy = v
+			}
+		case "bandwidthLimitKbps":
+			if v, ok := value.(float64); ok {
+				sm.settings.BandwidthLimitKBps = int(v)
+			} else if v, ok := value.(int); ok {
+				sm.settings.BandwidthLimitKBps = v
+			}
+		case "duplicateAction":
+			if v, ok := value.(string); ok {
+				sm.settings.DuplicateAction = v
+			}
+		case "mangadexDataSaver":
+			if v, ok := value.(bool); ok {
+				sm.settings.MangaDexDataSaver = v
+			}
+		case "mangadexLanguages":
+			if v, ok := value.([]interface{}); ok {
+				languages := []string{}
+				for _, item := range v {
+					if lang, ok := item.(string); ok && lang != "" {
+						languages = append(languages, lang)
+					}
+				}
+				sm.settings.MangaDexLanguages = languages
+			}
+		case "minFreeSpaceMb":
+			if v, ok := value.(float64); ok {
+				sm.settings.MinFreeSpaceMB = int(v)
+			} else if v, ok := value.(int); ok {
+				sm.settings.MinFreeSpaceMB = v
+			}
+		case "downloadQuotaMb":
+			if v, ok := value.(float64); ok {
+				sm.settings.DownloadQuotaMB = int(v)
+			} else if v, ok := value.(int); ok {
+				sm.settings.DownloadQuotaMB = v
+			}
+		case "downloadSchedule":
+			if v, ok := value.([]interface{}); ok {
+				var windows []DownloadWindow
+				if data, err := json.Marshal(v); err == nil {
+					if err := json.Unmarshal(data, &windows); err == nil {
+						sm.settings.DownloadSchedule = windows
+					} else {
+						fmt.Printf("Failed to update downloadSchedule: %v\n", err)
+					}
+				}
+			}
+		case "hooks":
+			if v, ok := value.([]interface{}); ok {
+				var hooks []DownloadHook
+				if data, err := json.Marshal(v); err == nil {
+					if err := json.Unmarshal(data, &hooks); err == nil {
+						sm.settings.Hooks = hooks
+					} else {
+						fmt.Printf("Failed to update hooks: %v\n", err)
+					}
+				}
+			}
+		case "siteOptions":
+			if v, ok := value.(map[string]interface{}); ok {
+				newMap := make(map[string]SiteOptions)
+				if data, err := json.Marshal(v); err == nil {
+					if err := json.Unmarshal(data, &newMap); err == nil {
+						sm.settings.SiteOptions = newMap
+					} else {
+						fmt.Printf("Failed to update siteOptions: %v\n", err)
+					}
+				}
+			}
+		case "siteLimits":
+			// Nested objects, decoded through JSON instead of field by field
+			if v, ok := value.(map[string]interface{}); ok {
+				newMap := make(map[string]SiteLimit)
+				if data, err := json.Marshal(v); err == nil {
+					if err := json.Unmarshal(data, &newMap); err == nil {
+						sm.settings.SiteLimits = newMap
+					} else {
+						fmt.Printf("Failed to update siteLimits: %v\n", err)
+					}
+				}
+			}
 		}
 
 	}
@@ -0,0 +1,129 @@
+package persistence
+
+import "sync"
+
+const subscriptionsFile = "subscriptions.json"
+
+// Subscription is a followed series that is checked for new chapters
+type Subscription struct {
+	ID         string `json:"id"`
+	URL        string `json:"url"`
+	Site       string `json:"site"`
+	SeriesName string `json:"seriesName"`
+	// Only chapters in these languages (empty = any)
+	Languages []string `json:"languages,omitempty"`
+	// Only chapters from these scanlation groups, matched case-insensitively (empty = any)
+	Groups []string `json:"groups,omitempty"`
+	// Queue new chapters automatically instead of only notifying
+	AutoDownload bool `json:"autoDownload"`
+	// Chapter IDs seen so far, anything else is new
+	KnownChapters []string `json:"knownChapters"`
+	CreatedAt     string   `json:"createdAt"`               // ISO 8601 format (RFC3339)
+	LastCheckedAt string   `json:"lastCheckedAt,omitempty"` // ISO 8601 format (RFC3339)
+	LastError     string   `json:"lastError,omitempty"`
+}
+
+type SubscriptionsData struct {
+	Subscriptions []Subscription `json:"subscriptions"`
+}
+
+type SubscriptionsManager struct {
+	data *SubscriptionsData
+	mu   sync.RWMutex
+}
+
+func NewSubscriptionsManager() *SubscriptionsManager {
+	sm := &SubscriptionsManager{
+		data: &SubscriptionsData{Subscriptions: []Subscription{}},
+	}
+	sm.Load()
+	return sm
+}
+
+func (sm *SubscriptionsManager) Load() error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	if !fileExists(subscriptionsFile) {
+		return saveJSON(subscriptionsFile, sm.data)
+	}
+
+	return loadJSON(subscriptionsFile, sm.data)
+}
+
+func (sm *SubscriptionsManager) GetSubscriptions() []Subscription {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	// Return a copy
+	subs := make([]Subscription, len(sm.data.Subscriptions))
+	copy(subs, sm.data.Subscriptions)
+	return subs
+}
+
+func (sm *SubscriptionsManager) AddSubscription(sub Subscription) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	sm.data.Subscriptions = append(sm.data.Subscriptions, sub)
+	saveJSON(subscriptionsFile, sm.data)
+}
+
+func (sm *SubscriptionsManager) UpdateSubscription(id string, updates map[string]interface{}) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	for i := range sm.data.Subscriptions {
+		if sm.data.Subscriptions[i].ID == id {
+			sub := &sm.data.Subscriptions[i]
+			for k, v := range updates {
+				switch k {
+				case "seriesName":
+					if s, ok := v.(string); ok {
+						sub.SeriesName = s
+					}
+				case "languages":
+					if l, ok := v.([]string); ok {
+						sub.Languages = l
+					}
+				case "groups":
+					if g, ok := v.([]string); ok {
+						sub.Groups = g
+					}
+				case "autoDownload":
+					if a, ok := v.(bool); ok {
+						sub.AutoDownload = a
+					}
+				case "knownChapters":
+					if c, ok := v.([]string); ok {
+						sub.KnownChapters = c
+					}
+				case "lastCheckedAt":
+					if t, ok := v.(string); ok {
+						sub.LastCheckedAt = t
+					}
+				case "lastError":
+					if e, ok := v.(string); ok {
+						sub.LastError = e
+					}
+				}
+			}
+			break
+		}
+	}
+	saveJSON(subscriptionsFile, sm.data)
+}
+
+func (sm *SubscriptionsManager) RemoveSubscription(id string) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	newSubs := []Subscription{}
+	for _, sub := range sm.data.Subscriptions {
+		if sub.ID != id {
+			newSubs = append(newSubs, sub)
+		}
+	}
+	sm.data.Subscriptions = newSubs
+	saveJSON(subscriptionsFile, sm.data)
+}
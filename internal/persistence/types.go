@@ -26,4 +26,6 @@ type ImageInfo struct {
 	Size         int64  `json:"size"`
 	Index        int    `json:"index"`
 	ModTime      int64  `json:"modTime"`
+	Animated     bool   `json:"animated"`
+	PreviewURL   string `json:"previewUrl,omitempty"` // Animated thumbnail, only set when enabled in settings
 }
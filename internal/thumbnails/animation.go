@@ -0,0 +1,555 @@
+package thumbnails
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color/palette"
+	"image/gif"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gen2brain/avif"
+	"golang.org/x/image/draw"
+	"golang.org/x/image/webp"
+)
+
+const (
+	// Animated previews are kept small and short so they stay cheap to serve in grids
+	previewWidth     = 200
+	previewHeight    = 300
+	previewMaxFrames = 48
+	// Browsers play delays below this as 100ms, we do the same so previews don't spin
+	minFrameDelay = 2 // centiseconds
+)
+
+// animation holds fully composited frames of an animated image
+type animation struct {
+	frames []*image.RGBA
+	delays []int // centiseconds, one per frame
+}
+
+// IsAnimated reports whether an image file contains more than one frame.
+// Only GIF, WebP and AVIF can be animated; other formats return false without opening the file.
+func IsAnimated(imagePath string) bool {
+	if !canAnimate(imagePath) {
+		return false
+	}
+
+	file, err := os.Open(imagePath)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	r := bufio.NewReader(file)
+	switch strings.ToLower(filepath.Ext(imagePath)) {
+	case ".gif":
+		return isAnimatedGIF(r)
+	case ".webp":
+		return isAnimatedWebP(r)
+	default:
+		return isAnimatedAVIF(r)
+	}
+}
+
+// animatedCache holds the IsAnimatedFile results, map[path]animatedEntry
+var animatedCache sync.Map
+
+type animatedEntry struct {
+	size     int64
+	modTime  time.Time
+	animated bool
+}
+
+// IsAnimatedFile is IsAnimated for a file already stat'ed by a folder listing. The result
+// is kept until the file's size or modification time change, so listing a folder again
+// doesn't read every GIF, WebP and AVIF in it again.
+func IsAnimatedFile(imagePath string, info os.FileInfo) bool {
+	if !canAnimate(imagePath) {
+		return false
+	}
+	if cached, ok := animatedCache.Load(imagePath); ok {
+		entry := cached.(animatedEntry)
+		if entry.size == info.Size() && entry.modTime.Equal(info.ModTime()) {
+			return entry.animated
+		}
+	}
+	animated := IsAnimated(imagePath)
+	animatedCache.Store(imagePath, animatedEntry{size: info.Size(), modTime: info.ModTime(), animated: animated})
+	return animated
+}
+
+// canAnimate reports whether the format of a file supports more than one frame
+func canAnimate(imagePath string) bool {
+	ext := strings.ToLower(filepath.Ext(imagePath))
+	return ext == ".gif" || ext == ".webp" || ext == ".avif"
+}
+
+// isAnimatedGIF walks the GIF block structure and stops at the second image descriptor,
+// so pixel data is skipped instead of decoded
+func isAnimatedGIF(r *bufio.Reader) bool {
+	header := make([]byte, 13)
+	if _, err := io.ReadFull(r, header); err != nil || string(header[:3]) != "GIF" {
+		return false
+	}
+	if header[10]&0x80 != 0 {
+		if _, err := r.Discard(3 * (1 << ((header[10] & 0x07) + 1))); err != nil {
+			return false
+		}
+	}
+
+	frames := 0
+	for {
+		blockType, err := r.ReadByte()
+		if err != nil {
+			return false
+		}
+		switch blockType {
+		case 0x2C: // Image descriptor
+			frames++
+			if frames > 1 {
+				return true
+			}
+			desc := make([]byte, 9)
+			if _, err := io.ReadFull(r, desc); err != nil {
+				return false
+			}
+			if desc[8]&0x80 != 0 {
+				if _, err := r.Discard(3 * (1 << ((desc[8] & 0x07) + 1))); err != nil {
+					return false
+				}
+			}
+			// LZW minimum code size, then the image data sub-blocks
+			if _, err := r.ReadByte(); err != nil {
+				return false
+			}
+			if !skipGIFSubBlocks(r) {
+				return false
+			}
+		case 0x21: // Extension
+			if _, err := r.ReadByte(); err != nil {
+				return false
+			}
+			if !skipGIFSubBlocks(r) {
+				return false
+			}
+		default: // Trailer (0x3B) or garbage
+			return false
+		}
+	}
+}
+
+func skipGIFSubBlocks(r *bufio.Reader) bool {
+	for {
+		size, err := r.ReadByte()
+		if err != nil {
+			return false
+		}
+		if size == 0 {
+			return true
+		}
+		if _, err := r.Discard(int(size)); err != nil {
+			return false
+		}
+	}
+}
+
+// isAnimatedWebP checks the animation flag of the extended (VP8X) header
+func isAnimatedWebP(r *bufio.Reader) bool {
+	header := make([]byte, 21)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return false
+	}
+	if string(header[0:4]) != "RIFF" || string(header[8:12]) != "WEBP" || string(header[12:16]) != "VP8X" {
+		return false
+	}
+	return header[20]&0x02 != 0
+}
+
+// isAnimatedAVIF looks for the "avis" (image sequence) brand in the ftyp box
+func isAnimatedAVIF(r *bufio.Reader) bool {
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(r, header); err != nil || string(header[4:8]) != "ftyp" {
+		return false
+	}
+	size := int(binary.BigEndian.Uint32(header[0:4]))
+	if size < 16 || size > 4096 {
+		return false
+	}
+	body := make([]byte, size-8)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return false
+	}
+	// Major brand, minor version, then the compatible brands
+	if string(body[0:4]) == "avis" {
+		return true
+	}
+	for i := 8; i+4 <= len(body); i += 4 {
+		if string(body[i:i+4]) == "avis" {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeFirstFrame returns the first frame composited on the full canvas
+func decodeFirstFrame(imagePath string) (image.Image, error) {
+	switch strings.ToLower(filepath.Ext(imagePath)) {
+	case ".gif":
+		return decodeGIFFirstFrame(imagePath)
+	case ".avif":
+		file, err := os.Open(imagePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open image: %w", err)
+		}
+		defer file.Close()
+		return avif.Decode(file)
+	}
+
+	// The WebP walk stops after the first ANMF chunk
+	anim, err := decodeAnimation(imagePath, 1)
+	if err != nil {
+		return nil, err
+	}
+	return anim.frames[0], nil
+}
+
+// decodeGIFFirstFrame decodes only the first frame, which may cover part of the logical screen
+func decodeGIFFirstFrame(imagePath string) (image.Image, error) {
+	data, err := os.ReadFile(imagePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+	config, err := gif.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	frame, err := gif.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	canvas := image.NewRGBA(image.Rect(0, 0, config.Width, config.Height))
+	draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+	return canvas, nil
+}
+
+// decodeAnimation decodes up to maxFrames frames, applying each format's blend and disposal rules
+func decodeAnimation(imagePath string, maxFrames int) (*animation, error) {
+	data, err := os.ReadFile(imagePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+
+	var anim *animation
+	switch strings.ToLower(filepath.Ext(imagePath)) {
+	case ".gif":
+		anim, err = decodeGIFAnimation(data, maxFrames)
+	case ".webp":
+		anim, err = decodeWebPAnimation(data, maxFrames)
+	case ".avif":
+		anim, err = decodeAVIFAnimation(data, maxFrames)
+	default:
+		err = fmt.Errorf("format does not support animation")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(anim.frames) == 0 {
+		return nil, fmt.Errorf("no frames found")
+	}
+	return anim, nil
+}
+
+func decodeGIFAnimation(data []byte, maxFrames int) (*animation, error) {
+	g, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	canvas := image.NewRGBA(image.Rect(0, 0, g.Config.Width, g.Config.Height))
+	anim := &animation{}
+	for i, frame := range g.Image {
+		if i >= maxFrames {
+			break
+		}
+
+		disposal := byte(gif.DisposalNone)
+		if i < len(g.Disposal) {
+			disposal = g.Disposal[i]
+		}
+		var previous *image.RGBA
+		if disposal == gif.DisposalPrevious {
+			previous = cloneRGBA(canvas)
+		}
+
+		draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+		anim.frames = append(anim.frames, cloneRGBA(canvas))
+		anim.delays = append(anim.delays, g.Delay[i])
+
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, frame.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = previous
+		}
+	}
+	return anim, nil
+}
+
+// decodeWebPAnimation composites ANMF frames. x/image/webp can't decode animations,
+// so every frame is rewrapped as a still WebP and decoded on its own.
+func decodeWebPAnimation(data []byte, maxFrames int) (*animation, error) {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, fmt.Errorf("invalid webp header")
+	}
+
+	var canvas *image.RGBA
+	anim := &animation{}
+	for _, chunk := range riffChunks(data[12:]) {
+		switch chunk.id {
+		case "VP8X":
+			if len(chunk.data) < 10 {
+				return nil, fmt.Errorf("invalid VP8X chunk")
+			}
+			width := int(uint24(chunk.data[4:7])) + 1
+			height := int(uint24(chunk.data[7:10])) + 1
+			canvas = image.NewRGBA(image.Rect(0, 0, width, height))
+		case "ANMF":
+			if canvas == nil || len(chunk.data) < 16 {
+				return nil, fmt.Errorf("invalid ANMF chunk")
+			}
+			if len(anim.frames) >= maxFrames {
+				return anim, nil
+			}
+
+			x := int(uint24(chunk.data[0:3])) * 2
+			y := int(uint24(chunk.data[3:6])) * 2
+			width := uint24(chunk.data[6:9])
+			height := uint24(chunk.data[9:12])
+			duration := int(uint24(chunk.data[12:15]))
+			flags := chunk.data[15]
+
+			frame, err := webp.Decode(bytes.NewReader(stillWebP(chunk.data[16:], width, height)))
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode frame %d: %w", len(anim.frames)+1, err)
+			}
+
+			rect := image.Rect(x, y, x+int(width)+1, y+int(height)+1)
+			op := draw.Over
+			if flags&0x02 != 0 { // Do not blend
+				op = draw.Src
+			}
+			draw.Draw(canvas, rect, frame, frame.Bounds().Min, op)
+			anim.frames = append(anim.frames, cloneRGBA(canvas))
+			anim.delays = append(anim.delays, duration/10)
+
+			if flags&0x01 != 0 { // Dispose to background
+				draw.Draw(canvas, rect, image.Transparent, image.Point{}, draw.Src)
+			}
+		}
+	}
+	return anim, nil
+}
+
+func decodeAVIFAnimation(data []byte, maxFrames int) (*animation, error) {
+	a, err := avif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	anim := &animation{}
+	for i, frame := range a.Image {
+		if i >= maxFrames {
+			break
+		}
+		anim.frames = append(anim.frames, toRGBA(frame))
+		delay := 10
+		if i < len(a.Delay) {
+			delay = int(a.Delay[i] * 100)
+		}
+		anim.delays = append(anim.delays, delay)
+	}
+	return anim, nil
+}
+
+type riffChunk struct {
+	id   string
+	data []byte
+}
+
+// riffChunks splits a RIFF payload into chunks, stopping at the first truncated one
+func riffChunks(data []byte) []riffChunk {
+	var chunks []riffChunk
+	for len(data) >= 8 {
+		size := int(binary.LittleEndian.Uint32(data[4:8]))
+		if size < 0 || 8+size > len(data) {
+			break
+		}
+		chunks = append(chunks, riffChunk{id: string(data[0:4]), data: data[8 : 8+size]})
+		// Chunks are padded to an even size
+		next := 8 + size + size&1
+		if next > len(data) {
+			break
+		}
+		data = data[next:]
+	}
+	return chunks
+}
+
+// stillWebP wraps the bitstream chunks of an ANMF frame into a standalone WebP file
+func stillWebP(frameData []byte, widthMinusOne, heightMinusOne uint32) []byte {
+	var body bytes.Buffer
+	body.WriteString("WEBP")
+
+	chunks := riffChunks(frameData)
+	hasAlpha := false
+	for _, c := range chunks {
+		if c.id == "ALPH" {
+			hasAlpha = true
+		}
+	}
+	if hasAlpha {
+		// ALPH needs a VP8X header carrying the frame dimensions
+		vp8x := make([]byte, 10)
+		vp8x[0] = 0x10
+		putUint24(vp8x[4:7], widthMinusOne)
+		putUint24(vp8x[7:10], heightMinusOne)
+		writeRIFFChunk(&body, "VP8X", vp8x)
+	}
+	for _, c := range chunks {
+		if c.id == "ALPH" || c.id == "VP8 " || c.id == "VP8L" {
+			writeRIFFChunk(&body, c.id, c.data)
+		}
+	}
+
+	var out bytes.Buffer
+	out.WriteString("RIFF")
+	binary.Write(&out, binary.LittleEndian, uint32(body.Len()))
+	out.Write(body.Bytes())
+	return out.Bytes()
+}
+
+func writeRIFFChunk(w *bytes.Buffer, id string, data []byte) {
+	w.WriteString(id)
+	binary.Write(w, binary.LittleEndian, uint32(len(data)))
+	w.Write(data)
+	if len(data)%2 == 1 {
+		w.WriteByte(0)
+	}
+}
+
+func uint24(b []byte) uint32 {
+	return uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16
+}
+
+func putUint24(b []byte, v uint32) {
+	b[0] = byte(v)
+	b[1] = byte(v >> 8)
+	b[2] = byte(v >> 16)
+}
+
+func cloneRGBA(src *image.RGBA) *image.RGBA {
+	dst := image.NewRGBA(src.Bounds())
+	copy(dst.Pix, src.Pix)
+	return dst
+}
+
+func toRGBA(src image.Image) *image.RGBA {
+	if rgba, ok := src.(*image.RGBA); ok {
+		return rgba
+	}
+	dst := image.NewRGBA(src.Bounds())
+	draw.Draw(dst, dst.Bounds(), src, src.Bounds().Min, draw.Src)
+	return dst
+}
+
+// generatePreviewCacheKey generates the cache key for an animated preview
+func (g *Generator) generatePreviewCacheKey(imagePath string) string {
+	return strings.TrimSuffix(g.generateCacheKey(imagePath), ".jpg") + ".anim.gif"
+}
+
+// GetPreviewCachePath returns the full cache path for an animated preview
+func (g *Generator) GetPreviewCachePath(imagePath string) string {
+	return filepath.Join(g.cacheDir, g.generatePreviewCacheKey(imagePath))
+}
+
+// GetAnimatedPreview returns the cache path of a short animated preview (generates if not cached).
+// Previews are written as GIF rather than animated WebP: neither the standard library nor
+// golang.org/x/image can encode WebP, and a libwebp binding isn't worth adding for
+// thumbnails. Every webview plays GIF.
+func (g *Generator) GetAnimatedPreview(imagePath string) (string, error) {
+	cachePath := g.GetPreviewCachePath(imagePath)
+	if _, err := os.Stat(cachePath); err == nil {
+		return cachePath, nil
+	}
+
+	// Deduplicate generation work, keyed separately from the static thumbnail
+	pendingKey := "preview:" + imagePath
+	waitCh := make(chan struct{})
+	actual, loaded := g.pending.LoadOrStore(pendingKey, waitCh)
+	if loaded {
+		<-actual.(chan struct{})
+		if _, err := os.Stat(cachePath); err != nil {
+			return "", fmt.Errorf("failed to generate animated preview")
+		}
+		return cachePath, nil
+	}
+	defer func() {
+		close(waitCh)
+		g.pending.Delete(pendingKey)
+	}()
+
+	g.semaphore <- struct{}{}
+	defer func() { <-g.semaphore }()
+
+	anim, err := decodeAnimation(imagePath, previewMaxFrames)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode animation: %w", err)
+	}
+
+	bounds := anim.frames[0].Bounds()
+	newWidth, newHeight := calculateThumbnailSize(bounds.Dx(), bounds.Dy(), previewWidth, previewHeight)
+	out := &gif.GIF{}
+	for i, frame := range anim.frames {
+		scaled := image.NewRGBA(image.Rect(0, 0, newWidth, newHeight))
+		draw.CatmullRom.Scale(scaled, scaled.Bounds(), frame, frame.Bounds(), draw.Over, nil)
+
+		paletted := image.NewPaletted(scaled.Bounds(), palette.Plan9)
+		draw.FloydSteinberg.Draw(paletted, paletted.Bounds(), scaled, image.Point{})
+
+		delay := anim.delays[i]
+		if delay < minFrameDelay {
+			delay = 10
+		}
+		out.Image = append(out.Image, paletted)
+		out.Delay = append(out.Delay, delay)
+	}
+
+	// Write to a temp file first so a concurrent reader never sees a partial GIF
+	os.MkdirAll(filepath.Dir(cachePath), 0755)
+	tmpPath := cachePath + ".tmp"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to create cache file: %w", err)
+	}
+	if err := gif.EncodeAll(file, out); err != nil {
+		file.Close()
+		os.Remove(tmpPath)
+		return "", fmt.Errorf("failed to encode animated preview: %w", err)
+	}
+	file.Close()
+
+	if err := os.Rename(tmpPath, cachePath); err != nil {
+		os.Remove(tmpPath)
+		return "", err
+	}
+	return cachePath, nil
+}
@@ -148,7 +148,19 @@ func (g *Generator) generateThumbnail(imagePath string) (string, error) {
 	var format string
 	var decodeErr error
 
-	for attempts := 0; attempts < 3; attempts++ {
+	switch {
+	case isSVG(imagePath):
+		// Rasterize instead of embedding the raw file, so the result is cached like any other thumbnail
+		img, decodeErr = rasterizeSVG(imagePath)
+		format = "svg"
+	case IsAnimated(imagePath):
+		// Animated images: image.Decode would hand back a raw first frame without its canvas,
+		// so composite it properly instead
+		img, decodeErr = decodeFirstFrame(imagePath)
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(imagePath)), ".")
+	}
+
+	for attempts := 0; img == nil && format != "svg" && attempts < 3; attempts++ {
 		file.Seek(0, 0)
 		img, format, decodeErr = image.Decode(file)
 		if decodeErr == nil {
@@ -181,9 +193,8 @@ func (g *Generator) generateThumbnail(imagePath string) (string, error) {
 	}
 
 	if decodeErr != nil {
-		// For SVG, return the original as base64
-		if strings.HasSuffix(strings.ToLower(imagePath), ".svg") {
-			return g.loadSVGAsThumbnail(imagePath)
+		if format == "svg" {
+			return "", decodeErr
 		}
 
 		// Final error logging
@@ -228,17 +239,6 @@ func (g *Generator) generateThumbnail(imagePath string) (string, error) {
 	return g.loadCachedThumbnail(imagePath)
 }
 
-// loadSVGAsThumbnail loads an SVG file and returns it as a data URL
-func (g *Generator) loadSVGAsThumbnail(imagePath string) (string, error) {
-	data, err := os.ReadFile(imagePath)
-	if err != nil {
-		return "", err
-	}
-
-	base64Data := base64.StdEncoding.EncodeToString(data)
-	return fmt.Sprintf("data:image/svg+xml;base64,%s", base64Data), nil
-}
-
 // calculateThumbnailSize calculates thumbnail dimensions maintaining aspect ratio
 func calculateThumbnailSize(origWidth, origHeight, maxWidth, maxHeight int) (int, int) {
 	// Calculate scale factors
@@ -290,6 +290,7 @@ func (g *Generator) ClearCacheForFolder(folderPath string) error {
 		imagePath := filepath.Join(folderPath, entry.Name())
 		cachePath := g.GetCachePath(imagePath)
 		os.Remove(cachePath) // Ignore errors for non-existent files
+		os.Remove(g.GetPreviewCachePath(imagePath))
 	}
 
 	return nil
@@ -0,0 +1,59 @@
+package thumbnails
+
+import (
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/srwiley/oksvg"
+	"github.com/srwiley/rasterx"
+	"golang.org/x/image/draw"
+)
+
+// SVGs larger than this are not rasterized, huge vector files can stall the parser
+const maxSVGSize = 10 * 1024 * 1024
+
+// isSVG checks the extension, SVG can't be sniffed by image.Decode
+func isSVG(imagePath string) bool {
+	return strings.ToLower(filepath.Ext(imagePath)) == ".svg"
+}
+
+// rasterizeSVG renders an SVG at thumbnail size on a white background.
+// Only vector shapes are drawn: scripts, external references and foreign content are never evaluated.
+func rasterizeSVG(imagePath string) (image.Image, error) {
+	file, err := os.Open(imagePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open svg: %w", err)
+	}
+	defer file.Close()
+
+	if info, err := file.Stat(); err == nil && info.Size() > maxSVGSize {
+		return nil, fmt.Errorf("svg too large to rasterize (%d bytes)", info.Size())
+	}
+
+	icon, err := oksvg.ReadIconStream(io.LimitReader(file, maxSVGSize), oksvg.IgnoreErrorMode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse svg: %w", err)
+	}
+
+	origWidth := int(icon.ViewBox.W)
+	origHeight := int(icon.ViewBox.H)
+	if origWidth <= 0 || origHeight <= 0 {
+		// No usable viewBox or size attributes, assume a page shaped canvas
+		origWidth, origHeight = thumbnailWidth, thumbnailHeight
+	}
+	width, height := calculateThumbnailSize(origWidth, origHeight, thumbnailWidth, thumbnailHeight)
+
+	// JPEG has no alpha, so paint white first instead of letting transparency turn black
+	canvas := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(canvas, canvas.Bounds(), image.White, image.Point{}, draw.Src)
+
+	icon.SetTarget(0, 0, float64(width), float64(height))
+	scanner := rasterx.NewScannerGV(width, height, canvas, canvas.Bounds())
+	icon.Draw(rasterx.NewDasher(width, height, scanner), 1.0)
+
+	return canvas, nil
+}